@@ -0,0 +1,233 @@
+package attack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scores given to techniques in an exported layer. A technique covered by
+// several tasks takes the highest score among them.
+const (
+	ScorePlanned   = 0
+	ScoreExecuted  = 1
+	ScoreSucceeded = 2
+	ScoreDetected  = 3
+)
+
+var scoreColors = []string{"#d9d9d9", "#66b1ff", "#ff6666", "#ffb000"}
+
+var scoreLabels = []string{"Planned", "Executed", "Succeeded", "Detected"}
+
+// Layer is an ATT&CK Navigator layer, format version 4.5
+type Layer struct {
+	Name        string           `json:"name"`
+	Versions    LayerVersions    `json:"versions"`
+	Domain      string           `json:"domain"`
+	Description string           `json:"description,omitempty"`
+	Techniques  []LayerTechnique `json:"techniques"`
+	Gradient    *LayerGradient   `json:"gradient,omitempty"`
+	LegendItems []LegendItem     `json:"legendItems,omitempty"`
+}
+
+type LayerVersions struct {
+	Attack    string `json:"attack,omitempty"`
+	Navigator string `json:"navigator,omitempty"`
+	Layer     string `json:"layer"`
+}
+
+type LayerTechnique struct {
+	TechniqueID       string `json:"techniqueID"`
+	Tactic            string `json:"tactic,omitempty"`
+	Score             *int   `json:"score,omitempty"`
+	Color             string `json:"color,omitempty"`
+	Comment           string `json:"comment,omitempty"`
+	Enabled           *bool  `json:"enabled,omitempty"`
+	ShowSubtechniques bool   `json:"showSubtechniques,omitempty"`
+}
+
+type LayerGradient struct {
+	Colors   []string `json:"colors"`
+	MinValue int      `json:"minValue"`
+	MaxValue int      `json:"maxValue"`
+}
+
+type LegendItem struct {
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// taskCoverage is what one task contributes to its technique
+type taskCoverage struct {
+	task      models.Task
+	results   int
+	succeeded int
+	detected  int
+	score     int
+}
+
+// BuildLayer scores each technique the operation's tasks cover. A task that
+// has not started counts as planned, one in progress, completed or with
+// results as executed, and results reporting success or detection raise the
+// score to succeeded or detected. The comment on each technique lists the
+// tasks behind it.
+func BuildLayer(operation *models.Operation, tasks []models.Task, results []models.Result, now time.Time) Layer {
+	byTask := make(map[primitive.ObjectID][]models.Result)
+	for _, r := range results {
+		byTask[r.TaskID] = append(byTask[r.TaskID], r)
+	}
+
+	coverage := make(map[string][]taskCoverage)
+	tactics := make(map[string]string)
+	for _, task := range tasks {
+		id := Normalize(task.MITREID)
+		if !ValidID(id) {
+			continue
+		}
+
+		cov := taskCoverage{task: task, results: len(byTask[task.ID])}
+		for _, r := range byTask[task.ID] {
+			switch Outcome(r.Result) {
+			case ScoreDetected:
+				cov.detected++
+			case ScoreSucceeded:
+				cov.succeeded++
+			}
+		}
+		switch {
+		case cov.detected > 0:
+			cov.score = ScoreDetected
+		case cov.succeeded > 0:
+			cov.score = ScoreSucceeded
+		case cov.results > 0 || task.Status == models.StatusInProgress || task.Status == models.StatusCompleted:
+			cov.score = ScoreExecuted
+		default:
+			cov.score = ScorePlanned
+		}
+
+		coverage[id] = append(coverage[id], cov)
+		if _, seen := tactics[id]; !seen && task.Phase != "" {
+			tactics[id] = Tactic(task.Phase)
+		}
+	}
+
+	ids := make([]string, 0, len(coverage))
+	for id := range coverage {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	enabled := true
+	techniques := make([]LayerTechnique, 0, len(ids))
+	listed := make(map[string]bool)
+	for _, id := range ids {
+		score := ScorePlanned
+		lines := make([]string, 0, len(coverage[id]))
+		for _, cov := range coverage[id] {
+			if cov.score > score {
+				score = cov.score
+			}
+			lines = append(lines, fmt.Sprintf("%s [%s]: %d results, %d succeeded, %d detected",
+				cov.task.Title, cov.task.Status, cov.results, cov.succeeded, cov.detected))
+		}
+
+		s := score
+		techniques = append(techniques, LayerTechnique{
+			TechniqueID: id,
+			Tactic:      tactics[id],
+			Score:       &s,
+			Color:       scoreColors[score],
+			Comment:     strings.Join(lines, "\n"),
+			Enabled:     &enabled,
+		})
+		listed[id] = true
+	}
+
+	// Expand parents so scored sub-techniques are visible in the matrix
+	for i, t := range techniques {
+		parent, _, found := strings.Cut(t.TechniqueID, ".")
+		if !found {
+			continue
+		}
+		if listed[parent] {
+			for j := range techniques {
+				if techniques[j].TechniqueID == parent {
+					techniques[j].ShowSubtechniques = true
+				}
+			}
+			continue
+		}
+		techniques = append(techniques, LayerTechnique{
+			TechniqueID:       parent,
+			Tactic:            techniques[i].Tactic,
+			Enabled:           &enabled,
+			ShowSubtechniques: true,
+		})
+		listed[parent] = true
+	}
+
+	legend := make([]LegendItem, len(scoreLabels))
+	for i, label := range scoreLabels {
+		legend[i] = LegendItem{Label: label, Color: scoreColors[i]}
+	}
+
+	return Layer{
+		Name:        operation.Name,
+		Versions:    LayerVersions{Navigator: "5.1.0", Layer: "4.5"},
+		Domain:      "enterprise-attack",
+		Description: fmt.Sprintf("ATT&CK coverage of operation %s, generated %s", operation.Name, now.UTC().Format(time.RFC3339)),
+		Techniques:  techniques,
+		Gradient: &LayerGradient{
+			Colors:   scoreColors,
+			MinValue: ScorePlanned,
+			MaxValue: ScoreDetected,
+		},
+		LegendItems: legend,
+	}
+}
+
+// Outcome reads the free-text outcome operators record on a result and
+// returns ScoreDetected, ScoreSucceeded or ScoreExecuted
+func Outcome(text string) int {
+	text = strings.ToLower(text)
+
+	undetected := strings.Contains(text, "undetected") || strings.Contains(text, "not detected")
+	if !undetected {
+		for _, word := range []string{"detect", "alert", "block", "prevent", "quarantin"} {
+			if strings.Contains(text, word) {
+				return ScoreDetected
+			}
+		}
+	}
+
+	if strings.Contains(text, "unsuccess") || strings.Contains(text, "fail") {
+		return ScoreExecuted
+	}
+	for _, word := range []string{"success", "succeed", "compromis", "achieved", "obtained"} {
+		if strings.Contains(text, word) {
+			return ScoreSucceeded
+		}
+	}
+
+	return ScoreExecuted
+}
+
+// Tactic returns the ATT&CK tactic short name for an operation phase
+func Tactic(phase models.OperationPhase) string {
+	return strings.ReplaceAll(string(phase), "_", "-")
+}
+
+// Phase returns the operation phase for an ATT&CK tactic short name, or an
+// empty phase when the tactic has none
+func Phase(tactic string) models.OperationPhase {
+	phase := models.OperationPhase(strings.ReplaceAll(tactic, "-", "_"))
+	if !phase.Valid() {
+		return ""
+	}
+	return phase
+}
@@ -0,0 +1,145 @@
+// Package attack reads the MITRE ATT&CK Enterprise catalogue from its STIX
+// 2.1 bundle and checks technique IDs against it.
+package attack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"redops/models"
+)
+
+// DefaultBundlePath is where the bundle is looked for when ATTACK_BUNDLE is
+// not set
+const DefaultBundlePath = "data/enterprise-attack.json"
+
+var techniqueIDPattern = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+
+type bundle struct {
+	Type    string      `json:"type"`
+	Objects []stixEntry `json:"objects"`
+}
+
+type stixEntry struct {
+	Type               string   `json:"type"`
+	Name               string   `json:"name"`
+	Description        string   `json:"description"`
+	Revoked            bool     `json:"revoked"`
+	Deprecated         bool     `json:"x_mitre_deprecated"`
+	IsSubtechnique     bool     `json:"x_mitre_is_subtechnique"`
+	Platforms          []string `json:"x_mitre_platforms"`
+	ExternalReferences []struct {
+		SourceName string `json:"source_name"`
+		ExternalID string `json:"external_id"`
+		URL        string `json:"url"`
+	} `json:"external_references"`
+	KillChainPhases []struct {
+		KillChainName string `json:"kill_chain_name"`
+		PhaseName     string `json:"phase_name"`
+	} `json:"kill_chain_phases"`
+}
+
+// BundlePath returns the configured location of the bundle, read from
+// ATTACK_BUNDLE
+func BundlePath() string {
+	if path := os.Getenv("ATTACK_BUNDLE"); path != "" {
+		return path
+	}
+	return DefaultBundlePath
+}
+
+// LoadFile parses the bundle stored at path
+func LoadFile(path string) ([]models.AttackTechnique, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Parse reads the attack-pattern objects of a STIX 2.1 bundle. Revoked
+// patterns and patterns without an ATT&CK ID are skipped and counted.
+func Parse(r io.Reader) ([]models.AttackTechnique, int, error) {
+	var b bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, 0, fmt.Errorf("reading STIX bundle: %w", err)
+	}
+	if b.Type != "bundle" {
+		return nil, 0, fmt.Errorf("not a STIX bundle")
+	}
+
+	var techniques []models.AttackTechnique
+	skipped := 0
+	for _, obj := range b.Objects {
+		if obj.Type != "attack-pattern" {
+			continue
+		}
+		if obj.Revoked {
+			skipped++
+			continue
+		}
+
+		technique := models.AttackTechnique{
+			Name:           obj.Name,
+			Description:    obj.Description,
+			Tactics:        []string{},
+			Platforms:      obj.Platforms,
+			IsSubtechnique: obj.IsSubtechnique,
+			Deprecated:     obj.Deprecated,
+		}
+		for _, ref := range obj.ExternalReferences {
+			if ref.SourceName == "mitre-attack" {
+				technique.TechniqueID = ref.ExternalID
+				technique.URL = ref.URL
+				break
+			}
+		}
+		if !techniqueIDPattern.MatchString(technique.TechniqueID) {
+			skipped++
+			continue
+		}
+		for _, phase := range obj.KillChainPhases {
+			if phase.KillChainName == "mitre-attack" {
+				technique.Tactics = append(technique.Tactics, phase.PhaseName)
+			}
+		}
+		if parent, _, found := strings.Cut(technique.TechniqueID, "."); found {
+			technique.IsSubtechnique = true
+			technique.ParentID = parent
+		}
+
+		techniques = append(techniques, technique)
+	}
+
+	return techniques, skipped, nil
+}
+
+// Normalize tidies a technique ID as typed by an operator, so " t1059.001"
+// becomes "T1059.001"
+func Normalize(id string) string {
+	return strings.ToUpper(strings.TrimSpace(id))
+}
+
+// ValidID reports whether id has the shape of a technique or sub-technique ID
+func ValidID(id string) bool {
+	return techniqueIDPattern.MatchString(id)
+}
+
+// Summarize describes the outcome of parsing a catalogue
+func Summarize(source string, techniques []models.AttackTechnique, skipped int) models.AttackImportSummary {
+	summary := models.AttackImportSummary{Source: source, Skipped: skipped}
+	for _, t := range techniques {
+		if t.IsSubtechnique {
+			summary.Subtechniques++
+		} else {
+			summary.Techniques++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,122 @@
+package attack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleBundle = `{
+  "type": "bundle",
+  "objects": [
+    {"type": "x-mitre-tactic", "name": "Execution"},
+    {
+      "type": "attack-pattern",
+      "name": "Command and Scripting Interpreter",
+      "description": "Adversaries may abuse interpreters.",
+      "x_mitre_platforms": ["Linux", "Windows"],
+      "external_references": [
+        {"source_name": "capec", "external_id": "CAPEC-1"},
+        {"source_name": "mitre-attack", "external_id": "T1059", "url": "https://attack.mitre.org/techniques/T1059"}
+      ],
+      "kill_chain_phases": [
+        {"kill_chain_name": "mitre-attack", "phase_name": "execution"},
+        {"kill_chain_name": "lockheed", "phase_name": "installation"}
+      ]
+    },
+    {
+      "type": "attack-pattern",
+      "name": "PowerShell",
+      "x_mitre_deprecated": true,
+      "external_references": [{"source_name": "mitre-attack", "external_id": "T1059.001"}],
+      "kill_chain_phases": [{"kill_chain_name": "mitre-attack", "phase_name": "execution"}]
+    },
+    {
+      "type": "attack-pattern",
+      "name": "Old technique",
+      "revoked": true,
+      "external_references": [{"source_name": "mitre-attack", "external_id": "T1000"}]
+    },
+    {
+      "type": "attack-pattern",
+      "name": "Mobile only",
+      "external_references": [{"source_name": "mitre-mobile-attack", "external_id": "T1400"}]
+    }
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	techniques, skipped, err := Parse(strings.NewReader(sampleBundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 2 {
+		t.Errorf("skipped = %d, want 2", skipped)
+	}
+	if len(techniques) != 2 {
+		t.Fatalf("got %d techniques, want 2", len(techniques))
+	}
+
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"technique ID", techniques[0].TechniqueID, "T1059"},
+		{"name", techniques[0].Name, "Command and Scripting Interpreter"},
+		{"url", techniques[0].URL, "https://attack.mitre.org/techniques/T1059"},
+		{"tactics", techniques[0].Tactics, []string{"execution"}},
+		{"platforms", techniques[0].Platforms, []string{"Linux", "Windows"}},
+		{"technique is not a sub-technique", techniques[0].IsSubtechnique, false},
+		{"sub-technique", techniques[1].IsSubtechnique, true},
+		{"parent", techniques[1].ParentID, "T1059"},
+		{"deprecated", techniques[1].Deprecated, true},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	summary := Summarize("test", techniques, skipped)
+	if summary.Techniques != 1 || summary.Subtechniques != 1 || summary.Skipped != 2 {
+		t.Errorf("Summarize() = %+v", summary)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", "<xml/>"},
+		{"not a bundle", `{"type": "attack-pattern"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Parse(strings.NewReader(tt.body)); err == nil {
+				t.Error("Parse() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestValidID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"T1059", true},
+		{"T1059.001", true},
+		{Normalize(" t1059.001 "), true},
+		{"t1059", false},
+		{"T105", false},
+		{"T1059.1", false},
+		{"TA0002", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidID(tt.id); got != tt.want {
+			t.Errorf("ValidID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package comments reads the @mentions out of task comments.
+package comments
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @username where the @ does not follow a word
+// character, so email addresses are not read as mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9][A-Za-z0-9_.-]*)`)
+
+// codePattern matches fenced code blocks and inline code, which are left out
+// when looking for mentions
+var codePattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+
+// Mentions returns the usernames mentioned in a markdown body, once each and
+// in the order they first appear
+func Mentions(body string) []string {
+	body = codePattern.ReplaceAllString(body, " ")
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		// A trailing dot ends the sentence rather than the username
+		name := strings.TrimRight(match[1], ".-")
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// Added returns the mentions in after that were not already in before
+func Added(before, after []string) []string {
+	known := make(map[string]bool, len(before))
+	for _, name := range before {
+		known[strings.ToLower(name)] = true
+	}
+
+	var added []string
+	for _, name := range after {
+		if !known[strings.ToLower(name)] {
+			added = append(added, name)
+		}
+	}
+	return added
+}
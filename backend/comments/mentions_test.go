@@ -0,0 +1,54 @@
+package comments
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"none", "no mentions here", nil},
+		{"single", "@alice please check", []string{"alice"}},
+		{"several in order", "cc @bob and @alice", []string{"bob", "alice"}},
+		{"repeated once", "@alice, @Alice and @alice again", []string{"alice"}},
+		{"sentence end", "Thanks @bob.", []string{"bob"}},
+		{"dotted name", "ask @j.doe-smith about it", []string{"j.doe-smith"}},
+		{"after punctuation", "(@carol) and \"@dave\"", []string{"carol", "dave"}},
+		{"email ignored", "mail alice@example.com", nil},
+		{"double at ignored", "@@alice", nil},
+		{"inline code ignored", "run `ssh @host` with @erin", []string{"erin"}},
+		{"fenced code ignored", "```\n@frank\n```\n@grace", []string{"grace"}},
+		{"bare at", "meet @ noon", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdded(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []string
+		want          []string
+	}{
+		{"new comment", nil, []string{"alice"}, []string{"alice"}},
+		{"unchanged", []string{"alice"}, []string{"alice"}, nil},
+		{"case insensitive", []string{"Alice"}, []string{"alice", "bob"}, []string{"bob"}},
+		{"removed", []string{"alice", "bob"}, []string{"bob"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Added(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Added() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the repositories rely on. CreateMany is a
+// no-op for indexes that already exist, so this is safe to run on every start.
+func EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	indexes := map[*mongo.Collection][]mongo.IndexModel{
+		Results: {
+			{Keys: bson.D{{Key: "taskId", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "startTime", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "sourceIP", Value: 1}}},
+			{Keys: bson.D{{Key: "destinationIP", Value: 1}, {Key: "destinationPort", Value: 1}}},
+			{Keys: bson.D{{Key: "destinationPort", Value: 1}}},
+			{Keys: bson.D{{Key: "toolApp", Value: 1}}},
+			{Keys: bson.D{{Key: "operatorName", Value: 1}}},
+			{Keys: bson.D{{Key: "fingerprint", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		},
+		Executions: {
+			{Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "created_at", Value: 1}}},
+			{Keys: bson.D{{Key: "operation_id", Value: 1}, {Key: "status", Value: 1}}},
+		},
+		Assets: {
+			{Keys: bson.D{{Key: "operation_id", Value: 1}, {Key: "host", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "operation_id", Value: 1}, {Key: "aliases", Value: 1}}},
+			{Keys: bson.D{{Key: "operation_id", Value: 1}, {Key: "services.port", Value: 1}}},
+		},
+		Techniques: {
+			{Keys: bson.D{{Key: "technique_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "tactics", Value: 1}}},
+		},
+		Comments: {
+			{Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		},
+		Evidence: {
+			{Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "created_at", Value: 1}}},
+			{Keys: bson.D{{Key: "result_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		},
+		TimeLog: {
+			{Keys: bson.D{{Key: "operation_id", Value: 1}, {Key: "start", Value: 1}}},
+			{Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "start", Value: 1}}},
+			// At most one running timer per user and task
+			{Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"running": true})},
+		},
+		AuditLog: {
+			{Keys: bson.D{{Key: "operation_id", Value: 1}, {Key: "timestamp", Value: 1}}},
+			{Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "timestamp", Value: 1}}},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := collection.Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -3,12 +3,22 @@ package database
 import (
 	"context"
 	"log"
+	"os"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// defaultURI is used when MONGODB_URI is not set
+const defaultURI = "mongodb://localhost:27017"
+
+// Transactions reports whether the server can run multi-document
+// transactions. ConnectDB sets it; without them WithTransaction runs its
+// writes one after another.
+var Transactions bool
+
 var (
 	Client     *mongo.Client
 	Database   *mongo.Database
@@ -17,13 +27,30 @@ var (
 	Tasks      *mongo.Collection
 	Tools      *mongo.Collection
 	Results    *mongo.Collection
+	AuditLog   *mongo.Collection
+	Executions *mongo.Collection
+	Assets     *mongo.Collection
+	Templates  *mongo.Collection
+	Techniques *mongo.Collection
+	Comments   *mongo.Collection
+	Evidence   *mongo.Collection
+	TimeLog    *mongo.Collection
 )
 
+// ConnectDB connects to MONGODB_URI. Deletes, restores, purges and bulk task
+// writes are only atomic on a replica set or a sharded cluster, which support
+// transactions; a single-node replica set is enough for development, for
+// example mongodb://localhost:27017/?replicaSet=rs0. A standalone mongod
+// still works, with a warning at start.
 func ConnectDB() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI("mongodb://18.188.248.46:27017")
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		uri = defaultURI
+	}
+	clientOptions := options.Client().ApplyURI(uri)
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return err
@@ -34,6 +61,15 @@ func ConnectDB() error {
 	if err != nil {
 		return err
 	}
+	Transactions, err = supportsTransactions(ctx, client)
+	if err != nil {
+		client.Disconnect(ctx)
+		return err
+	}
+	if !Transactions {
+		log.Println("Warning: MongoDB is not a replica set, so cascading deletes, restores, purges and bulk task " +
+			"writes are not atomic. Start mongod with --replSet, run rs.initiate() and add ?replicaSet=<name> to MONGODB_URI.")
+	}
 
 	Client = client
 	Database = client.Database("redops")
@@ -44,6 +80,14 @@ func ConnectDB() error {
 	Tasks = Database.Collection("tasks")
 	Tools = Database.Collection("tools")
 	Results = Database.Collection("results")
+	AuditLog = Database.Collection("audit_log")
+	Executions = Database.Collection("tool_executions")
+	Assets = Database.Collection("assets")
+	Templates = Database.Collection("operation_templates")
+	Techniques = Database.Collection("attack_techniques")
+	Comments = Database.Collection("task_comments")
+	Evidence = Database.Collection("evidence")
+	TimeLog = Database.Collection("time_entries")
 
 	log.Println("Connected to MongoDB!")
 	return nil
@@ -60,3 +104,23 @@ func CloseDB() error {
 	log.Println("Disconnected from MongoDB!")
 	return nil
 }
+
+// supportsTransactions reports whether the server is a replica set member or
+// a mongos router, the deployments that support transactions
+func supportsTransactions(ctx context.Context, client *mongo.Client) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	admin := client.Database("admin")
+	err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		// Servers before 4.4 only know the legacy name
+		err = admin.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithTransaction runs fn inside a multi-document transaction, committing
+// when it returns nil and aborting otherwise. Transactions need MongoDB to
+// run as a replica set; on a standalone server fn runs in a plain session and
+// the writes it made before failing stay in place.
+func WithTransaction(fn func(ctx mongo.SessionContext) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session, err := Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	if !Transactions {
+		return mongo.WithSession(ctx, session, fn)
+	}
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,100 @@
+// Package evidence decides which files may be attached to tasks and results
+// as evidence.
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxSize is the largest file accepted unless EVIDENCE_MAX_SIZE says
+// otherwise
+const DefaultMaxSize int64 = 100 << 20
+
+var ErrTypeNotAllowed = errors.New("file type not allowed as evidence")
+
+// types maps the accepted file extensions to the content type they are
+// stored and served with
+var types = map[string]string{
+	// Screenshots and recordings
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".webp": "image/webp",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+	// Packet captures
+	".pcap":   "application/vnd.tcpdump.pcap",
+	".pcapng": "application/octet-stream",
+	".cap":    "application/vnd.tcpdump.pcap",
+	// Logs, tool output and loot
+	".txt":    "text/plain; charset=utf-8",
+	".log":    "text/plain; charset=utf-8",
+	".csv":    "text/csv; charset=utf-8",
+	".json":   "application/json",
+	".xml":    "application/xml",
+	".html":   "text/plain; charset=utf-8",
+	".md":     "text/markdown; charset=utf-8",
+	".har":    "application/json",
+	".nessus": "application/xml",
+	".evtx":   "application/octet-stream",
+	".pdf":    "application/pdf",
+	".docx":   "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	// Archives
+	".zip": "application/zip",
+	".7z":  "application/x-7z-compressed",
+	".tar": "application/x-tar",
+	".gz":  "application/gzip",
+	".tgz": "application/gzip",
+}
+
+// MaxSize returns the largest evidence file accepted in bytes, read from
+// EVIDENCE_MAX_SIZE
+func MaxSize() int64 {
+	if value := os.Getenv("EVIDENCE_MAX_SIZE"); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxSize
+}
+
+// ContentType returns the content type a file is stored with, or
+// ErrTypeNotAllowed when its extension is not accepted. HTML is served as
+// plain text so that captured pages are never rendered by the browser.
+func ContentType(filename string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if contentType, ok := types[ext]; ok {
+		return contentType, nil
+	}
+	return "", fmt.Errorf("%w: %q, accepted are %s", ErrTypeNotAllowed, ext, strings.Join(Extensions(), " "))
+}
+
+// Extensions lists the accepted file extensions in alphabetical order
+func Extensions() []string {
+	extensions := make([]string, 0, len(types))
+	for ext := range types {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// Disposition builds a Content-Disposition header that makes browsers
+// download the file under its original name
+func Disposition(filename string) string {
+	name := filepath.Base(filename)
+	if header := mime.FormatMediaType("attachment", map[string]string{"filename": name}); header != "" {
+		return header
+	}
+	return "attachment"
+}
@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrProgramNotAllowed = errors.New("program is not on the execution allow-list")
+	ErrOptionArgument    = errors.New("caller supplied values cannot be options")
+)
+
+// defaultPrograms are the scanners a tool may run when
+// EXECUTION_ALLOWED_PROGRAMS is not set
+var defaultPrograms = []string{"nmap", "masscan", "nikto", "sslscan", "whatweb", "dig", "whois"}
+
+// AllowedPrograms returns the programs tools may run, read from the
+// comma-separated EXECUTION_ALLOWED_PROGRAMS
+func AllowedPrograms() []string {
+	value := os.Getenv("EXECUTION_ALLOWED_PROGRAMS")
+	if value == "" {
+		return defaultPrograms
+	}
+
+	var programs []string
+	for _, program := range strings.Split(value, ",") {
+		if program = strings.TrimSpace(program); program != "" {
+			programs = append(programs, program)
+		}
+	}
+	return programs
+}
+
+// CheckProgram makes sure argv runs an allowed program by its bare name, so
+// it is found on PATH rather than at a path chosen by whoever wrote the tool
+func CheckProgram(argv []string) error {
+	if len(argv) == 0 {
+		return fmt.Errorf("%w: empty command", ErrProgramNotAllowed)
+	}
+	program := argv[0]
+	if !strings.ContainsAny(program, `/\`) {
+		for _, allowed := range AllowedPrograms() {
+			if program == allowed {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrProgramNotAllowed, program)
+}
+
+// CheckValues rejects caller supplied argument values that start with a
+// dash. Options come from the tool definition only, so a caller filling in
+// a target cannot turn it into a flag such as --script or -oN.
+func CheckValues(values []string) error {
+	for _, value := range values {
+		if strings.HasPrefix(strings.TrimSpace(value), "-") {
+			return fmt.Errorf("%w: %q", ErrOptionArgument, value)
+		}
+	}
+	return nil
+}
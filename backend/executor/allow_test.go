@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckProgram(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		argv    []string
+		wantErr bool
+	}{
+		{"default program", "", []string{"nmap", "-sV", "10.0.0.1"}, false},
+		{"not listed", "", []string{"bash", "-c", "id"}, true},
+		{"path to listed name", "", []string{"/tmp/nmap"}, true},
+		{"relative path", "", []string{"./nmap"}, true},
+		{"empty command", "", nil, true},
+		{"configured list", "ffuf, gobuster", []string{"gobuster", "dir"}, false},
+		{"default dropped by configured list", "ffuf", []string{"nmap"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EXECUTION_ALLOWED_PROGRAMS", tt.env)
+			err := CheckProgram(tt.argv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckProgram(%v) = %v, wantErr %v", tt.argv, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrProgramNotAllowed) {
+				t.Errorf("CheckProgram(%v) = %v, want ErrProgramNotAllowed", tt.argv, err)
+			}
+		})
+	}
+}
+
+func TestCheckValues(t *testing.T) {
+	tests := []struct {
+		values  []string
+		wantErr bool
+	}{
+		{[]string{"10.0.0.1", "example.com"}, false},
+		{[]string{"10.0.0.1", "--script=evil"}, true},
+		{[]string{" -oN/etc/passwd"}, true},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if err := CheckValues(tt.values); (err != nil) != tt.wantErr {
+			t.Errorf("CheckValues(%q) = %v, wantErr %v", tt.values, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxOutput caps how much of a tool's combined output is kept
+const maxOutput = 1 << 20
+
+var (
+	ErrCancelled = errors.New("execution cancelled")
+	ErrTimedOut  = errors.New("execution timed out")
+)
+
+// Runner runs tool commands in the background and keeps track of them so
+// they can be cancelled while queued or running
+type Runner struct {
+	mu      sync.Mutex
+	cancels map[primitive.ObjectID]context.CancelFunc
+	timeout time.Duration
+}
+
+// NewRunner creates a runner whose executions are killed after
+// EXECUTION_TIMEOUT (a Go duration, one hour by default)
+func NewRunner() *Runner {
+	timeout := time.Hour
+	if value := os.Getenv("EXECUTION_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			timeout = d
+		}
+	}
+
+	return &Runner{
+		cancels: make(map[primitive.ObjectID]context.CancelFunc),
+		timeout: timeout,
+	}
+}
+
+// Start runs argv in the background. onStart is called first and can veto
+// the run by returning false; onFinish receives the captured output and
+// ErrCancelled, ErrTimedOut or the process error.
+func (r *Runner) Start(id primitive.ObjectID, argv []string, onStart func() bool, onFinish func(output string, err error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+
+	r.mu.Lock()
+	r.cancels[id] = cancel
+	r.mu.Unlock()
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.cancels, id)
+			r.mu.Unlock()
+			cancel()
+		}()
+
+		if ctx.Err() != nil || !onStart() {
+			onFinish("", ErrCancelled)
+			return
+		}
+
+		var output limitedBuffer
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+
+		err := cmd.Run()
+		switch {
+		case errors.Is(ctx.Err(), context.Canceled):
+			err = ErrCancelled
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			err = ErrTimedOut
+		}
+
+		onFinish(output.String(), err)
+	}()
+}
+
+// Cancel stops an execution started by this runner. It reports whether the
+// execution was still being tracked.
+func (r *Runner) Cancel(id primitive.ObjectID) bool {
+	r.mu.Lock()
+	cancel, ok := r.cancels[id]
+	r.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// limitedBuffer keeps the first maxOutput bytes written to it and discards
+// the rest so a chatty tool cannot exhaust memory
+type limitedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if room := maxOutput - b.buf.Len(); room < len(p) {
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		b.truncated = true
+		return len(p), nil
+	}
+
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.truncated {
+		return b.buf.String() + "\n[output truncated]"
+	}
+	return b.buf.String()
+}
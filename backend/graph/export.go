@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"redops/models"
+)
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{ID: "label", For: "node", Name: "label", Type: "string"},
+	{ID: "os", For: "node", Name: "os", Type: "string"},
+	{ID: "roles", For: "node", Name: "roles", Type: "string"},
+	{ID: "first_seen", For: "node", Name: "first_seen", Type: "string"},
+	{ID: "last_seen", For: "node", Name: "last_seen", Type: "string"},
+	{ID: "kind", For: "edge", Name: "kind", Type: "string"},
+	{ID: "port", For: "edge", Name: "port", Type: "string"},
+	{ID: "via", For: "edge", Name: "via", Type: "string"},
+	{ID: "tool", For: "edge", Name: "tool", Type: "string"},
+	{ID: "command", For: "edge", Name: "command", Type: "string"},
+	{ID: "operator", For: "edge", Name: "operator", Type: "string"},
+	{ID: "time", For: "edge", Name: "time", Type: "string"},
+	{ID: "result_id", For: "edge", Name: "result_id", Type: "string"},
+}
+
+// WriteGraphML encodes the graph as GraphML for tools such as yEd or Gephi
+func WriteGraphML(w io.Writer, graph *models.AttackGraph, name string) error {
+	doc := graphMLDocument{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{ID: name, EdgeDefault: "directed"},
+	}
+
+	for _, node := range graph.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: nonEmptyData(
+				graphMLData{Key: "label", Value: node.Label},
+				graphMLData{Key: "os", Value: node.OS},
+				graphMLData{Key: "roles", Value: strings.Join(node.Roles, ",")},
+				graphMLData{Key: "first_seen", Value: formatTime(node.FirstSeen)},
+				graphMLData{Key: "last_seen", Value: formatTime(node.LastSeen)},
+			),
+		})
+	}
+
+	for _, edge := range graph.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     edge.ID,
+			Source: edge.Source,
+			Target: edge.Target,
+			Data: nonEmptyData(
+				graphMLData{Key: "kind", Value: edge.Kind},
+				graphMLData{Key: "port", Value: edge.Port},
+				graphMLData{Key: "via", Value: edge.Via},
+				graphMLData{Key: "tool", Value: edge.Tool},
+				graphMLData{Key: "command", Value: edge.Command},
+				graphMLData{Key: "operator", Value: edge.Operator},
+				graphMLData{Key: "time", Value: formatTime(edge.Time)},
+				graphMLData{Key: "result_id", Value: edge.ResultID.Hex()},
+			),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// WriteDOT encodes the graph in Graphviz DOT format
+func WriteDOT(w io.Writer, graph *models.AttackGraph, name string) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "digraph %s {\n", quoteDOT(name))
+	b.WriteString("  rankdir=LR;\n  node [shape=box];\n")
+
+	for _, node := range graph.Nodes {
+		label := node.Label
+		if node.OS != "" {
+			label += "\n" + node.OS
+		}
+		attrs := fmt.Sprintf("label=%s", quoteDOT(label))
+		if containsString(node.Roles, RolePivot) {
+			attrs += ", style=filled, fillcolor=\"#f4cccc\""
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", quoteDOT(node.ID), attrs)
+	}
+
+	for _, edge := range graph.Edges {
+		var label []string
+		if edge.Tool != "" {
+			label = append(label, edge.Tool)
+		}
+		if edge.Port != "" {
+			label = append(label, "port "+edge.Port)
+		}
+		if edge.Operator != "" {
+			label = append(label, edge.Operator)
+		}
+		if !edge.Time.IsZero() {
+			label = append(label, formatTime(edge.Time))
+		}
+		attrs := fmt.Sprintf("label=%s", quoteDOT(strings.Join(label, "\n")))
+		if edge.Kind == EdgePivot {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", quoteDOT(edge.Source), quoteDOT(edge.Target), attrs)
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func nonEmptyData(data ...graphMLData) []graphMLData {
+	var out []graphMLData
+	for _, d := range data {
+		if d.Value != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func quoteDOT(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
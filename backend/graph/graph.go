@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"redops/models"
+)
+
+const (
+	RoleSource      = "source"
+	RolePivot       = "pivot"
+	RoleDestination = "destination"
+
+	EdgeDirect = "direct"
+	EdgePivot  = "pivot"
+)
+
+// Build turns results into an attack-path graph. A result with a pivot
+// contributes two edges, source to pivot and pivot to destination, so the
+// path through each compromised host stays visible. Hosts are resolved
+// through the assets' aliases, so a merged asset is a single node under its
+// primary host, and assets supply hostnames and operating systems for the
+// node labels.
+func Build(results []models.Result, assets []models.Asset) *models.AttackGraph {
+	nodes := make(map[string]*models.GraphNode)
+	assetsByHost := make(map[string]*models.Asset)
+	for i := range assets {
+		asset := &assets[i]
+		assetsByHost[normalizeHost(asset.Host)] = asset
+		for _, alias := range asset.Aliases {
+			assetsByHost[normalizeHost(alias)] = asset
+		}
+	}
+
+	addNode := func(host, role string, seen time.Time) string {
+		host = normalizeHost(host)
+		if host == "" {
+			return ""
+		}
+		asset, found := assetsByHost[host]
+		if found {
+			host = normalizeHost(asset.Host)
+		}
+
+		node, ok := nodes[host]
+		if !ok {
+			node = &models.GraphNode{ID: host, Label: host, FirstSeen: seen, LastSeen: seen}
+			if found {
+				node.Hostnames = asset.Hostnames
+				node.OS = asset.OS
+				if len(asset.Hostnames) > 0 {
+					node.Label = fmt.Sprintf("%s (%s)", asset.Hostnames[0], host)
+				}
+			}
+			nodes[host] = node
+		}
+
+		if !containsString(node.Roles, role) {
+			node.Roles = append(node.Roles, role)
+		}
+		if seen.Before(node.FirstSeen) {
+			node.FirstSeen = seen
+		}
+		if seen.After(node.LastSeen) {
+			node.LastSeen = seen
+		}
+		return host
+	}
+
+	graph := &models.AttackGraph{Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}}
+	for _, result := range results {
+		seen := result.StartTime
+		if seen.IsZero() {
+			seen = result.CreatedAt
+		}
+
+		source := addNode(result.SourceIP, RoleSource, seen)
+		pivot := addNode(result.PivotIP, RolePivot, seen)
+		destination := addNode(result.DestinationIP, RoleDestination, seen)
+
+		edge := func(from, to, kind, port, via string) {
+			if from == "" || to == "" || from == to {
+				return
+			}
+			graph.Edges = append(graph.Edges, models.GraphEdge{
+				Source:   from,
+				Target:   to,
+				Kind:     kind,
+				Port:     port,
+				Via:      via,
+				Tool:     result.ToolApp,
+				Command:  result.Command,
+				Operator: result.OperatorName,
+				Time:     seen,
+				TaskID:   result.TaskID,
+				ResultID: result.ID,
+			})
+		}
+
+		if pivot != "" {
+			edge(source, pivot, EdgePivot, result.PivotPort, "")
+			edge(pivot, destination, EdgeDirect, result.DestinationPort, pivot)
+		} else {
+			edge(source, destination, EdgeDirect, result.DestinationPort, "")
+		}
+	}
+
+	for _, node := range nodes {
+		sort.Strings(node.Roles)
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].ID < graph.Nodes[j].ID
+	})
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		return graph.Edges[i].Time.Before(graph.Edges[j].Time)
+	})
+	for i := range graph.Edges {
+		graph.Edges[i].ID = fmt.Sprintf("e%d", i)
+	}
+
+	return graph
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSpace(host))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AssetHandler struct {
+	repo *repositories.AssetRepository
+}
+
+type MergeAssetsRequest struct {
+	TargetID  string   `json:"target_id" binding:"required"`
+	SourceIDs []string `json:"source_ids" binding:"required"`
+}
+
+func NewAssetHandler(repo *repositories.AssetRepository) *AssetHandler {
+	return &AssetHandler{repo: repo}
+}
+
+// GetOperationAssets lists the assets of an operation, optionally filtered by
+// host, open port, operating system or a free-text query
+func (h *AssetHandler) GetOperationAssets(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+		return
+	}
+
+	assets, err := h.repo.GetByOperationID(objectID, repositories.AssetFilter{
+		Host:  c.Query("host"),
+		Port:  c.Query("port"),
+		OS:    c.Query("os"),
+		Query: c.Query("q"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, assets)
+}
+
+func (h *AssetHandler) GetAsset(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	asset, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// MergeAssets folds duplicate assets of an operation into a single asset
+func (h *AssetHandler) MergeAssets(c *gin.Context) {
+	operationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+		return
+	}
+
+	var req MergeAssetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	targetID, err := primitive.ObjectIDFromHex(req.TargetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID format"})
+		return
+	}
+
+	var sourceIDs []primitive.ObjectID
+	for _, id := range req.SourceIDs {
+		sourceID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID format"})
+			return
+		}
+		if sourceID != targetID {
+			sourceIDs = append(sourceIDs, sourceID)
+		}
+	}
+	if len(sourceIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one source asset is required"})
+		return
+	}
+
+	target, err := h.repo.GetByID(targetID)
+	if err != nil || target.OperationID != operationID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+
+	asset, err := h.repo.Merge(targetID, sourceIDs)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source asset not found"})
+		case repositories.ErrAssetOperationMismatch:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"log"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"redops/models"
+	"redops/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// osKeywords identify a DestinationSystem value that names an operating
+// system rather than a hostname
+var osKeywords = []string{
+	"windows", "linux", "ubuntu", "debian", "centos", "red hat", "rhel", "fedora",
+	"macos", "mac os", "os x", "freebsd", "openbsd", "solaris", "aix", "android",
+	"ios", "cisco", "junos", "vmware", "esxi",
+}
+
+var (
+	nmapReportLine = regexp.MustCompile(`^Nmap scan report for (\S+)(?: \(([^)]+)\))?`)
+	nmapPortLine   = regexp.MustCompile(`^(\d+)/(tcp|udp|sctp)\s+open\s+(\S+)`)
+	nmapOSLine     = regexp.MustCompile(`^(?:OS details|Running): (.+)$`)
+)
+
+// recordAssets folds observations into the inventory. Failures are logged so
+// that inventory upkeep never fails the import or execution it came from.
+func recordAssets(repo *repositories.AssetRepository, operationID primitive.ObjectID, observations []models.AssetObservation) {
+	for _, obs := range observations {
+		if err := repo.Record(operationID, obs); err != nil {
+			log.Printf("Failed to record asset %s: %v", obs.Host, err)
+		}
+	}
+}
+
+// recordResultAssets adds the hosts named by results to an operation's
+// asset inventory
+func recordResultAssets(repo *repositories.AssetRepository, operationID primitive.ObjectID, results []models.Result) {
+	for i := range results {
+		recordAssets(repo, operationID, observationsFromResult(&results[i]))
+	}
+}
+
+// observationsFromResult extracts the destination and pivot hosts of a result
+func observationsFromResult(result *models.Result) []models.AssetObservation {
+	seen := result.StartTime
+	if seen.IsZero() {
+		seen = result.CreatedAt
+	}
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+
+	target := models.AssetObservation{
+		Host:   strings.TrimSpace(result.DestinationIP),
+		TaskID: result.TaskID,
+		SeenAt: seen,
+	}
+	target.Port, target.Protocol = splitPort(result.DestinationPort)
+
+	if system := strings.TrimSpace(result.DestinationSystem); system != "" {
+		if looksLikeOS(system) {
+			target.OS = system
+		} else {
+			target.Hostname = system
+		}
+	}
+
+	if result.URL != "" {
+		if u, err := url.Parse(result.URL); err == nil && u.Hostname() != "" {
+			if target.Host == "" {
+				target.Host = u.Hostname()
+			} else if net.ParseIP(u.Hostname()) == nil && target.Hostname == "" {
+				target.Hostname = u.Hostname()
+			}
+			if target.Port == "" {
+				target.Port = u.Port()
+				if target.Port == "" {
+					target.Port = defaultPort(u.Scheme)
+				}
+				target.Protocol = "tcp"
+			}
+			target.Service = u.Scheme
+		}
+	}
+
+	if target.Host == "" && target.Hostname != "" {
+		target.Host, target.Hostname = target.Hostname, ""
+	}
+
+	var observations []models.AssetObservation
+	if target.Host != "" {
+		observations = append(observations, target)
+	}
+
+	if pivot := strings.TrimSpace(result.PivotIP); pivot != "" {
+		obs := models.AssetObservation{Host: pivot, TaskID: result.TaskID, SeenAt: seen}
+		obs.Port, obs.Protocol = splitPort(result.PivotPort)
+		observations = append(observations, obs)
+	}
+
+	return observations
+}
+
+// observationsFromExecution extracts hosts from a finished tool execution:
+// the target it was pointed at plus any hosts, open ports and operating
+// systems reported in nmap-style output
+func observationsFromExecution(execution *models.ToolExecution, output string) []models.AssetObservation {
+	seen := execution.EndTime
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+
+	target := firstArgument(execution.Arguments, "target", "host", "ip", "rhost")
+	port, protocol := splitPort(firstArgument(execution.Arguments, "port", "rport"))
+
+	var observations []models.AssetObservation
+	current := models.AssetObservation{Host: target, TaskID: execution.TaskID, SeenAt: seen}
+	if singleHost(target) {
+		obs := current
+		obs.Port, obs.Protocol = port, protocol
+		observations = append(observations, obs)
+	} else {
+		current.Host = ""
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := nmapReportLine.FindStringSubmatch(line); m != nil {
+			current = models.AssetObservation{Host: m[1], TaskID: execution.TaskID, SeenAt: seen}
+			if m[2] != "" {
+				current.Host, current.Hostname = m[2], m[1]
+			}
+			observations = append(observations, current)
+			continue
+		}
+		if current.Host == "" {
+			continue
+		}
+		if m := nmapPortLine.FindStringSubmatch(line); m != nil {
+			obs := current
+			obs.Port, obs.Protocol, obs.Service = m[1], m[2], m[3]
+			observations = append(observations, obs)
+			continue
+		}
+		if m := nmapOSLine.FindStringSubmatch(line); m != nil {
+			obs := current
+			obs.OS = m[1]
+			observations = append(observations, obs)
+		}
+	}
+
+	return observations
+}
+
+// looksLikeOS reports whether a DestinationSystem value names an operating
+// system. Keywords must appear as whole words so hostnames such as bios-mgmt
+// are not mistaken for iOS.
+func looksLikeOS(value string) bool {
+	lower := strings.ToLower(strings.TrimSpace(value))
+	// A single dotted or hyphenated label such as studios-web01 or
+	// linux-dc.corp.local is a hostname even when it contains an OS name
+	if !strings.ContainsAny(lower, " \t") && strings.ContainsAny(lower, ".-") {
+		return false
+	}
+
+	words := " " + strings.Join(strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ") + " "
+	for _, keyword := range osKeywords {
+		if strings.Contains(words, " "+keyword+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPort separates values such as "443/tcp" into port and protocol
+func splitPort(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	port, protocol, found := strings.Cut(value, "/")
+	if !found {
+		return value, ""
+	}
+	if _, err := strconv.Atoi(strings.TrimSpace(port)); err != nil {
+		port, protocol = protocol, port
+	}
+	return strings.TrimSpace(port), strings.ToLower(strings.TrimSpace(protocol))
+}
+
+func defaultPort(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "http", "ws":
+		return "80"
+	case "https", "wss":
+		return "443"
+	case "ftp":
+		return "21"
+	case "ssh":
+		return "22"
+	}
+	return ""
+}
+
+func firstArgument(arguments map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(arguments[key]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// singleHost reports whether a target names one host rather than a range
+func singleHost(target string) bool {
+	if target == "" || strings.ContainsAny(target, "/, *") {
+		return false
+	}
+	if start, _, found := strings.Cut(target, "-"); found && net.ParseIP(start) != nil {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import "testing"
+
+func TestLooksLikeOS(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"Windows Server 2019", true},
+		{"Ubuntu 22.04", true},
+		{"Red Hat Enterprise Linux 8", true},
+		{"Mac OS X 10.15", true},
+		{"Cisco IOS 15.2", true},
+		{"windows", true},
+		{"studios-web01", false},
+		{"bios-mgmt", false},
+		{"chaos", false},
+		{"linux-dc01.corp.local", false},
+		{"fileserver", false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeOS(tt.value); got != tt.want {
+			t.Errorf("looksLikeOS(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"redops/attack"
+	"redops/models"
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AttackHandler struct {
+	repo          *repositories.AttackRepository
+	operationRepo *repositories.OperationRepository
+	taskRepo      *repositories.TaskRepository
+	resultRepo    *repositories.ResultRepository
+}
+
+// SkippedTechnique explains why a layer entry did not become a task
+type SkippedTechnique struct {
+	TechniqueID string `json:"technique_id"`
+	Reason      string `json:"reason"`
+}
+
+func NewAttackHandler(repo *repositories.AttackRepository, operationRepo *repositories.OperationRepository, taskRepo *repositories.TaskRepository, resultRepo *repositories.ResultRepository) *AttackHandler {
+	return &AttackHandler{
+		repo:          repo,
+		operationRepo: operationRepo,
+		taskRepo:      taskRepo,
+		resultRepo:    resultRepo,
+	}
+}
+
+// SearchTechniques looks techniques up by ID prefix or name for the task
+// editor, optionally within one tactic
+func (h *AttackHandler) SearchTechniques(c *gin.Context) {
+	limit := int64(50)
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	techniques, err := h.repo.Search(c.Query("q"), c.Query("tactic"), c.Query("include_deprecated") == "true", limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, techniques)
+}
+
+// GetTechnique returns one technique with its sub-techniques
+func (h *AttackHandler) GetTechnique(c *gin.Context) {
+	techniqueID := attack.Normalize(c.Param("techniqueId"))
+
+	technique, err := h.repo.GetByTechniqueID(techniqueID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Technique not found"})
+		return
+	}
+
+	subtechniques, err := h.repo.Subtechniques(techniqueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"technique":     technique,
+		"subtechniques": subtechniques,
+	})
+}
+
+// ImportCatalogue loads the ATT&CK Enterprise STIX bundle into the catalogue.
+// The bundle is taken from a "bundle" file upload when one is sent and from
+// the server's configured bundle file otherwise. Admins only.
+func (h *AttackHandler) ImportCatalogue(c *gin.Context) {
+	if c.GetString("role") != string(models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can import the ATT&CK catalogue"})
+		return
+	}
+
+	var (
+		techniques []models.AttackTechnique
+		skipped    int
+		source     string
+		err        error
+	)
+	if header, formErr := c.FormFile("bundle"); formErr == nil {
+		source = header.Filename
+		file, openErr := header.Open()
+		if openErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": openErr.Error()})
+			return
+		}
+		defer file.Close()
+		techniques, skipped, err = attack.Parse(file)
+	} else {
+		source = attack.BundlePath()
+		techniques, skipped, err = attack.LoadFile(source)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(techniques) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The bundle contains no ATT&CK techniques"})
+		return
+	}
+
+	if err := h.repo.Import(techniques); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attack.Summarize(source, techniques, skipped))
+}
+
+// GetAttackLayer exports the operation's ATT&CK coverage as a Navigator layer
+func (h *AttackHandler) GetAttackLayer(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	tasks, err := h.taskRepo.GetByOperationID(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var results []models.Result
+	if len(tasks) > 0 {
+		taskIDs := make([]primitive.ObjectID, len(tasks))
+		for i, task := range tasks {
+			taskIDs[i] = task.ID
+		}
+		results, err = h.resultRepo.GetByTaskIDs(taskIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	layer := attack.BuildLayer(operation, tasks, results, time.Now())
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-layer.json"`, objectID.Hex()))
+	}
+	c.JSON(http.StatusOK, layer)
+}
+
+// ImportAttackLayer plans a pending task for each technique in a Navigator
+// layer that the operation does not cover yet. The layer is read from a
+// "layer" file upload or from the JSON body.
+func (h *AttackHandler) ImportAttackLayer(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+	if rejectArchived(c, operation) {
+		return
+	}
+
+	var layer attack.Layer
+	if header, formErr := c.FormFile("layer"); formErr == nil {
+		file, openErr := header.Open()
+		if openErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": openErr.Error()})
+			return
+		}
+		defer file.Close()
+		if err := json.NewDecoder(file).Decode(&layer); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid layer file: " + err.Error()})
+			return
+		}
+	} else if err := c.ShouldBindJSON(&layer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing, err := h.taskRepo.GetByOperationID(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	planned := make(map[string]bool)
+	for _, task := range existing {
+		planned[attack.Normalize(task.MITREID)] = true
+	}
+
+	var ids []string
+	for _, entry := range layer.Techniques {
+		ids = append(ids, attack.Normalize(entry.TechniqueID))
+	}
+	known := make(map[string]models.AttackTechnique)
+	if len(ids) > 0 {
+		techniques, err := h.repo.GetByTechniqueIDs(ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, t := range techniques {
+			known[t.TechniqueID] = t
+		}
+	}
+	count, err := h.repo.Count()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks := []models.Task{}
+	skipped := []SkippedTechnique{}
+	for _, entry := range layer.Techniques {
+		id := attack.Normalize(entry.TechniqueID)
+		technique, isKnown := known[id]
+		switch {
+		case entry.Enabled != nil && !*entry.Enabled:
+			skipped = append(skipped, SkippedTechnique{TechniqueID: id, Reason: "disabled in the layer"})
+			continue
+		case !attack.ValidID(id):
+			skipped = append(skipped, SkippedTechnique{TechniqueID: id, Reason: "not an ATT&CK technique ID"})
+			continue
+		case planned[id]:
+			skipped = append(skipped, SkippedTechnique{TechniqueID: id, Reason: "already planned"})
+			continue
+		case count > 0 && !isKnown:
+			skipped = append(skipped, SkippedTechnique{TechniqueID: id, Reason: "unknown technique"})
+			continue
+		case technique.Deprecated:
+			skipped = append(skipped, SkippedTechnique{TechniqueID: id, Reason: "deprecated technique"})
+			continue
+		}
+		planned[id] = true
+
+		task := models.Task{
+			OperationID: objectID,
+			Title:       id,
+			Description: entry.Comment,
+			Status:      models.StatusPending,
+			Phase:       attack.Phase(entry.Tactic),
+			MITREID:     id,
+			Tools:       []string{},
+		}
+		if isKnown {
+			task.Title = id + " " + technique.Name
+			if task.Phase == "" && len(technique.Tactics) > 0 {
+				task.Phase = attack.Phase(technique.Tactics[0])
+			}
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err := h.taskRepo.CreateMany(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"created": tasks,
+		"skipped": skipped,
+	})
+}
+
+// checkTechnique normalises a task's ATT&CK ID and makes sure it names a
+// current technique in the catalogue. Before a catalogue has been imported
+// only the shape of the ID is checked. It answers the request itself and
+// reports false when the ID is not acceptable.
+func checkTechnique(c *gin.Context, repo *repositories.AttackRepository, task *models.Task) bool {
+	task.MITREID = attack.Normalize(task.MITREID)
+	if task.MITREID == "" {
+		return true
+	}
+
+	if !attack.ValidID(task.MITREID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%q is not an ATT&CK technique ID such as T1059 or T1059.001", task.MITREID)})
+		return false
+	}
+
+	technique, err := repo.GetByTechniqueID(task.MITREID)
+	if err == mongo.ErrNoDocuments {
+		count, countErr := repo.Count()
+		if countErr == nil && count == 0 {
+			return true
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown ATT&CK technique %s", task.MITREID)})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if technique.Deprecated {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ATT&CK technique %s is deprecated", task.MITREID)})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"log"
+
+	"redops/models"
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAudit stamps the entry with the authenticated user and stores it.
+// Failures are logged rather than returned so auditing never blocks a change
+// that has already been applied.
+func recordAudit(repo *repositories.AuditRepository, c *gin.Context, entry models.AuditEntry) {
+	entry.ActorID = c.GetString("userID")
+	entry.ActorName = c.GetString("username")
+
+	if err := repo.Create(&entry); err != nil {
+		log.Printf("Failed to record audit entry %s: %v", entry.Action, err)
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"redops/comments"
+	"redops/models"
+	"redops/repositories"
+	"redops/websocket"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxCommentLength caps the size of a comment body
+const maxCommentLength = 20000
+
+type CommentHandler struct {
+	repo             *repositories.CommentRepository
+	taskRepo         *repositories.TaskRepository
+	operationRepo    *repositories.OperationRepository
+	userRepo         *repositories.UserRepository
+	notificationRepo *repositories.NotificationRepository
+	hub              *websocket.Hub
+}
+
+// CommentRequest carries the markdown body of a new or edited comment. A new
+// comment answers another one of the same task when ParentID is set.
+type CommentRequest struct {
+	Body     string `json:"body" binding:"required"`
+	ParentID string `json:"parent_id"`
+}
+
+func NewCommentHandler(repo *repositories.CommentRepository, taskRepo *repositories.TaskRepository, operationRepo *repositories.OperationRepository, userRepo *repositories.UserRepository, notificationRepo *repositories.NotificationRepository, hub *websocket.Hub) *CommentHandler {
+	return &CommentHandler{
+		repo:             repo,
+		taskRepo:         taskRepo,
+		operationRepo:    operationRepo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		hub:              hub,
+	}
+}
+
+// GetTaskComments returns the discussion of a task, oldest comment first
+func (h *CommentHandler) GetTaskComments(c *gin.Context) {
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	if _, err := h.taskRepo.GetByID(taskObjectID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	comments, err := h.repo.GetByTaskID(taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateComment adds a comment to a task, notifies the users it mentions and
+// pushes it to everyone following the task
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body, ok := commentBody(c, req.Body)
+	if !ok {
+		return
+	}
+
+	task, err := h.taskRepo.GetByID(taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	comment := models.Comment{
+		TaskID:      task.ID,
+		OperationID: task.OperationID,
+		AuthorID:    c.GetString("userID"),
+		AuthorName:  c.GetString("username"),
+		Body:        body,
+		Mentions:    comments.Mentions(body),
+	}
+
+	if req.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(req.ParentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent comment ID format"})
+			return
+		}
+		parent, err := h.repo.GetByID(parentID)
+		if err != nil || parent.TaskID != task.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent comment not found on this task"})
+			return
+		}
+		comment.ParentID = parentID
+	}
+
+	if err := h.repo.Create(&comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notifyMentioned(c, task, &comment, comment.Mentions)
+	h.publish(task.ID, "comment_created", comment)
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateComment lets the author change the body of a comment. The previous
+// body is kept in the edit history and only newly mentioned users are
+// notified.
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	comment, ok := h.comment(c)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != c.GetString("userID") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit a comment"})
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body, ok := commentBody(c, req.Body)
+	if !ok {
+		return
+	}
+	if body == comment.Body {
+		c.JSON(http.StatusOK, comment)
+		return
+	}
+
+	previous := comment.Mentions
+	if err := h.repo.Edit(comment, body, comments.Mentions(body)); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if added := comments.Added(previous, comment.Mentions); len(added) > 0 {
+		if task, err := h.taskRepo.GetByID(comment.TaskID); err == nil {
+			h.notifyMentioned(c, task, comment, added)
+		}
+	}
+	h.publish(comment.TaskID, "comment_updated", comment)
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment removes a comment. Authors can delete their own comments and
+// admins can delete any.
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	comment, ok := h.comment(c)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != c.GetString("userID") && c.GetString("role") != string(models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author or an admin can delete a comment"})
+		return
+	}
+
+	if err := h.repo.Delete(comment.ID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.publish(comment.TaskID, "comment_deleted", gin.H{"id": comment.ID, "task_id": comment.TaskID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// comment loads the comment named in the URL, answering the request itself
+// when it cannot
+func (h *CommentHandler) comment(c *gin.Context) (*models.Comment, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	comment, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+
+	return comment, true
+}
+
+// commentBody trims a comment body and checks it is neither empty nor too
+// long, answering the request itself when it is
+func commentBody(c *gin.Context, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	switch {
+	case body == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment body is required"})
+		return "", false
+	case len(body) > maxCommentLength:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Comment body is longer than %d characters", maxCommentLength)})
+		return "", false
+	}
+	return body, true
+}
+
+// notifyMentioned stores a notification for each mentioned user other than
+// the author and pushes it to their open connections. Only members of the
+// task's operation are notified; names that match no member are ignored.
+func (h *CommentHandler) notifyMentioned(c *gin.Context, task *models.Task, comment *models.Comment, usernames []string) {
+	if len(usernames) == 0 {
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(task.OperationID)
+	if err != nil {
+		log.Printf("Failed to look up operation of comment %s: %v", comment.ID.Hex(), err)
+		return
+	}
+
+	users, err := h.userRepo.GetByUsernames(usernames)
+	if err != nil {
+		log.Printf("Failed to look up mentioned users of comment %s: %v", comment.ID.Hex(), err)
+		return
+	}
+
+	var userIDs []string
+	for _, user := range users {
+		if !operation.IsMember(user.ID) {
+			continue
+		}
+		if userID := user.ID.Hex(); userID != comment.AuthorID {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	notify(h.notificationRepo, h.hub, userIDs, models.Notification{
+		Type:    models.NotificationTypeInfo,
+		Title:   "You were mentioned",
+		Message: fmt.Sprintf("%s mentioned you on task %s", c.GetString("username"), task.Title),
+		Link:    "/operations/" + task.OperationID.Hex() + "/tasks/" + task.ID.Hex(),
+	})
+}
+
+// publish pushes a comment event to the connections following the task
+func (h *CommentHandler) publish(taskID primitive.ObjectID, event string, payload interface{}) {
+	h.hub.Publish(websocket.TaskTopic(taskID.Hex()), websocket.Notification{
+		Type:    event,
+		Payload: payload,
+	})
+}
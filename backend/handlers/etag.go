@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag tags the response with the version of the document it carries
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatch reads the version a client expects from the If-Match header of a
+// PATCH. It returns 0 for "*", which matches any version. A header that
+// cannot be read is answered with 400 and a missing one as described at
+// missingPrecondition; both report false when the request was answered.
+func ifMatch(c *gin.Context) (int64, bool) {
+	version, present, ok := readIfMatch(c)
+	if !ok {
+		return 0, false
+	}
+	if !present {
+		return 0, missingPrecondition(c)
+	}
+	return version, true
+}
+
+// expectedVersion picks the version a full update has to match: the
+// If-Match header first, then the version sent in the body. "*" matches the
+// version that was just read. A request with neither is handled as described
+// at missingPrecondition.
+func expectedVersion(c *gin.Context, body, current int64) (int64, bool) {
+	version, present, ok := readIfMatch(c)
+	switch {
+	case !ok:
+		return 0, false
+	case present && version == 0:
+		return current, true
+	case present:
+		return version, true
+	case body != 0:
+		return body, true
+	}
+	if !missingPrecondition(c) {
+		return 0, false
+	}
+	return current, true
+}
+
+// readIfMatch parses the If-Match header, answering the request itself and
+// reporting false when it cannot be read. The version is 0 for "*".
+func readIfMatch(c *gin.Context) (version int64, present bool, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	switch header {
+	case "":
+		return 0, false, true
+	case "*":
+		return 0, true, true
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+		return 0, true, false
+	}
+	return version, true, true
+}
+
+// missingPrecondition handles an update that names no version. With
+// REQUIRE_IF_MATCH=true it is answered with 428 Precondition Required, so
+// that clients which do not send back what they read cannot silently
+// overwrite someone else's change, and false is returned. Otherwise the
+// update goes ahead against the stored version with a deprecation warning;
+// requiring the precondition becomes the default in the next release.
+func missingPrecondition(c *gin.Context) bool {
+	if os.Getenv("REQUIRE_IF_MATCH") == "true" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Send the ETag of the version you edited in an If-Match header"})
+		return false
+	}
+
+	log.Printf("Deprecated: %s %s without If-Match or version", c.Request.Method, c.FullPath())
+	c.Header("Warning", `299 - "Updates without If-Match are deprecated and will be refused with 428 in the next release"`)
+	return true
+}
+
+// respondConflict answers a write that lost to a concurrent change with the
+// document as it is now stored
+func respondConflict(c *gin.Context, version int64, current interface{}) {
+	setETag(c, version)
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "This was changed by someone else. Review the current version and try again.",
+		"current": current,
+	})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		require string
+		header  string
+		body    int64
+		want    int64
+		ok      bool
+		code    int
+		warning bool
+	}{
+		{name: "header", header: `"3"`, want: 3, ok: true},
+		{name: "weak header", header: `W/"4"`, want: 4, ok: true},
+		{name: "any version", header: "*", want: 7, ok: true},
+		{name: "body version", body: 5, want: 5, ok: true},
+		{name: "bad header", header: "abc", code: http.StatusBadRequest},
+		{name: "missing precondition", want: 7, ok: true, warning: true},
+		{name: "missing precondition required", require: "true", code: http.StatusPreconditionRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REQUIRE_IF_MATCH", tt.require)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+
+			got, ok := expectedVersion(c, tt.body, 7)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("expectedVersion() = %d, %v, want %d, %v", got, ok, tt.want, tt.ok)
+			}
+			if !ok && w.Code != tt.code {
+				t.Errorf("status = %d, want %d", w.Code, tt.code)
+			}
+			if warned := w.Header().Get("Warning") != ""; warned != tt.warning {
+				t.Errorf("Warning header sent = %v, want %v", warned, tt.warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"redops/evidence"
+	"redops/models"
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// multipartOverhead is the room left for the multipart framing and form
+// fields around an evidence file
+const multipartOverhead = 1 << 20
+
+type EvidenceHandler struct {
+	repo          *repositories.EvidenceRepository
+	taskRepo      *repositories.TaskRepository
+	resultRepo    *repositories.ResultRepository
+	operationRepo *repositories.OperationRepository
+}
+
+func NewEvidenceHandler(repo *repositories.EvidenceRepository, taskRepo *repositories.TaskRepository, resultRepo *repositories.ResultRepository, operationRepo *repositories.OperationRepository) *EvidenceHandler {
+	return &EvidenceHandler{
+		repo:          repo,
+		taskRepo:      taskRepo,
+		resultRepo:    resultRepo,
+		operationRepo: operationRepo,
+	}
+}
+
+// UploadTaskEvidence attaches an uploaded file to a task
+func (h *EvidenceHandler) UploadTaskEvidence(c *gin.Context) {
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	task, err := h.taskRepo.GetByID(taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	h.upload(c, &models.Evidence{OperationID: task.OperationID, TaskID: task.ID})
+}
+
+// UploadResultEvidence attaches an uploaded file to a single result
+func (h *EvidenceHandler) UploadResultEvidence(c *gin.Context) {
+	resultObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID format"})
+		return
+	}
+
+	result, err := h.resultRepo.GetByID(resultObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+		return
+	}
+
+	task, err := h.taskRepo.GetByID(result.TaskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	h.upload(c, &models.Evidence{OperationID: task.OperationID, TaskID: task.ID, ResultID: result.ID})
+}
+
+// GetTaskEvidence lists the files attached to a task and its results
+func (h *EvidenceHandler) GetTaskEvidence(c *gin.Context) {
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	if _, err := h.taskRepo.GetByID(taskObjectID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	files, err := h.repo.GetByTaskID(taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, files)
+}
+
+// GetResultEvidence lists the files attached to a result
+func (h *EvidenceHandler) GetResultEvidence(c *gin.Context) {
+	resultObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID format"})
+		return
+	}
+
+	if _, err := h.resultRepo.GetByID(resultObjectID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+		return
+	}
+
+	files, err := h.repo.GetByResultID(resultObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, files)
+}
+
+// GetEvidence returns an evidence file's details and chain of custody
+func (h *EvidenceHandler) GetEvidence(c *gin.Context) {
+	file, ok := h.evidence(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, file)
+}
+
+// DownloadEvidence sends the content of an evidence file as an attachment
+// and records the download in its chain of custody
+func (h *EvidenceHandler) DownloadEvidence(c *gin.Context) {
+	file, ok := h.evidence(c)
+	if !ok {
+		return
+	}
+
+	stream, err := h.repo.Open(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Evidence content could not be read"})
+		return
+	}
+	defer stream.Close()
+
+	event := custodyEvent(c)
+	event.Action = models.CustodyDownloaded
+	if err := h.repo.RecordCustody(file.ID, event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record the download: " + err.Error()})
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, stream, map[string]string{
+		"Content-Disposition":    evidence.Disposition(file.Filename),
+		"X-Content-Type-Options": "nosniff",
+		"X-Checksum-SHA256":      file.SHA256,
+	})
+}
+
+// VerifyEvidence hashes the stored content again, records the check in the
+// chain of custody and reports whether the file is still intact
+func (h *EvidenceHandler) VerifyEvidence(c *gin.Context) {
+	file, ok := h.evidence(c)
+	if !ok {
+		return
+	}
+
+	intact, err := h.repo.Verify(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := custodyEvent(c)
+	event.Action = models.CustodyVerified
+	event.Intact = &intact
+	if err := h.repo.RecordCustody(file.ID, event); err != nil {
+		log.Printf("Failed to record verification of evidence %s: %v", file.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": file.ID, "sha256": file.SHA256, "intact": intact})
+}
+
+// DeleteEvidence moves an evidence file to the trash
+func (h *EvidenceHandler) DeleteEvidence(c *gin.Context) {
+	file, ok := h.evidence(c)
+	if !ok {
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(file.OperationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+	if rejectArchived(c, operation) {
+		return
+	}
+
+	if err := h.repo.Delete(file.ID, custodyEvent(c)); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Evidence not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Evidence deleted successfully"})
+}
+
+// upload stores the file sent in the "file" form field as evidence,
+// answering the request itself
+func (h *EvidenceHandler) upload(c *gin.Context, file *models.Evidence) {
+	operation, err := h.operationRepo.GetByID(file.OperationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+	if rejectArchived(c, operation) {
+		return
+	}
+
+	maxSize := evidence.MaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Evidence files may be at most %d bytes", maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	if header.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Evidence files may be at most %d bytes", maxSize)})
+		return
+	}
+
+	contentType, err := evidence.ContentType(header.Filename)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error opening file"})
+		return
+	}
+	defer src.Close()
+
+	file.Filename = header.Filename
+	file.ContentType = contentType
+	file.Description = c.PostForm("description")
+
+	if err := h.repo.Upload(file, src, maxSize, custodyEvent(c)); err != nil {
+		if err == repositories.ErrEvidenceTooLarge {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Evidence files may be at most %d bytes", maxSize)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, file)
+}
+
+// evidence loads the evidence file named in the URL, answering the request
+// itself when it does not exist or its task or result is in the trash
+func (h *EvidenceHandler) evidence(c *gin.Context) (*models.Evidence, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	file, err := h.repo.GetByID(objectID)
+	if err == nil {
+		_, err = h.taskRepo.GetByID(file.TaskID)
+	}
+	if err == nil && !file.ResultID.IsZero() {
+		_, err = h.resultRepo.GetByID(file.ResultID)
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Evidence not found"})
+		return nil, false
+	}
+
+	return file, true
+}
+
+// custodyEvent identifies the authenticated user for the chain of custody
+func custodyEvent(c *gin.Context) models.CustodyEvent {
+	return models.CustodyEvent{
+		UserID:   c.GetString("userID"),
+		Username: c.GetString("username"),
+		ClientIP: c.ClientIP(),
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"redops/graph"
+	"redops/models"
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type GraphHandler struct {
+	operationRepo *repositories.OperationRepository
+	taskRepo      *repositories.TaskRepository
+	resultRepo    *repositories.ResultRepository
+	assetRepo     *repositories.AssetRepository
+}
+
+func NewGraphHandler(operationRepo *repositories.OperationRepository, taskRepo *repositories.TaskRepository, resultRepo *repositories.ResultRepository, assetRepo *repositories.AssetRepository) *GraphHandler {
+	return &GraphHandler{
+		operationRepo: operationRepo,
+		taskRepo:      taskRepo,
+		resultRepo:    resultRepo,
+		assetRepo:     assetRepo,
+	}
+}
+
+// GetOperationGraph returns the pivot and attack-path graph of an operation
+// as JSON, or as a GraphML or DOT download when format is graphml or dot
+func (h *GraphHandler) GetOperationGraph(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	tasks, err := h.taskRepo.GetByOperationID(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var results []models.Result
+	if len(tasks) > 0 {
+		taskIDs := make([]primitive.ObjectID, len(tasks))
+		for i, task := range tasks {
+			taskIDs[i] = task.ID
+		}
+		results, err = h.resultRepo.GetByTaskIDs(taskIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	assets, err := h.assetRepo.GetByOperationID(objectID, repositories.AssetFilter{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attackGraph := graph.Build(results, assets)
+	filename := unsafeFilenameChars.ReplaceAllString(operation.Name, "_")
+	if filename == "" {
+		filename = operation.ID.Hex()
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, attackGraph)
+	case "graphml":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-attack-path.graphml"`, filename))
+		c.Header("Content-Type", "application/graphml+xml")
+		c.Status(http.StatusOK)
+		if err := graph.WriteGraphML(c.Writer, attackGraph, operation.Name); err != nil {
+			c.Error(err)
+		}
+	case "dot":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-attack-path.dot"`, filename))
+		c.Header("Content-Type", "text/vnd.graphviz")
+		c.Status(http.StatusOK)
+		if err := graph.WriteDOT(c.Writer, attackGraph, operation.Name); err != nil {
+			c.Error(err)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected json, graphml or dot"})
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"redops/executor"
+	"redops/lifecycle"
+	"redops/models"
+	"redops/repositories"
+	"redops/websocket"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HaltHandler struct {
+	operationRepo    *repositories.OperationRepository
+	executionRepo    *repositories.ToolExecutionRepository
+	notificationRepo *repositories.NotificationRepository
+	auditRepo        *repositories.AuditRepository
+	runner           *executor.Runner
+	hub              *websocket.Hub
+}
+
+type HaltOperationRequest struct {
+	Reason string `json:"reason"`
+}
+
+func NewHaltHandler(operationRepo *repositories.OperationRepository, executionRepo *repositories.ToolExecutionRepository, notificationRepo *repositories.NotificationRepository, auditRepo *repositories.AuditRepository, runner *executor.Runner, hub *websocket.Hub) *HaltHandler {
+	return &HaltHandler{
+		operationRepo:    operationRepo,
+		executionRepo:    executionRepo,
+		notificationRepo: notificationRepo,
+		auditRepo:        auditRepo,
+		runner:           runner,
+		hub:              hub,
+	}
+}
+
+// HaltOperation is the emergency stop. It halts the operation, cancels its
+// queued and running executions and alerts every member. Anyone signed in
+// may pull it; only the team lead can resume.
+func (h *HaltHandler) HaltOperation(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var req HaltOperationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	current := lifecycle.Current(operation)
+	if !lifecycle.CanHalt(current) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A " + string(current) + " operation cannot be halted"})
+		return
+	}
+
+	now := time.Now()
+	halt := &models.OperationHalt{
+		Reason:         req.Reason,
+		HaltedBy:       c.GetString("userID"),
+		HaltedAt:       now,
+		PreviousStatus: current,
+	}
+	halted, err := h.operationRepo.Halt(objectID, operation.Status, halt, models.StatusChange{
+		From:      current,
+		To:        models.OperationStatusHalted,
+		ChangedBy: c.GetString("userID"),
+		Reason:    req.Reason,
+		ChangedAt: now,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !halted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Operation status changed, try again"})
+		return
+	}
+
+	cancelled, err := h.executionRepo.CancelActive(objectID)
+	if err != nil {
+		log.Printf("Failed to cancel executions of halted operation %s: %v", objectID.Hex(), err)
+	}
+	for _, id := range cancelled {
+		h.runner.Cancel(id)
+	}
+
+	recordAudit(h.auditRepo, c, models.AuditEntry{
+		OperationID: objectID,
+		Action:      models.AuditOperationHalted,
+		From:        string(current),
+		To:          string(models.OperationStatusHalted),
+		Message:     req.Reason,
+	})
+
+	message := fmt.Sprintf("%s halted operation %s. Stop all testing immediately.", c.GetString("username"), operation.Name)
+	if req.Reason != "" {
+		message += " Reason: " + req.Reason
+	}
+	h.notifyMembers(operation, models.Notification{
+		Type:    models.NotificationTypeUrgent,
+		Title:   "Operation halted",
+		Message: message,
+		Link:    "/operations/" + objectID.Hex(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Operation halted",
+		"halt":                 halt,
+		"cancelled_executions": len(cancelled),
+	})
+}
+
+// ResumeOperation releases the emergency stop. Only the operation's team
+// lead or an admin may resume.
+func (h *HaltHandler) ResumeOperation(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	if c.GetString("userID") != operation.TeamLead.Hex() && c.GetString("role") != string(models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the team lead can resume a halted operation"})
+		return
+	}
+
+	if !operation.Halted() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Operation is not halted"})
+		return
+	}
+
+	previous := models.OperationStatusActive
+	if operation.Halt != nil && lifecycle.Valid(operation.Halt.PreviousStatus) {
+		previous = operation.Halt.PreviousStatus
+	}
+	resumed, err := h.operationRepo.Resume(objectID, models.StatusChange{
+		From:      models.OperationStatusHalted,
+		To:        previous,
+		ChangedBy: c.GetString("userID"),
+		ChangedAt: time.Now(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !resumed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Operation is not halted"})
+		return
+	}
+
+	recordAudit(h.auditRepo, c, models.AuditEntry{
+		OperationID: objectID,
+		Action:      models.AuditOperationResumed,
+		From:        string(models.OperationStatusHalted),
+		To:          string(previous),
+	})
+
+	h.notifyMembers(operation, models.Notification{
+		Type:    models.NotificationTypeInfo,
+		Title:   "Operation resumed",
+		Message: fmt.Sprintf("%s resumed operation %s. Testing may continue.", c.GetString("username"), operation.Name),
+		Link:    "/operations/" + objectID.Hex(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Operation resumed", "status": previous})
+}
+
+// notifyMembers stores a copy of the notification for the team lead and
+// each member and pushes it to their open connections
+func (h *HaltHandler) notifyMembers(operation *models.Operation, notification models.Notification) {
+	var userIDs []string
+	for _, id := range operation.Team() {
+		userIDs = append(userIDs, id.Hex())
+	}
+	notify(h.notificationRepo, h.hub, userIDs, notification)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"redops/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rejectArchived answers 423 Locked and reports true when the operation is
+// archived and so read-only
+func rejectArchived(c *gin.Context, operation *models.Operation) bool {
+	if !operation.Archived() {
+		return false
+	}
+
+	c.JSON(http.StatusLocked, gin.H{"error": "Operation is archived and read-only"})
+	return true
+}
+
+// rejectHalted answers 423 Locked and reports true when the operation's
+// kill switch is engaged or it is archived, so no tool may run and no
+// result may change
+func rejectHalted(c *gin.Context, operation *models.Operation) bool {
+	if rejectArchived(c, operation) {
+		return true
+	}
+	if !operation.Halted() {
+		return false
+	}
+
+	c.JSON(http.StatusLocked, gin.H{
+		"error": "Operation is halted",
+		"halt":  operation.Halt,
+	})
+	return true
+}
+
+// leadsOperation reports whether the signed-in user is the operation's team
+// lead or an admin
+func leadsOperation(c *gin.Context, operation *models.Operation) bool {
+	return c.GetString("userID") == operation.TeamLead.Hex() || c.GetString("role") == string(models.RoleAdmin)
+}
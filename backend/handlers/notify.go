@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log"
+
+	"redops/models"
+	"redops/repositories"
+	"redops/websocket"
+)
+
+// notify stores a copy of the notification for each user and pushes it to
+// their open connections. Failures are logged rather than returned so a
+// notification never blocks a change that has already been applied.
+func notify(repo *repositories.NotificationRepository, hub *websocket.Hub, userIDs []string, notification models.Notification) {
+	for _, userID := range userIDs {
+		n := notification
+		n.UserID = userID
+		if err := repo.Create(&n); err != nil {
+			log.Printf("Failed to store notification for user %s: %v", userID, err)
+			continue
+		}
+		hub.SendToUsers([]string{userID}, websocket.Notification{
+			Type:    websocket.TypeNotification,
+			Payload: n,
+		})
+	}
+}
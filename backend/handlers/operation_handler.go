@@ -1,20 +1,27 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"redops/lifecycle"
 	"redops/models"
 	"redops/repositories"
+	"redops/roe"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type OperationHandler struct {
-	repo *repositories.OperationRepository
+	repo      *repositories.OperationRepository
+	auditRepo *repositories.AuditRepository
 }
 
-func NewOperationHandler(repo *repositories.OperationRepository) *OperationHandler {
-	return &OperationHandler{repo: repo}
+func NewOperationHandler(repo *repositories.OperationRepository, auditRepo *repositories.AuditRepository) *OperationHandler {
+	return &OperationHandler{repo: repo, auditRepo: auditRepo}
 }
 
 func (h *OperationHandler) CreateOperation(c *gin.Context) {
@@ -24,6 +31,17 @@ func (h *OperationHandler) CreateOperation(c *gin.Context) {
 		return
 	}
 
+	if operation.CurrentPhase != "" && !operation.CurrentPhase.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phase"})
+		return
+	}
+
+	// Every operation starts out being planned
+	operation.Status = models.OperationStatusPlanning
+	operation.StatusHistory = nil
+	operation.PhaseHistory = nil
+	operation.Halt = nil
+
 	if err := h.repo.Create(&operation); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -63,22 +81,27 @@ func (h *OperationHandler) GetOperation(c *gin.Context) {
 	taskRepo := repositories.NewTaskRepository()
 	tasks, _ := taskRepo.GetByOperationID(operation.ID)
 
+	setETag(c, operation.Version)
 	c.JSON(http.StatusOK, gin.H{
-		"_id":           operation.ID,
-		"name":          operation.Name,
-		"type":          operation.Type,
-		"description":   operation.Description,
-		"scope":         operation.Scope,
-		"roe":           operation.ROE,
-		"team_lead":     teamLead,
-		"members":       members,
-		"current_phase": operation.CurrentPhase,
-		"status":        operation.Status,
-		"start_date":    operation.StartDate,
-		"end_date":      operation.EndDate,
-		"created_at":    operation.CreatedAt,
-		"updated_at":    operation.UpdatedAt,
-		"tasks":         tasks,
+		"_id":            operation.ID,
+		"name":           operation.Name,
+		"type":           operation.Type,
+		"description":    operation.Description,
+		"scope":          operation.Scope,
+		"roe":            operation.ROE,
+		"team_lead":      teamLead,
+		"members":        members,
+		"current_phase":  operation.CurrentPhase,
+		"phase_history":  operation.PhaseHistory,
+		"status":         operation.Status,
+		"status_history": operation.StatusHistory,
+		"halt":           operation.Halt,
+		"start_date":     operation.StartDate,
+		"end_date":       operation.EndDate,
+		"created_at":     operation.CreatedAt,
+		"updated_at":     operation.UpdatedAt,
+		"version":        operation.Version,
+		"tasks":          tasks,
 	})
 }
 
@@ -90,21 +113,139 @@ func (h *OperationHandler) UpdateOperation(c *gin.Context) {
 		return
 	}
 
+	existing, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	if rejectArchived(c, existing) {
+		return
+	}
+
 	var operation models.Operation
 	if err := c.ShouldBindJSON(&operation); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Status only changes through the lifecycle and halt endpoints
+	if operation.Status != "" && operation.Status != existing.Status {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the status endpoint to change an operation's status"})
+		return
+	}
+
+	if operation.CurrentPhase != "" && !operation.CurrentPhase.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phase"})
+		return
+	}
+
 	operation.ID = objectID
-	if err := h.repo.Update(&operation); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	operation.Status = existing.Status
+	operation.PhaseHistory = nil
+	operation.StatusHistory = nil
+	operation.Halt = existing.Halt
+	// The structured rules only change through the ROE endpoint, which
+	// validates them and is limited to the team lead or an admin
+	operation.ROERules = existing.ROERules
+	operation.CreatedAt = existing.CreatedAt
+	version, ok := expectedVersion(c, operation.Version, existing.Version)
+	if !ok {
 		return
 	}
+	operation.Version = version
+
+	var change *models.PhaseChange
+	if operation.CurrentPhase != existing.CurrentPhase {
+		change = &models.PhaseChange{
+			From:      existing.CurrentPhase,
+			To:        operation.CurrentPhase,
+			ChangedBy: c.GetString("userID"),
+			ChangedAt: time.Now(),
+		}
+	}
+	if err := h.repo.Update(&operation, change); err != nil {
+		switch err {
+		case repositories.ErrVersionConflict:
+			h.respondConflict(c, operation.ID)
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if change != nil {
+		recordAudit(h.auditRepo, c, models.AuditEntry{
+			OperationID: operation.ID,
+			Action:      models.AuditPhaseChanged,
+			From:        string(existing.CurrentPhase),
+			To:          string(operation.CurrentPhase),
+		})
+	}
 
+	setETag(c, operation.Version)
 	c.JSON(http.StatusOK, operation)
 }
 
+// PatchOperation changes only the fields sent in a JSON Merge Patch body
+func (h *OperationHandler) PatchOperation(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	if rejectArchived(c, operation) {
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	set, ok := bindPatch(c, operation, models.OperationPatchFields)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.repo.Patch(objectID, set, version)
+	if err != nil {
+		switch err {
+		case repositories.ErrVersionConflict:
+			h.respondConflict(c, objectID)
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	operation.Version = newVersion
+
+	setETag(c, operation.Version)
+	c.JSON(http.StatusOK, operation)
+}
+
+// respondConflict answers a write that lost to a concurrent change with the
+// operation as it is now stored
+func (h *OperationHandler) respondConflict(c *gin.Context, id primitive.ObjectID) {
+	current, err := h.repo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+	respondConflict(c, current.Version, current)
+}
+
 func (h *OperationHandler) DeleteOperation(c *gin.Context) {
 	id := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -113,16 +254,45 @@ func (h *OperationHandler) DeleteOperation(c *gin.Context) {
 		return
 	}
 
+	operation, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	// Archiving keeps the evidence and makes the operation read-only
+	if c.Query("mode") == "archive" {
+		change, ok := h.transition(c, operation, models.OperationStatusArchived, c.Query("reason"))
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Operation archived successfully", "change": change})
+		return
+	}
+
+	if rejectArchived(c, operation) {
+		return
+	}
+
 	if err := h.repo.Delete(objectID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch err {
+		case repositories.ErrActiveExecutions:
+			c.JSON(http.StatusConflict, gin.H{"error": "Halt the operation or wait for its tool executions to finish before deleting it"})
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Operation deleted successfully"})
 }
 
+// ListOperations returns the operations. Archived operations are only
+// included with include_archived=true.
 func (h *OperationHandler) ListOperations(c *gin.Context) {
-	operations, err := h.repo.List()
+	operations, err := h.repo.List(c.Query("include_archived") == "true")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -148,6 +318,10 @@ func (h *OperationHandler) GetOperationsByTeamMember(c *gin.Context) {
 	c.JSON(http.StatusOK, operations)
 }
 
+type UpdatePhaseRequest struct {
+	Phase models.OperationPhase `json:"phase" binding:"required"`
+}
+
 func (h *OperationHandler) UpdateOperationPhase(c *gin.Context) {
 	id := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -156,16 +330,266 @@ func (h *OperationHandler) UpdateOperationPhase(c *gin.Context) {
 		return
 	}
 
-	var phase models.OperationPhase
-	if err := c.ShouldBindJSON(&phase); err != nil {
+	var req UpdatePhaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !req.Phase.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phase"})
+		return
+	}
 
-	if err := h.repo.UpdatePhase(objectID, phase); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	existing, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+	if rejectArchived(c, existing) {
 		return
 	}
 
+	if req.Phase != existing.CurrentPhase {
+		change := models.PhaseChange{
+			From:      existing.CurrentPhase,
+			To:        req.Phase,
+			ChangedBy: c.GetString("userID"),
+			ChangedAt: time.Now(),
+		}
+		if err := h.repo.UpdatePhase(objectID, change); err != nil {
+			switch err {
+			case repositories.ErrVersionConflict:
+				h.respondConflict(c, objectID)
+			case mongo.ErrNoDocuments:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		recordAudit(h.auditRepo, c, models.AuditEntry{
+			OperationID: objectID,
+			Action:      models.AuditPhaseChanged,
+			From:        string(existing.CurrentPhase),
+			To:          string(req.Phase),
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Operation phase updated successfully"})
 }
+
+// GetPhaseHistory returns the operation's phase changes and how long it has
+// spent in each phase
+func (h *OperationHandler) GetPhaseHistory(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	history := operation.PhaseHistory
+	if history == nil {
+		history = []models.PhaseChange{}
+	}
+	metrics := lifecycle.PhaseMetrics(operation, time.Now())
+	if metrics == nil {
+		metrics = []models.PhaseMetric{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"current_phase": operation.CurrentPhase,
+		"history":       history,
+		"metrics":       metrics,
+	})
+}
+
+type TransitionStatusRequest struct {
+	Status models.OperationStatus `json:"status" binding:"required"`
+	Reason string                 `json:"reason"`
+}
+
+// GetOperationStatus returns the operation's lifecycle status, the statuses
+// the current user may move it to and the history of changes
+func (h *OperationHandler) GetOperationStatus(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	history := operation.StatusHistory
+	if history == nil {
+		history = []models.StatusChange{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  lifecycle.Current(operation),
+		"allowed": lifecycle.Next(operation, lifecycleActor(c)),
+		"history": history,
+	})
+}
+
+// TransitionOperationStatus moves the operation to another lifecycle status
+func (h *OperationHandler) TransitionOperationStatus(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var req TransitionStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operation, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	change, ok := h.transition(c, operation, req.Status, req.Reason)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, change)
+}
+
+func (h *OperationHandler) GetROERules(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	rules := operation.ROERules
+	if rules == nil {
+		rules = &models.ROERules{}
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *OperationHandler) UpdateROERules(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var rules models.ROERules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := roe.Validate(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operation, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+	if !leadsOperation(c, operation) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the team lead can change the rules of engagement"})
+		return
+	}
+	if rejectArchived(c, operation) {
+		return
+	}
+
+	if err := h.repo.UpdateROERules(objectID, &rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// transition moves the operation to another lifecycle status, answering the
+// request itself when the move is refused or fails
+func (h *OperationHandler) transition(c *gin.Context, operation *models.Operation, to models.OperationStatus, reason string) (*models.StatusChange, bool) {
+	if err := lifecycle.Check(operation, to, lifecycleActor(c)); err != nil {
+		status := http.StatusConflict
+		switch {
+		case errors.Is(err, lifecycle.ErrUnknownStatus):
+			status = http.StatusBadRequest
+		case errors.Is(err, lifecycle.ErrForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, lifecycle.ErrMissingFields):
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	now := time.Now()
+	from := lifecycle.Current(operation)
+	change := models.StatusChange{
+		From:      from,
+		To:        to,
+		ChangedBy: c.GetString("userID"),
+		Reason:    reason,
+		ChangedAt: now,
+	}
+
+	set := bson.M{}
+	if to == models.OperationStatusActive && operation.StartDate.IsZero() {
+		set["start_date"] = now
+	}
+	if to == models.OperationStatusClosed && operation.EndDate.IsZero() {
+		set["end_date"] = now
+	}
+
+	changed, err := h.repo.TransitionStatus(operation.ID, operation.Status, change, set)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Operation status changed, try again"})
+		return nil, false
+	}
+
+	recordAudit(h.auditRepo, c, models.AuditEntry{
+		OperationID: operation.ID,
+		Action:      models.AuditStatusChanged,
+		From:        string(from),
+		To:          string(to),
+		Message:     reason,
+	})
+
+	return &change, true
+}
+
+// lifecycleActor identifies the authenticated user for lifecycle checks
+func lifecycleActor(c *gin.Context) lifecycle.Actor {
+	return lifecycle.Actor{
+		UserID: c.GetString("userID"),
+		Role:   models.UserRole(c.GetString("role")),
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"redops/models"
+	"redops/owasp"
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OWASPHandler struct {
+	operationRepo *repositories.OperationRepository
+	taskRepo      *repositories.TaskRepository
+}
+
+// WebAppOperationRequest describes a web application test to seed from the
+// testing guide. Categories limits the tests to some WSTG categories, such
+// as "INPV"; all tests are planned when it is empty.
+type WebAppOperationRequest struct {
+	InstantiateTemplateRequest
+	Categories []string `json:"categories"`
+}
+
+func NewOWASPHandler(operationRepo *repositories.OperationRepository, taskRepo *repositories.TaskRepository) *OWASPHandler {
+	return &OWASPHandler{
+		operationRepo: operationRepo,
+		taskRepo:      taskRepo,
+	}
+}
+
+// ListTop10 returns the OWASP Top 10 (2021) categories
+func (h *OWASPHandler) ListTop10(c *gin.Context) {
+	c.JSON(http.StatusOK, owasp.Top10())
+}
+
+// ListWSTG returns the testing guide's tests, optionally limited to a comma
+// separated list of categories
+func (h *OWASPHandler) ListWSTG(c *gin.Context) {
+	var categories []string
+	if value := c.Query("category"); value != "" {
+		categories = strings.Split(value, ",")
+	}
+
+	c.JSON(http.StatusOK, owasp.WSTG(categories...))
+}
+
+// CreateWebAppOperation creates a penetration test operation with one
+// pending task per testing guide test
+func (h *OWASPHandler) CreateWebAppOperation(c *gin.Context) {
+	var req WebAppOperationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tests := owasp.WSTG(req.Categories...)
+	if len(tests) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No WSTG tests match the requested categories"})
+		return
+	}
+
+	operation := &models.Operation{
+		Name:         req.Name,
+		Type:         models.TypePenTest,
+		Description:  req.Description,
+		Scope:        req.Scope,
+		TeamLead:     req.TeamLead,
+		Members:      req.Members,
+		CurrentPhase: models.OperationPhases[0],
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+	}
+	if operation.Members == nil {
+		operation.Members = []primitive.ObjectID{}
+	}
+
+	tasks := make([]models.Task, len(tests))
+	for i, test := range tests {
+		tasks[i] = models.Task{
+			Title:       test.ID + " " + test.Name,
+			Description: fmt.Sprintf("OWASP Web Security Testing Guide v4.2 test %s, covering %s.", test.ID, test.Top10),
+			Phase:       owasp.Phase(test),
+			OWASPID:     test.ID,
+		}
+	}
+
+	if err := createOperationWithTasks(h.operationRepo, h.taskRepo, operation, tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"operation": operation,
+		"tasks":     tasks,
+	})
+}
+
+// GetOWASPCoverage reports how the operation's tasks cover each Top 10
+// category
+func (h *OWASPHandler) GetOWASPCoverage(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if _, err := h.operationRepo.GetByID(objectID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	tasks, err := h.taskRepo.GetByOperationID(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	untagged := 0
+	for _, task := range tasks {
+		if task.OWASPID == "" {
+			untagged++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": owasp.Coverage(tasks),
+		"untagged":   untagged,
+	})
+}
+
+// checkOWASP normalises a task's OWASP ID and makes sure it names a Top 10
+// category or a testing guide test. It answers the request itself and
+// reports false when the ID is unknown.
+func checkOWASP(c *gin.Context, task *models.Task) bool {
+	task.OWASPID = owasp.Normalize(task.OWASPID)
+	if task.OWASPID == "" || owasp.Valid(task.OWASPID) {
+		return true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%q is not an OWASP Top 10 category such as A03:2021 or a WSTG test such as WSTG-INPV-05", task.OWASPID)})
+	return false
+}
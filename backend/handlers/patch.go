@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"redops/patch"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// bindPatch merges the request's JSON Merge Patch body into target and
+// returns the fields to store. It answers the request itself and reports
+// false when the patch is unreadable or touches a field outside allowed.
+func bindPatch(c *gin.Context, target interface{}, allowed []string) (bson.M, bool) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	set, err := patch.Apply(target, body, allowed)
+	if err != nil {
+		var fieldErr *patch.FieldError
+		if errors.As(err, &fieldErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": fieldErr.Field})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return set, true
+}
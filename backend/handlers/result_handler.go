@@ -1,22 +1,42 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xuri/excelize/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"redops/models"
 	"redops/repositories"
+	"redops/roe"
+	"redops/scope"
+	"redops/utils"
 )
 
 type ResultHandler struct {
-	repo *repositories.ResultRepository
+	repo          *repositories.ResultRepository
+	taskRepo      *repositories.TaskRepository
+	operationRepo *repositories.OperationRepository
+	toolRepo      *repositories.ToolRepository
+	assetRepo     *repositories.AssetRepository
+	auditRepo     *repositories.AuditRepository
 }
 
-func NewResultHandler(repo *repositories.ResultRepository) *ResultHandler {
-	return &ResultHandler{repo: repo}
+func NewResultHandler(repo *repositories.ResultRepository, taskRepo *repositories.TaskRepository, operationRepo *repositories.OperationRepository, toolRepo *repositories.ToolRepository, assetRepo *repositories.AssetRepository, auditRepo *repositories.AuditRepository) *ResultHandler {
+	return &ResultHandler{
+		repo:          repo,
+		taskRepo:      taskRepo,
+		operationRepo: operationRepo,
+		toolRepo:      toolRepo,
+		assetRepo:     assetRepo,
+		auditRepo:     auditRepo,
+	}
 }
 
 // GetTaskResults retrieves all results for a specific task
@@ -39,7 +59,241 @@ func (h *ResultHandler) GetTaskResults(c *gin.Context) {
 	c.JSON(http.StatusOK, results)
 }
 
-// ImportResults imports results from an Excel file
+// CreateResult adds a single result to a task
+func (h *ResultHandler) CreateResult(c *gin.Context) {
+	taskID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var result models.Result
+	if err := c.ShouldBindJSON(&result); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, err := resultTimezone(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	operation, err := h.operationForTask(taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if rejectHalted(c, operation) {
+		return
+	}
+
+	result.ID = primitive.NilObjectID
+	result.TaskID = taskID
+	normaliseResultTimes(&result, loc, utils.ParseTimestamp)
+	flagResultScope(scope.Parse(operation.Scope), &result)
+	if !h.enforceResultROE(c, operation, &result, "New result") {
+		return
+	}
+	if err := h.repo.Create(&result); err != nil {
+		if err == repositories.ErrDuplicateResult {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating result"})
+		return
+	}
+
+	recordResultAssets(h.assetRepo, operation.ID, []models.Result{result})
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetResult retrieves a single result
+func (h *ResultHandler) GetResult(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+		return
+	}
+
+	result, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// UpdateResult replaces the contents of a single result
+func (h *ResultHandler) UpdateResult(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+		return
+	}
+
+	var result models.Result
+	if err := c.ShouldBindJSON(&result); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, err := resultTimezone(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	existing, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+		return
+	}
+
+	operation, err := h.operationForTask(existing.TaskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if rejectHalted(c, operation) {
+		return
+	}
+
+	result.ID = objectID
+	result.TaskID = existing.TaskID
+	normaliseResultTimes(&result, loc, utils.ParseTimestamp)
+	flagResultScope(scope.Parse(operation.Scope), &result)
+	if !h.enforceResultROE(c, operation, &result, "Result "+objectID.Hex()) {
+		return
+	}
+	if err := h.repo.Update(&result); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+			return
+		}
+		if err == repositories.ErrDuplicateResult {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating result"})
+		return
+	}
+
+	recordResultAssets(h.assetRepo, operation.ID, []models.Result{result})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteResult deletes a single result
+func (h *ResultHandler) DeleteResult(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+		return
+	}
+
+	existing, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+		return
+	}
+
+	operation, err := h.operationForTask(existing.TaskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if rejectHalted(c, operation) {
+		return
+	}
+
+	if err := h.repo.Delete(objectID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting result"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Result deleted successfully"})
+}
+
+// ListResults returns a page of results filtered and sorted by query parameters
+func (h *ResultHandler) ListResults(c *gin.Context) {
+	filter := repositories.ResultFilter{
+		SourceIP:        c.Query("sourceIP"),
+		DestinationIP:   c.Query("destinationIP"),
+		DestinationPort: c.Query("destinationPort"),
+		ToolApp:         c.Query("toolApp"),
+		OperatorName:    c.Query("operatorName"),
+		SortBy:          c.Query("sort"),
+		SortDesc:        c.Query("order") == "desc",
+		Cursor:          c.Query("cursor"),
+	}
+
+	if taskID := c.Query("taskId"); taskID != "" {
+		objectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+			return
+		}
+		filter.TaskID = objectID
+	}
+
+	if outOfScope := c.Query("outOfScope"); outOfScope != "" {
+		flagged, err := strconv.ParseBool(outOfScope)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid outOfScope value"})
+			return
+		}
+		filter.OutOfScope = &flagged
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from time, expected RFC3339"})
+			return
+		}
+		filter.From = t
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to time, expected RFC3339"})
+			return
+		}
+		filter.To = t
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	page, err := h.repo.List(filter)
+	if err != nil {
+		if err == repositories.ErrInvalidCursor || err == repositories.ErrInvalidSortField {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ImportResults imports results from an Excel file. The mode parameter
+// selects how rows already present are handled and defaults to skip-duplicates.
 func (h *ResultHandler) ImportResults(c *gin.Context) {
 	taskID := c.Param("taskId")
 
@@ -50,6 +304,34 @@ func (h *ResultHandler) ImportResults(c *gin.Context) {
 		return
 	}
 
+	mode := models.ImportMode(c.Query("mode"))
+	if mode == "" {
+		mode = models.ImportMode(c.PostForm("mode"))
+	}
+	if mode == "" {
+		mode = models.ImportSkipDuplicates
+	}
+	if !mode.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": repositories.ErrInvalidImportMode.Error()})
+		return
+	}
+
+	loc, err := resultTimezone(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	operation, err := h.operationForTask(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if rejectHalted(c, operation) {
+		return
+	}
+	scopeDef := scope.Parse(operation.Scope)
+
 	// Get the uploaded file
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -80,8 +362,11 @@ func (h *ResultHandler) ImportResults(c *gin.Context) {
 		return
 	}
 
-	// Skip header row and process data
+	// Skip header row and process data. Rows that break a blocking rule of
+	// engagement are rejected, the rest are imported with their warnings.
 	var results []models.Result
+	var violations []models.ImportViolation
+	toolTypes := make(map[string]models.ToolType)
 	for i, row := range rows {
 		if i == 0 {
 			continue // Skip header row
@@ -107,15 +392,42 @@ func (h *ResultHandler) ImportResults(c *gin.Context) {
 			Comments:           getCellValue(row, 15),
 			OperatorName:       getCellValue(row, 16),
 		}
+		normaliseResultTimes(&result, loc, utils.ParseSpreadsheetTimestamp)
+		flagResultScope(scopeDef, &result)
+
+		if rowViolations := h.checkResultROE(operation, &result, toolTypes); len(rowViolations) > 0 {
+			rejected := roe.Blocking(rowViolations)
+			violations = append(violations, models.ImportViolation{Row: i + 1, Rejected: rejected, Violations: rowViolations})
+			recordROEViolations(h.auditRepo, c, operation.ID, objectID, fmt.Sprintf("Import row %d", i+1), rowViolations)
+			if rejected {
+				continue
+			}
+		}
+
 		results = append(results, result)
 	}
 
-	// Insert all results
-	if err := h.repo.CreateMany(results); err != nil {
+	// Insert the results according to the import mode
+	summary, err := h.repo.Import(results, mode)
+	if err != nil {
+		if err == repositories.ErrInvalidImportMode {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error inserting results"})
 		return
 	}
 
+	for _, v := range violations {
+		if v.Rejected {
+			summary.Rejected++
+		}
+	}
+	summary.Total += summary.Rejected
+	summary.Violations = violations
+
+	recordResultAssets(h.assetRepo, operation.ID, results)
+
 	// Fetch and return the updated results
 	updatedResults, err := h.repo.GetByTaskID(objectID)
 	if err != nil {
@@ -123,7 +435,10 @@ func (h *ResultHandler) ImportResults(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedResults)
+	c.JSON(http.StatusOK, gin.H{
+		"summary": summary,
+		"results": updatedResults,
+	})
 }
 
 // DeleteTaskResults deletes all results for a specific task
@@ -137,6 +452,15 @@ func (h *ResultHandler) DeleteTaskResults(c *gin.Context) {
 		return
 	}
 
+	operation, err := h.operationForTask(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if rejectHalted(c, operation) {
+		return
+	}
+
 	if err := h.repo.DeleteByTaskID(objectID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting results"})
 		return
@@ -145,6 +469,85 @@ func (h *ResultHandler) DeleteTaskResults(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Results deleted successfully"})
 }
 
+// enforceResultROE checks a single result against the operation's rules of
+// engagement and audits any violations. It answers 403 and returns false
+// when a blocking rule is broken.
+func (h *ResultHandler) enforceResultROE(c *gin.Context, operation *models.Operation, result *models.Result, subject string) bool {
+	violations := h.checkResultROE(operation, result, make(map[string]models.ToolType))
+	recordROEViolations(h.auditRepo, c, operation.ID, result.TaskID, subject, violations)
+	if roe.Blocking(violations) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "Result violates the rules of engagement",
+			"violations": violations,
+		})
+		return false
+	}
+	return true
+}
+
+// checkResultROE evaluates a result against the operation's rules of
+// engagement. Tool types are looked up by the result's tool name and cached.
+func (h *ResultHandler) checkResultROE(operation *models.Operation, result *models.Result, toolTypes map[string]models.ToolType) []models.ROEViolation {
+	if operation.ROERules == nil {
+		return nil
+	}
+
+	name := strings.ToLower(strings.TrimSpace(result.ToolApp))
+	toolType, cached := toolTypes[name]
+	if !cached && name != "" {
+		if tool, err := h.toolRepo.GetByName(name); err == nil {
+			toolType = tool.Type
+		}
+		toolTypes[name] = toolType
+	}
+
+	// Rows without a start time are not checked against the testing hours;
+	// the time of the import says nothing about when the activity happened
+	return roe.Evaluate(operation.ROERules, roe.Action{
+		Phase:            operation.CurrentPhase,
+		ToolType:         toolType,
+		ToolName:         result.ToolApp,
+		Command:          result.Command,
+		Time:             result.StartTime,
+		RecentExecutions: -1,
+	})
+}
+
+// operationForTask loads the operation a task belongs to
+func (h *ResultHandler) operationForTask(taskID primitive.ObjectID) (*models.Operation, error) {
+	task, err := h.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return h.operationRepo.GetByID(task.OperationID)
+}
+
+// resultTimezone returns the location named by the timezone parameter, used
+// for timestamps without an explicit zone, or the configured default.
+func resultTimezone(c *gin.Context) (*time.Location, error) {
+	name := c.Query("timezone")
+	if name == "" {
+		name = c.PostForm("timezone")
+	}
+	if name == "" {
+		return utils.ResultTimezone(), nil
+	}
+	return time.LoadLocation(name)
+}
+
+// normaliseResultTimes fills StartTime and EndTime from the free-text Start
+// and End columns with parse, which is utils.ParseSpreadsheetTimestamp for
+// rows of an xlsx import and utils.ParseTimestamp otherwise. Unparseable
+// values are kept as text only.
+func normaliseResultTimes(result *models.Result, loc *time.Location, parse func(string, *time.Location) (time.Time, error)) {
+	if t, err := parse(result.Start, loc); err == nil {
+		result.StartTime = t
+	}
+	if t, err := parse(result.End, loc); err == nil {
+		result.EndTime = t
+	}
+}
+
 // Helper function to safely get cell value
 func getCellValue(row []string, index int) string {
 	if index < len(row) {
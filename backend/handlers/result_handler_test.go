@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"redops/repositories"
+)
+
+func TestListResultsRejectsBadQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewResultHandler(&repositories.ResultRepository{}, nil, nil, nil, nil, nil)
+	router := gin.New()
+	router.GET("/results", h.ListResults)
+
+	for _, query := range []string{
+		"cursor=bogus!",
+		"sort=output",
+		"from=yesterday",
+		"taskId=123",
+		"limit=0",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/results?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET /results?%s = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestImportResultsRejectsUnknownMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// The mode is checked before anything is loaded, so no repositories are needed
+	h := NewResultHandler(nil, nil, nil, nil, nil, nil)
+	router := gin.New()
+	router.POST("/tasks/:taskId/results/import", h.ImportResults)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/tasks/"+primitive.NewObjectID().Hex()+"/results/import?mode=bogus", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("import with mode=bogus = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"strings"
+
+	"redops/models"
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordROEViolations writes one audit entry per violation so warnings that
+// were allowed through remain visible after the fact
+func recordROEViolations(repo *repositories.AuditRepository, c *gin.Context, operationID, taskID primitive.ObjectID, subject string, violations []models.ROEViolation) {
+	for _, v := range violations {
+		recordAudit(repo, c, models.AuditEntry{
+			OperationID: operationID,
+			TaskID:      taskID,
+			Action:      models.AuditROEViolation,
+			To:          string(v.Enforcement),
+			Message:     strings.TrimSpace(subject + ": " + v.Message),
+		})
+	}
+}
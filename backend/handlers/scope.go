@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"redops/models"
+	"redops/scope"
+)
+
+// flagResultScope marks a result as out of scope when its destination, URL
+// or pivot falls outside the operation's scope at the time it was recorded
+func flagResultScope(def *models.ScopeDefinition, result *models.Result) {
+	result.OutOfScope = false
+	result.ScopeReason = ""
+	if scope.Empty(def) {
+		return
+	}
+
+	at := result.StartTime
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	for _, target := range []string{result.DestinationIP, result.URL, result.PivotIP} {
+		if strings.TrimSpace(target) == "" {
+			continue
+		}
+		verdict := scope.Evaluate(def, target, at)
+		if !verdict.InScope {
+			result.OutOfScope = true
+			result.ScopeReason = fmt.Sprintf("%s: %s", target, verdict.Reason)
+			return
+		}
+	}
+}
+
+// executionTargets collects what a tool execution will be pointed at: the
+// target-style arguments and any argument of the command that is an
+// address, range or URL
+func executionTargets(arguments map[string]string, argv []string) []string {
+	var targets []string
+	for _, key := range []string{"target", "targets", "host", "hosts", "ip", "rhost", "rhosts", "url"} {
+		for _, value := range strings.FieldsFunc(arguments[key], func(r rune) bool {
+			return r == ',' || r == ' '
+		}) {
+			targets = append(targets, value)
+		}
+	}
+
+	// Every argument of the final command counts, including values the tool
+	// definition placed after an option such as --target=10.0.0.1
+	for _, arg := range argv[1:] {
+		if strings.HasPrefix(arg, "-") {
+			_, value, found := strings.Cut(arg, "=")
+			if !found {
+				continue
+			}
+			arg = value
+		}
+		for _, value := range strings.Split(arg, ",") {
+			if looksLikeTarget(value) {
+				targets = append(targets, value)
+			}
+		}
+	}
+
+	return targets
+}
+
+// looksLikeTarget recognises addresses, CIDRs, ranges and URLs. Bare words
+// are ignored because they are as likely to be file names as hostnames.
+func looksLikeTarget(arg string) bool {
+	if strings.HasPrefix(arg, "-") {
+		return false
+	}
+	if strings.Contains(arg, "://") {
+		return true
+	}
+	def := scope.Parse(arg)
+	return len(def.Include) == 1 && def.Include[0].Kind != models.ScopeDomain
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"redops/repositories"
+	"redops/scope"
+	"redops/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ScopeHandler struct {
+	operationRepo *repositories.OperationRepository
+}
+
+type EvaluateScopeRequest struct {
+	Targets []string `json:"targets" binding:"required"`
+	Time    string   `json:"time"`
+}
+
+func NewScopeHandler(operationRepo *repositories.OperationRepository) *ScopeHandler {
+	return &ScopeHandler{operationRepo: operationRepo}
+}
+
+// GetOperationScope returns the structured form of an operation's scope
+func (h *ScopeHandler) GetOperationScope(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scope.Parse(operation.Scope))
+}
+
+// EvaluateScope checks targets against an operation's scope, at the given
+// time or now
+func (h *ScopeHandler) EvaluateScope(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var req EvaluateScopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	at := time.Now()
+	if req.Time != "" {
+		at, err = utils.ParseTimestamp(req.Time, utils.ResultTimezone())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time"})
+			return
+		}
+	}
+
+	operation, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scope.EvaluateAll(scope.Parse(operation.Scope), req.Targets, at))
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"redops/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkAssignee answers 400 and reports false when a task of the operation
+// is being given to someone outside its team. Unassigning is always allowed.
+func (h *TaskHandler) checkAssignee(c *gin.Context, operationID, assignee primitive.ObjectID) bool {
+	if assignee.IsZero() {
+		return true
+	}
+
+	operation, err := h.operationRepo.GetByID(operationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return false
+	}
+	if !operation.IsMember(assignee) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "assigned_to must be the team lead or a member of the operation"})
+		return false
+	}
+	return true
+}
+
+// notifyAssignee tells the new assignee of a task about it, unless they
+// assigned it to themselves
+func (h *TaskHandler) notifyAssignee(c *gin.Context, task *models.Task) {
+	h.notifyAssigned(c, task.AssignedTo, fmt.Sprintf("%s assigned you task %s", c.GetString("username"), task.Title),
+		"/operations/"+task.OperationID.Hex()+"/tasks/"+task.ID.Hex())
+}
+
+func (h *TaskHandler) notifyAssigned(c *gin.Context, assignee primitive.ObjectID, message, link string) {
+	userID := assignee.Hex()
+	if assignee.IsZero() || userID == c.GetString("userID") {
+		return
+	}
+
+	notify(h.notificationRepo, h.hub, []string{userID}, models.Notification{
+		Type:    models.NotificationTypeInfo,
+		Title:   "Task assigned to you",
+		Message: message,
+		Link:    link,
+	})
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"redops/lifecycle"
+	"redops/models"
+	"redops/repositories"
+	"redops/taskgraph"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxBulkTasks caps the number of tasks a single bulk request may change
+const maxBulkTasks = 500
+
+type BulkAction string
+
+const (
+	BulkReassign   BulkAction = "reassign"
+	BulkStatus     BulkAction = "status"
+	BulkPhase      BulkAction = "phase"
+	BulkAddTags    BulkAction = "add_tags"
+	BulkRemoveTags BulkAction = "remove_tags"
+	BulkDelete     BulkAction = "delete"
+)
+
+// BulkTaskRequest applies one action to a list of tasks of an operation.
+// AssignedTo goes with reassign (empty unassigns), Status and Reason with
+// status, Phase with phase and Tags with add_tags and remove_tags.
+type BulkTaskRequest struct {
+	Action     BulkAction            `json:"action" binding:"required"`
+	TaskIDs    []string              `json:"task_ids" binding:"required"`
+	AssignedTo string                `json:"assigned_to"`
+	Status     models.TaskStatus     `json:"status"`
+	Reason     string                `json:"reason"`
+	Phase      models.OperationPhase `json:"phase"`
+	Tags       []string              `json:"tags"`
+}
+
+// BulkTaskResult reports what happened to one task of a bulk request.
+// Unchanged tasks already were as asked.
+type BulkTaskResult struct {
+	ID        string `json:"id"`
+	OK        bool   `json:"ok"`
+	Unchanged bool   `json:"unchanged,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BulkUpdateTasks applies an action to many tasks of an operation at once.
+// The changes are all written in one transaction or not at all: when any
+// task fails, nothing is changed and the report says which tasks failed.
+func (h *TaskHandler) BulkUpdateTasks(c *gin.Context) {
+	operationObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+		return
+	}
+
+	var req BulkTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch {
+	case len(req.TaskIDs) == 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task_ids must list at least one task"})
+		return
+	case len(req.TaskIDs) > maxBulkTasks:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A bulk request may change at most 500 tasks"})
+		return
+	}
+
+	assignee, ok := bulkParameters(c, &req)
+	if !ok {
+		return
+	}
+
+	if !h.operationWritable(c, operationObjectID) {
+		return
+	}
+	if req.Action == BulkReassign && !h.checkAssignee(c, operationObjectID, assignee) {
+		return
+	}
+
+	tasks, err := h.repo.GetByOperationID(operationObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan := planBulk(&req, assignee, tasks, c.GetString("userID"), time.Now())
+
+	if plan.failed > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"applied": false, "failed": plan.failed, "results": plan.results})
+		return
+	}
+
+	if len(plan.changes) > 0 {
+		if err := h.repo.Bulk(operationObjectID, plan.changes); err != nil {
+			var changeErr *repositories.TaskChangeError
+			if !errors.As(err, &changeErr) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			message := changeErr.Err.Error()
+			switch changeErr.Err {
+			case repositories.ErrVersionConflict:
+				message = "Task was changed by someone else, try again"
+			case repositories.ErrActiveExecutions:
+				message = "Wait for the task's tool executions to finish before deleting it"
+			}
+			item := &plan.results[plan.changed[changeErr.Index]]
+			item.OK = false
+			item.Error = message
+			c.JSON(http.StatusConflict, gin.H{"applied": false, "failed": 1, "results": plan.results})
+			return
+		}
+	}
+
+	if req.Action == BulkStatus {
+		for i, change := range plan.changes {
+			recordAudit(h.auditRepo, c, models.AuditEntry{
+				OperationID: operationObjectID,
+				TaskID:      change.ID,
+				Action:      models.AuditTaskStatusChanged,
+				From:        string(plan.statusChanges[i].From),
+				To:          string(plan.statusChanges[i].To),
+				Message:     req.Reason,
+			})
+		}
+	}
+	if len(plan.changes) > 0 && (req.Action == BulkStatus || req.Action == BulkDelete) {
+		h.syncBlocked(c, operationObjectID, nil)
+	}
+	// One notification covers the whole reassignment
+	switch {
+	case req.Action != BulkReassign || len(plan.changes) == 0:
+	case len(plan.changes) == 1:
+		task := plan.tasks[plan.changes[0].ID]
+		task.AssignedTo = assignee
+		h.notifyAssignee(c, task)
+	default:
+		h.notifyAssigned(c, assignee, fmt.Sprintf("%s assigned you %d tasks", c.GetString("username"), len(plan.changes)),
+			"/operations/"+operationObjectID.Hex())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"applied": true, "changed": len(plan.changes), "failed": 0, "results": plan.results})
+}
+
+// bulkPlan holds the changes a bulk request makes and the report on each of
+// its tasks
+type bulkPlan struct {
+	results       []BulkTaskResult
+	changes       []repositories.TaskChange
+	changed       []int // maps each change back to its place in the report
+	statusChanges []models.TaskStatusChange
+	failed        int
+	tasks         map[primitive.ObjectID]*models.Task
+}
+
+// planBulk works out the change for each task of the request in order. Each
+// task is checked against the operation as the earlier items of the request
+// leave it, so completing a task and then one that depends on it in the
+// same request works.
+func planBulk(req *BulkTaskRequest, assignee primitive.ObjectID, tasks []models.Task, userID string, now time.Time) *bulkPlan {
+	plan := &bulkPlan{
+		results: make([]BulkTaskResult, len(req.TaskIDs)),
+		tasks:   make(map[primitive.ObjectID]*models.Task, len(tasks)),
+	}
+	for i := range tasks {
+		plan.tasks[tasks[i].ID] = &tasks[i]
+	}
+	// The graph shares the tasks, so a planned status change is seen by the
+	// items after it
+	graph := taskgraph.New(tasks)
+	seen := make(map[primitive.ObjectID]bool)
+
+	for i, id := range req.TaskIDs {
+		plan.results[i] = BulkTaskResult{ID: id, OK: true}
+		fail := func(message string) {
+			plan.results[i].OK = false
+			plan.results[i].Error = message
+			plan.failed++
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			fail("Invalid task ID format")
+			continue
+		}
+		if seen[taskObjectID] {
+			fail("Task is listed more than once")
+			continue
+		}
+		seen[taskObjectID] = true
+
+		task, found := plan.tasks[taskObjectID]
+		if !found {
+			fail("Task not found in this operation")
+			continue
+		}
+
+		change := repositories.TaskChange{ID: task.ID, Version: task.Version}
+		var statusChange models.TaskStatusChange
+		switch req.Action {
+		case BulkReassign:
+			if task.AssignedTo == assignee {
+				plan.results[i].Unchanged = true
+				continue
+			}
+			change.Update = bson.M{"$set": bson.M{"assigned_to": assignee}}
+		case BulkStatus:
+			if lifecycle.CurrentTask(task) == req.Status {
+				plan.results[i].Unchanged = true
+				continue
+			}
+			if err := lifecycle.CheckTask(task, req.Status); err != nil {
+				fail(err.Error())
+				continue
+			}
+			startsWork := req.Status == models.StatusInProgress || req.Status == models.StatusCompleted
+			if startsWork && graph.Waiting(task.ID) {
+				fail(lifecycle.ErrWaiting.Error())
+				continue
+			}
+			statusChange = models.TaskStatusChange{
+				From:      lifecycle.CurrentTask(task),
+				To:        req.Status,
+				ChangedBy: userID,
+				Reason:    req.Reason,
+				ChangedAt: now,
+			}
+			lifecycle.StampTask(task, req.Status, now)
+			task.Status = req.Status
+			change.Update = bson.M{
+				"$set": bson.M{
+					"status":     req.Status,
+					"start_date": task.StartDate,
+					"end_date":   task.EndDate,
+				},
+				"$push": bson.M{"status_history": statusChange},
+			}
+			if startsWork {
+				change.Check = waitingCheck(task.ID)
+			}
+		case BulkPhase:
+			if task.Phase == req.Phase {
+				plan.results[i].Unchanged = true
+				continue
+			}
+			change.Update = bson.M{"$set": bson.M{"phase": req.Phase}}
+		case BulkAddTags:
+			if containsAll(task.Tags, req.Tags) {
+				plan.results[i].Unchanged = true
+				continue
+			}
+			change.Update = bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": req.Tags}}}
+		case BulkRemoveTags:
+			if !containsAny(task.Tags, req.Tags) {
+				plan.results[i].Unchanged = true
+				continue
+			}
+			change.Update = bson.M{"$pull": bson.M{"tags": bson.M{"$in": req.Tags}}}
+		case BulkDelete:
+			change.Delete = true
+		}
+
+		plan.changes = append(plan.changes, change)
+		plan.changed = append(plan.changed, i)
+		plan.statusChanges = append(plan.statusChanges, statusChange)
+	}
+	return plan
+}
+
+// waitingCheck refuses to start or complete a task while one of its
+// dependencies is unfinished. Bulk runs it against the tasks as the
+// transaction has left them so far.
+func waitingCheck(id primitive.ObjectID) repositories.DependencyCheck {
+	return func(tasks []models.Task) error {
+		for _, task := range tasks {
+			if task.ID == id {
+				if taskgraph.New(tasks).Waiting(id) {
+					return lifecycle.ErrWaiting
+				}
+				return nil
+			}
+		}
+		// A task that is gone fails on its own write
+		return nil
+	}
+}
+
+// bulkParameters checks that the request carries what its action needs,
+// answering the request itself when it does not. It returns the assignee of
+// a reassign.
+func bulkParameters(c *gin.Context, req *BulkTaskRequest) (primitive.ObjectID, bool) {
+	var assignee primitive.ObjectID
+	switch req.Action {
+	case BulkReassign:
+		if req.AssignedTo != "" {
+			id, err := primitive.ObjectIDFromHex(req.AssignedTo)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assigned_to user ID format"})
+				return assignee, false
+			}
+			assignee = id
+		}
+	case BulkStatus:
+		if !lifecycle.ValidTask(req.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, in_progress, blocked or completed"})
+			return assignee, false
+		}
+	case BulkPhase:
+		if req.Phase != "" && !req.Phase.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phase"})
+			return assignee, false
+		}
+	case BulkAddTags, BulkRemoveTags:
+		req.Tags = normalizeTags(req.Tags)
+		if len(req.Tags) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tags must list at least one tag"})
+			return assignee, false
+		}
+	case BulkDelete:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown action, expected reassign, status, phase, add_tags, remove_tags or delete"})
+		return assignee, false
+	}
+	return assignee, true
+}
+
+// normalizeTags trims the tags and drops empty and repeated ones
+func normalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+func containsAll(have, want []string) bool {
+	for _, tag := range want {
+		if !containsAny(have, []string{tag}) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(have, want []string) bool {
+	for _, a := range have {
+		for _, b := range want {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
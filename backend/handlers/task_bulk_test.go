@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"redops/lifecycle"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPlanBulkCompletesBlockerAndDependent(t *testing.T) {
+	blocker := models.Task{ID: primitive.NewObjectID(), Status: models.StatusInProgress, Version: 1}
+	dependent := models.Task{ID: primitive.NewObjectID(), Status: models.StatusInProgress, Version: 1, DependsOn: []primitive.ObjectID{blocker.ID}}
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		order   []primitive.ObjectID
+		failed  int
+		changes int
+	}{
+		{"blocker first", []primitive.ObjectID{blocker.ID, dependent.ID}, 0, 2},
+		{"dependent first", []primitive.ObjectID{dependent.ID, blocker.ID}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &BulkTaskRequest{Action: BulkStatus, Status: models.StatusCompleted}
+			for _, id := range tt.order {
+				req.TaskIDs = append(req.TaskIDs, id.Hex())
+			}
+
+			plan := planBulk(req, primitive.NilObjectID, []models.Task{blocker, dependent}, "user", now)
+			if plan.failed != tt.failed || len(plan.changes) != tt.changes {
+				t.Fatalf("planBulk() failed %d with %d changes, want %d with %d: %+v", plan.failed, len(plan.changes), tt.failed, tt.changes, plan.results)
+			}
+			if tt.failed > 0 && plan.results[0].Error != lifecycle.ErrWaiting.Error() {
+				t.Errorf("dependent error = %q, want %q", plan.results[0].Error, lifecycle.ErrWaiting)
+			}
+		})
+	}
+}
+
+func TestPlanBulkChecksWithinTransaction(t *testing.T) {
+	blocker := models.Task{ID: primitive.NewObjectID(), Status: models.StatusInProgress, Version: 1}
+	dependent := models.Task{ID: primitive.NewObjectID(), Status: models.StatusInProgress, Version: 1, DependsOn: []primitive.ObjectID{blocker.ID}}
+	req := &BulkTaskRequest{Action: BulkStatus, Status: models.StatusCompleted, TaskIDs: []string{blocker.ID.Hex(), dependent.ID.Hex()}}
+
+	plan := planBulk(req, primitive.NilObjectID, []models.Task{blocker, dependent}, "user", time.Now())
+	if len(plan.changes) != 2 || plan.changes[1].Check == nil {
+		t.Fatalf("planBulk() changes = %+v, want the dependent's change checked", plan.changes)
+	}
+
+	// Bulk hands the check the tasks as the transaction has left them
+	check := plan.changes[1].Check
+	stored := []models.Task{blocker, dependent}
+	if err := check(stored); !errors.Is(err, lifecycle.ErrWaiting) {
+		t.Errorf("check before the blocker is written = %v, want ErrWaiting", err)
+	}
+	stored[0].Status = models.StatusCompleted
+	if err := check(stored); err != nil {
+		t.Errorf("check after the blocker is written = %v, want nil", err)
+	}
+}
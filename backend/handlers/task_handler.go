@@ -1,34 +1,97 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"redops/lifecycle"
 	"redops/models"
 	"redops/repositories"
+	"redops/taskgraph"
+	"redops/websocket"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// UpdateTaskStatusRequest moves a task to another status
+type UpdateTaskStatusRequest struct {
+	Status models.TaskStatus `json:"status" binding:"required"`
+	Reason string            `json:"reason"`
+}
+
 type TaskHandler struct {
-	repo *repositories.TaskRepository
+	repo             *repositories.TaskRepository
+	operationRepo    *repositories.OperationRepository
+	attackRepo       *repositories.AttackRepository
+	auditRepo        *repositories.AuditRepository
+	notificationRepo *repositories.NotificationRepository
+	hub              *websocket.Hub
 }
 
-func NewTaskHandler(repo *repositories.TaskRepository) *TaskHandler {
-	return &TaskHandler{repo: repo}
+func NewTaskHandler(repo *repositories.TaskRepository, operationRepo *repositories.OperationRepository, attackRepo *repositories.AttackRepository, auditRepo *repositories.AuditRepository, notificationRepo *repositories.NotificationRepository, hub *websocket.Hub) *TaskHandler {
+	return &TaskHandler{
+		repo:             repo,
+		operationRepo:    operationRepo,
+		attackRepo:       attackRepo,
+		auditRepo:        auditRepo,
+		notificationRepo: notificationRepo,
+		hub:              hub,
+	}
 }
 
 func (h *TaskHandler) CreateTask(c *gin.Context) {
+	operationObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+		return
+	}
+
 	var task models.Task
 	if err := c.ShouldBindJSON(&task); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.repo.Create(&task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !h.operationWritable(c, operationObjectID) {
+		return
+	}
+
+	if !checkTechnique(c, h.attackRepo, &task) || !checkOWASP(c, &task) {
+		return
+	}
+	if !h.checkAssignee(c, operationObjectID, task.AssignedTo) {
 		return
 	}
 
+	task.OperationID = operationObjectID
+
+	// New tasks start as pending; asking for another status is a first
+	// transition, checked, stamped and recorded like any other
+	to := task.Status
+	task.Status = models.StatusPending
+	task.StatusHistory = nil
+	if to != "" && to != models.StatusPending {
+		change, ok := h.checkTransition(c, &task, to, "")
+		if !ok {
+			return
+		}
+		task.Status = to
+		task.StatusHistory = []models.TaskStatusChange{*change}
+	}
+
+	if err := h.repo.Create(&task, dependencyCheck(&task)); err != nil {
+		if !invalidDependencies(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.syncBlocked(c, operationObjectID, &task)
+	h.notifyAssignee(c, &task)
 	c.JSON(http.StatusCreated, task)
 }
 
@@ -54,14 +117,30 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 		return
 	}
 
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
-	id := c.Param("id")
-	objectID, err := primitive.ObjectIDFromHex(id)
+	operationObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+		return
+	}
+
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	existing, err := h.repo.GetByOperationAndTaskID(operationObjectID, taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	if !h.operationWritable(c, operationObjectID) {
 		return
 	}
 
@@ -71,28 +150,214 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	task.ID = objectID
-	if err := h.repo.Update(&task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if task.Phase != "" && !task.Phase.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phase"})
+		return
+	}
+
+	// Tasks keep a technique that has since been deprecated until it is changed
+	if task.MITREID != existing.MITREID && !checkTechnique(c, h.attackRepo, &task) {
+		return
+	}
+	if task.OWASPID != existing.OWASPID && !checkOWASP(c, &task) {
+		return
+	}
+	if task.AssignedTo != existing.AssignedTo && !h.checkAssignee(c, operationObjectID, task.AssignedTo) {
+		return
+	}
+
+	task.ID = taskObjectID
+	task.OperationID = operationObjectID
+	task.CreatedAt = existing.CreatedAt
+	task.StatusHistory = existing.StatusHistory
+	// Dependencies left out of a full update are kept; clear them with a patch
+	if task.DependsOn == nil {
+		task.DependsOn = existing.DependsOn
+	}
+	if task.Status == "" {
+		task.Status = existing.Status
+	}
+	if task.Status != existing.Status {
+		to := task.Status
+		task.Status = existing.Status
+		change, ok := h.checkTransition(c, &task, to, "")
+		if !ok {
+			return
+		}
+		task.Status = to
+		task.StatusHistory = append(task.StatusHistory, *change)
+	}
+	version, ok := expectedVersion(c, task.Version, existing.Version)
+	if !ok {
+		return
+	}
+	task.Version = version
+	if err := h.repo.Update(&task, dependencyCheck(&task)); err != nil {
+		if invalidDependencies(c, err) {
+			return
+		}
+		switch err {
+		case repositories.ErrVersionConflict:
+			h.respondConflict(c, task.ID)
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
+	if task.Status != existing.Status {
+		recordAudit(h.auditRepo, c, models.AuditEntry{
+			OperationID: task.OperationID,
+			TaskID:      task.ID,
+			Action:      models.AuditTaskStatusChanged,
+			From:        string(existing.Status),
+			To:          string(task.Status),
+		})
+	}
+
+	h.syncBlocked(c, operationObjectID, &task)
+	if task.AssignedTo != existing.AssignedTo {
+		h.notifyAssignee(c, &task)
+	}
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
+}
+
+// PatchTask changes only the fields sent in a JSON Merge Patch body
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	operationObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+		return
+	}
+
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	task, err := h.repo.GetByOperationAndTaskID(operationObjectID, taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	if !h.operationWritable(c, operationObjectID) {
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	previousAssignee := task.AssignedTo
+	set, ok := bindPatch(c, task, models.TaskPatchFields)
+	if !ok {
+		return
+	}
+
+	if task.Phase != "" && !task.Phase.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phase"})
+		return
+	}
+
+	if _, changesTechnique := set["mitre_id"]; changesTechnique {
+		if !checkTechnique(c, h.attackRepo, task) {
+			return
+		}
+		set["mitre_id"] = task.MITREID
+	}
+	if _, changesOWASP := set["owasp_id"]; changesOWASP {
+		if !checkOWASP(c, task) {
+			return
+		}
+		set["owasp_id"] = task.OWASPID
+	}
+	var check repositories.DependencyCheck
+	if _, changesDependencies := set["depends_on"]; changesDependencies {
+		check = dependencyCheck(task)
+	}
+	reassigned := task.AssignedTo != previousAssignee
+	if reassigned && !h.checkAssignee(c, operationObjectID, task.AssignedTo) {
+		return
+	}
+
+	newVersion, err := h.repo.Patch(operationObjectID, taskObjectID, set, version, check)
+	if err != nil {
+		if invalidDependencies(c, err) {
+			return
+		}
+		switch err {
+		case repositories.ErrVersionConflict:
+			h.respondConflict(c, taskObjectID)
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	task.Version = newVersion
+
+	h.syncBlocked(c, operationObjectID, task)
+	if reassigned {
+		h.notifyAssignee(c, task)
+	}
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
+// respondConflict answers a write that lost to a concurrent change with the
+// task as it is now stored
+func (h *TaskHandler) respondConflict(c *gin.Context, id primitive.ObjectID) {
+	current, err := h.repo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	respondConflict(c, current.Version, current)
+}
+
+// DeleteTask removes a task with its results and executions
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
-	id := c.Param("id")
-	objectID, err := primitive.ObjectIDFromHex(id)
+	operationObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
 		return
 	}
 
-	if err := h.repo.Delete(objectID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	if _, err := h.repo.GetByOperationAndTaskID(operationObjectID, taskObjectID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	if !h.operationWritable(c, operationObjectID) {
 		return
 	}
 
+	if err := h.repo.Delete(taskObjectID); err != nil {
+		switch err {
+		case repositories.ErrActiveExecutions:
+			c.JSON(http.StatusConflict, gin.H{"error": "Wait for the task's tool executions to finish before deleting it"})
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.syncBlocked(c, operationObjectID, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
 
@@ -140,26 +405,91 @@ func (h *TaskHandler) GetTasksByAssignedUser(c *gin.Context) {
 	c.JSON(http.StatusOK, tasks)
 }
 
+// GetTaskStatus returns the task's status, the statuses it may move to and
+// the history of changes
+func (h *TaskHandler) GetTaskStatus(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	task, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	history := task.StatusHistory
+	if history == nil {
+		history = []models.TaskStatusChange{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  lifecycle.CurrentTask(task),
+		"allowed": lifecycle.NextTask(task),
+		"history": history,
+	})
+}
+
+// UpdateTaskStatus moves the task to another status, stamping its start date
+// when work begins and its end date when it is completed
 func (h *TaskHandler) UpdateTaskStatus(c *gin.Context) {
-	id := c.Param("id")
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
 		return
 	}
 
-	var status models.TaskStatus
-	if err := c.ShouldBindJSON(&status); err != nil {
+	var req UpdateTaskStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.repo.UpdateStatus(objectID, status); err != nil {
+	task, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	if !h.operationWritable(c, task.OperationID) {
+		return
+	}
+
+	from := task.Status
+	change, ok := h.checkTransition(c, task, req.Status, req.Reason)
+	if !ok {
+		return
+	}
+
+	set := bson.M{"start_date": task.StartDate, "end_date": task.EndDate}
+	changed, err := h.repo.TransitionStatus(objectID, from, *change, set)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task status changed, try again"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Task status updated successfully"})
+	task.Status = change.To
+	task.StatusHistory = append(task.StatusHistory, *change)
+	task.Version++
+
+	recordAudit(h.auditRepo, c, models.AuditEntry{
+		OperationID: task.OperationID,
+		TaskID:      task.ID,
+		Action:      models.AuditTaskStatusChanged,
+		From:        string(change.From),
+		To:          string(change.To),
+		Message:     req.Reason,
+	})
+
+	h.syncBlocked(c, task.OperationID, task)
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
 }
 
 func (h *TaskHandler) UpdateTaskResults(c *gin.Context) {
@@ -176,6 +506,16 @@ func (h *TaskHandler) UpdateTaskResults(c *gin.Context) {
 		return
 	}
 
+	existing, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	if !h.operationWritable(c, existing.OperationID) {
+		return
+	}
+
 	if err := h.repo.UpdateResults(objectID, results); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -183,3 +523,171 @@ func (h *TaskHandler) UpdateTaskResults(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task results updated successfully"})
 }
+
+// operationWritable loads the task's operation and answers the request
+// itself when the operation is missing or archived
+func (h *TaskHandler) operationWritable(c *gin.Context, operationID primitive.ObjectID) bool {
+	operation, err := h.operationRepo.GetByID(operationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return false
+	}
+	return !rejectArchived(c, operation)
+}
+
+// GetTaskPlan returns the operation's tasks in dependency order together with
+// the critical path, the dependent chain with the most estimated hours
+func (h *TaskHandler) GetTaskPlan(c *gin.Context) {
+	operationObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+		return
+	}
+
+	if _, err := h.operationRepo.GetByID(operationObjectID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	tasks, err := h.repo.GetByOperationID(operationObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	graph := taskgraph.New(tasks)
+	order, err := graph.Order()
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	path, hours, err := graph.CriticalPath()
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	planned := func(ids []primitive.ObjectID) []models.Task {
+		out := make([]models.Task, len(ids))
+		for i, id := range ids {
+			out[i] = *graph.Task(id)
+		}
+		return out
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order":               planned(order),
+		"critical_path":       planned(path),
+		"critical_path_hours": hours,
+	})
+}
+
+// checkTransition makes sure the task may move to status and stamps its
+// dates for the move. It answers the request itself and reports false when
+// the move is refused, otherwise it returns the change for the task's status
+// history.
+func (h *TaskHandler) checkTransition(c *gin.Context, task *models.Task, to models.TaskStatus, reason string) (*models.TaskStatusChange, bool) {
+	if err := lifecycle.CheckTask(task, to); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, lifecycle.ErrUnknownTaskStatus) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// Dependencies have to be finished before work on the task can start
+	if to == models.StatusInProgress || to == models.StatusCompleted {
+		tasks, err := h.repo.GetByOperationID(task.OperationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		graph := taskgraph.New(tasks)
+		if graph.Set(*task) == nil && graph.Waiting(task.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": lifecycle.ErrWaiting.Error()})
+			return nil, false
+		}
+	}
+
+	now := time.Now()
+	from := lifecycle.CurrentTask(task)
+	lifecycle.StampTask(task, to, now)
+
+	return &models.TaskStatusChange{
+		From:      from,
+		To:        to,
+		ChangedBy: c.GetString("userID"),
+		Reason:    reason,
+		ChangedAt: now,
+	}, true
+}
+
+// dependencyCheck makes sure a task only depends on tasks of its own
+// operation and that its dependencies do not form a cycle. It runs within
+// the task's write, see repositories.DependencyCheck; tasks without
+// dependencies need no check.
+func dependencyCheck(task *models.Task) repositories.DependencyCheck {
+	if len(task.DependsOn) == 0 {
+		return nil
+	}
+	return func(tasks []models.Task) error {
+		return taskgraph.New(tasks).Set(*task)
+	}
+}
+
+// invalidDependencies answers a write that its dependency check rejected
+func invalidDependencies(c *gin.Context, err error) bool {
+	var dependencyErr *repositories.DependencyError
+	if !errors.As(err, &dependencyErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dependencies: " + dependencyErr.Error()})
+	return true
+}
+
+// syncBlocked blocks pending tasks whose dependencies are not all completed
+// and releases blocked tasks whose dependencies now are. When changed is one
+// of the operation's tasks it is updated to match.
+func (h *TaskHandler) syncBlocked(c *gin.Context, operationID primitive.ObjectID, changed *models.Task) {
+	tasks, err := h.repo.GetByOperationID(operationID)
+	if err != nil {
+		log.Printf("Failed to check task dependencies of operation %s: %v", operationID.Hex(), err)
+		return
+	}
+
+	graph := taskgraph.New(tasks)
+	for id, status := range graph.StatusUpdates() {
+		task := graph.Task(id)
+		change := models.TaskStatusChange{
+			From:      task.Status,
+			To:        status,
+			ChangedBy: c.GetString("userID"),
+			Reason:    taskgraph.AutomaticReason,
+			ChangedAt: time.Now(),
+		}
+		moved, err := h.repo.TransitionStatus(id, task.Status, change, nil)
+		if err != nil {
+			log.Printf("Failed to update status of task %s: %v", id.Hex(), err)
+			continue
+		}
+		if !moved {
+			continue
+		}
+
+		recordAudit(h.auditRepo, c, models.AuditEntry{
+			OperationID: operationID,
+			TaskID:      id,
+			Action:      models.AuditTaskStatusChanged,
+			From:        string(task.Status),
+			To:          string(status),
+			Message:     taskgraph.AutomaticReason,
+		})
+
+		if changed != nil && changed.ID == id {
+			changed.Status = status
+			changed.StatusHistory = append(changed.StatusHistory, change)
+			changed.Version++
+		}
+	}
+}
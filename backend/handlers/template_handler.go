@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"redops/models"
+	"redops/owasp"
+	"redops/repositories"
+	"redops/roe"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TemplateHandler struct {
+	repo          *repositories.TemplateRepository
+	operationRepo *repositories.OperationRepository
+	taskRepo      *repositories.TaskRepository
+}
+
+// InstantiateTemplateRequest carries the engagement specifics a template
+// leaves open
+type InstantiateTemplateRequest struct {
+	Name        string               `json:"name" binding:"required"`
+	Description string               `json:"description"`
+	Scope       string               `json:"scope"`
+	TeamLead    primitive.ObjectID   `json:"team_lead"`
+	Members     []primitive.ObjectID `json:"members"`
+	StartDate   time.Time            `json:"start_date"`
+	EndDate     time.Time            `json:"end_date"`
+}
+
+type CloneOperationRequest struct {
+	Name string `json:"name"`
+}
+
+func NewTemplateHandler(repo *repositories.TemplateRepository, operationRepo *repositories.OperationRepository, taskRepo *repositories.TaskRepository) *TemplateHandler {
+	return &TemplateHandler{
+		repo:          repo,
+		operationRepo: operationRepo,
+		taskRepo:      taskRepo,
+	}
+}
+
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	if !managesTemplates(c) {
+		return
+	}
+
+	var template models.OperationTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateTemplate(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template.ID = primitive.NilObjectID
+	template.CreatedBy = c.GetString("userID")
+	if err := h.repo.Create(&template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.repo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	template, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	if !managesTemplates(c) {
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var template models.OperationTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateTemplate(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template.ID = objectID
+	if err := h.repo.Update(&template); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	if !managesTemplates(c) {
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if err := h.repo.Delete(objectID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// InstantiateTemplate creates a new operation and its task plan from a template
+func (h *TemplateHandler) InstantiateTemplate(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	operation := &models.Operation{
+		Name:         req.Name,
+		Type:         template.Type,
+		Description:  req.Description,
+		Scope:        req.Scope,
+		ROE:          template.ROE,
+		ROERules:     template.ROERules,
+		TeamLead:     req.TeamLead,
+		Members:      req.Members,
+		CurrentPhase: models.OperationPhases[0],
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+	}
+	if operation.Description == "" {
+		operation.Description = template.Description
+	}
+	if operation.Members == nil {
+		operation.Members = []primitive.ObjectID{}
+	}
+
+	tasks := make([]models.Task, len(template.Tasks))
+	for i, t := range template.Tasks {
+		tasks[i] = models.Task{
+			Title:       t.Title,
+			Description: t.Description,
+			Phase:       t.Phase,
+			MITREID:     t.MITREID,
+			OWASPID:     t.OWASPID,
+			Tools:       t.Tools,
+		}
+	}
+
+	if err := createOperationWithTasks(h.operationRepo, h.taskRepo, operation, tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"operation": operation,
+		"tasks":     tasks,
+	})
+}
+
+// CloneOperation copies an operation's set-up and task plan into a new
+// operation in planning. Results, executions, assets, history and task
+// assignments stay behind, so the copied tasks start unassigned.
+func (h *TemplateHandler) CloneOperation(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var req CloneOperationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	sourceTasks, err := h.taskRepo.GetByOperationID(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = fmt.Sprintf("Copy of %s", source.Name)
+	}
+
+	operation := &models.Operation{
+		Name:         name,
+		Type:         source.Type,
+		Description:  source.Description,
+		Scope:        source.Scope,
+		ROE:          source.ROE,
+		ROERules:     source.ROERules,
+		TeamLead:     source.TeamLead,
+		Members:      source.Members,
+		CurrentPhase: models.OperationPhases[0],
+	}
+	if operation.Members == nil {
+		operation.Members = []primitive.ObjectID{}
+	}
+
+	tasks := make([]models.Task, len(sourceTasks))
+	for i, t := range sourceTasks {
+		tasks[i] = models.Task{
+			Title:       t.Title,
+			Description: t.Description,
+			Phase:       t.Phase,
+			MITREID:     t.MITREID,
+			OWASPID:     t.OWASPID,
+			Tools:       t.Tools,
+		}
+	}
+
+	if err := createOperationWithTasks(h.operationRepo, h.taskRepo, operation, tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"operation": operation,
+		"tasks":     tasks,
+	})
+}
+
+// managesTemplates answers 403 and reports false unless the user is a team
+// lead or an admin, the roles that curate the shared templates
+func managesTemplates(c *gin.Context) bool {
+	switch models.UserRole(c.GetString("role")) {
+	case models.RoleAdmin, models.RoleTeamLead:
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Only team leads and admins can change templates"})
+	return false
+}
+
+// createOperationWithTasks stores a new operation in planning with its
+// pending tasks. If the tasks cannot be stored the operation is removed
+// again so a failed call leaves nothing behind.
+func createOperationWithTasks(operationRepo *repositories.OperationRepository, taskRepo *repositories.TaskRepository, operation *models.Operation, tasks []models.Task) error {
+	operation.Status = models.OperationStatusPlanning
+	if err := operationRepo.Create(operation); err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		tasks[i].OperationID = operation.ID
+		tasks[i].Status = models.StatusPending
+		if tasks[i].Tools == nil {
+			tasks[i].Tools = []string{}
+		}
+	}
+
+	if err := taskRepo.CreateMany(tasks); err != nil {
+		// Removing the operation also removes any tasks that were stored
+		if cleanupErr := operationRepo.Remove(operation.ID); cleanupErr != nil {
+			log.Printf("Failed to remove operation %s: %v", operation.ID.Hex(), cleanupErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// validateTemplate checks the task plan and rules of engagement a template
+// will hand to new operations
+func validateTemplate(template *models.OperationTemplate) error {
+	for i, task := range template.Tasks {
+		if strings.TrimSpace(task.Title) == "" {
+			return fmt.Errorf("task %d needs a title", i+1)
+		}
+		if task.Phase != "" && !task.Phase.Valid() {
+			return fmt.Errorf("task %d has unknown phase %q", i+1, task.Phase)
+		}
+		if task.OWASPID != "" && !owasp.Valid(task.OWASPID) {
+			return fmt.Errorf("task %d has unknown OWASP ID %q", i+1, task.OWASPID)
+		}
+	}
+	if template.ROERules != nil {
+		if err := roe.Validate(template.ROERules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
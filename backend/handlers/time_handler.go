@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"redops/models"
+	"redops/repositories"
+	"redops/timesheet"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxManualEntry is the longest stretch a single manual entry may cover
+const maxManualEntry = 24 * time.Hour
+
+type TimeHandler struct {
+	repo          *repositories.TimeEntryRepository
+	taskRepo      *repositories.TaskRepository
+	operationRepo *repositories.OperationRepository
+}
+
+// TimeEntryRequest records time by hand. The entry either runs from Start to
+// End or lasts Hours from Start.
+type TimeEntryRequest struct {
+	Start time.Time `json:"start" binding:"required"`
+	End   time.Time `json:"end"`
+	Hours float64   `json:"hours"`
+	Note  string    `json:"note"`
+}
+
+// TimerRequest optionally annotates a timer
+type TimerRequest struct {
+	Note string `json:"note"`
+}
+
+func NewTimeHandler(repo *repositories.TimeEntryRepository, taskRepo *repositories.TaskRepository, operationRepo *repositories.OperationRepository) *TimeHandler {
+	return &TimeHandler{repo: repo, taskRepo: taskRepo, operationRepo: operationRepo}
+}
+
+// StartTimer starts the current user's timer on a task
+func (h *TimeHandler) StartTimer(c *gin.Context) {
+	task, ok := h.writableTask(c)
+	if !ok {
+		return
+	}
+
+	var req TimerRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	entry := models.TimeEntry{
+		OperationID: task.OperationID,
+		TaskID:      task.ID,
+		UserID:      c.GetString("userID"),
+		Username:    c.GetString("username"),
+		Start:       time.Now(),
+		Running:     true,
+		Note:        req.Note,
+	}
+	if err := h.repo.Create(&entry); err != nil {
+		if err == repositories.ErrTimerRunning {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// StopTimer stops the current user's running timer on a task
+func (h *TimeHandler) StopTimer(c *gin.Context) {
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	entry, err := h.repo.Stop(taskObjectID, c.GetString("userID"), time.Now())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No timer is running for this task"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetRunningTimers returns the timers the current user has running
+func (h *TimeHandler) GetRunningTimers(c *gin.Context) {
+	entries, err := h.repo.GetRunning(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// CreateTimeEntry books time on a task by hand for the current user
+func (h *TimeHandler) CreateTimeEntry(c *gin.Context) {
+	task, ok := h.writableTask(c)
+	if !ok {
+		return
+	}
+
+	var req TimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	end, ok := manualEnd(c, req)
+	if !ok {
+		return
+	}
+
+	entry := models.TimeEntry{
+		OperationID: task.OperationID,
+		TaskID:      task.ID,
+		UserID:      c.GetString("userID"),
+		Username:    c.GetString("username"),
+		Start:       req.Start,
+		End:         end,
+		Manual:      true,
+		Note:        req.Note,
+	}
+	if err := h.repo.Create(&entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// GetTaskTimeEntries returns the time booked on a task with its total
+func (h *TimeHandler) GetTaskTimeEntries(c *gin.Context) {
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	task, err := h.taskRepo.GetByID(taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	entries, err := h.repo.GetByTaskID(taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := timesheet.Build(task.OperationID, entries, []models.Task{*task}, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"entries":     entries,
+		"total_hours": report.TotalHours,
+		"by_operator": report.ByOperator,
+	})
+}
+
+// UpdateTimeEntry changes the start, end and note of a stopped entry. Users
+// edit their own entries, admins anyone's.
+func (h *TimeHandler) UpdateTimeEntry(c *gin.Context) {
+	entry, ok := h.ownEntry(c)
+	if !ok {
+		return
+	}
+	if entry.Running {
+		c.JSON(http.StatusConflict, gin.H{"error": "Stop the timer before editing the entry"})
+		return
+	}
+
+	var req TimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	end, ok := manualEnd(c, req)
+	if !ok {
+		return
+	}
+
+	entry.Start = req.Start
+	entry.End = end
+	entry.Note = req.Note
+	if err := h.repo.Update(entry); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteTimeEntry removes an entry. Users delete their own entries, admins
+// anyone's.
+func (h *TimeHandler) DeleteTimeEntry(c *gin.Context) {
+	entry, ok := h.ownEntry(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(entry.ID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Time entry deleted successfully"})
+}
+
+// GetTimeReport rolls up the time booked on an operation per task, phase and
+// operator as JSON, or exports the entries when format is csv or xlsx. The
+// from and to parameters (YYYY-MM-DD or RFC 3339) limit the entries to those
+// started in that period, to bill it.
+func (h *TimeHandler) GetTimeReport(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	from, err := reportBound(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	to, err := reportBound(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	tasks, err := h.taskRepo.GetByOperationID(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	entries, err := h.repo.GetByOperationID(objectID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	report := timesheet.Build(objectID, entries, tasks, now)
+	filename := unsafeFilenameChars.ReplaceAllString(operation.Name, "_")
+	if filename == "" {
+		filename = operation.ID.Hex()
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, report)
+	case "csv":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-time.csv"`, filename))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := timesheet.WriteCSV(c.Writer, entries, tasks, now); err != nil {
+			c.Error(err)
+		}
+	case "xlsx":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-time.xlsx"`, filename))
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Status(http.StatusOK)
+		if err := timesheet.WriteXLSX(c.Writer, report, entries, tasks, now); err != nil {
+			c.Error(err)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected json, csv or xlsx"})
+	}
+}
+
+// writableTask loads the task named in the URL and makes sure its operation
+// still takes changes, answering the request itself when it does not
+func (h *TimeHandler) writableTask(c *gin.Context) (*models.Task, bool) {
+	taskObjectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return nil, false
+	}
+
+	task, err := h.taskRepo.GetByID(taskObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return nil, false
+	}
+
+	operation, err := h.operationRepo.GetByID(task.OperationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return nil, false
+	}
+	if rejectArchived(c, operation) {
+		return nil, false
+	}
+
+	return task, true
+}
+
+// ownEntry loads the entry named in the URL when the current user may change
+// it, answering the request itself otherwise
+func (h *TimeHandler) ownEntry(c *gin.Context) (*models.TimeEntry, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	entry, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+		return nil, false
+	}
+
+	if entry.UserID != c.GetString("userID") && c.GetString("role") != string(models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the operator who booked the time or an admin can change it"})
+		return nil, false
+	}
+
+	operation, err := h.operationRepo.GetByID(entry.OperationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return nil, false
+	}
+	if rejectArchived(c, operation) {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// manualEnd works out and checks the end of a manual entry, answering the
+// request itself when it is invalid
+func manualEnd(c *gin.Context, req TimeEntryRequest) (time.Time, bool) {
+	end := req.End
+	if end.IsZero() {
+		if req.Hours <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Either end or a positive number of hours is required"})
+			return time.Time{}, false
+		}
+		end = req.Start.Add(time.Duration(req.Hours * float64(time.Hour)))
+	}
+
+	switch {
+	case !end.After(req.Start):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End must be after start"})
+		return time.Time{}, false
+	case end.Sub(req.Start) > maxManualEntry:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A time entry may cover at most 24 hours"})
+		return time.Time{}, false
+	case end.After(time.Now()):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Time cannot be booked in the future"})
+		return time.Time{}, false
+	}
+	return end, true
+}
+
+// reportBound parses a report period bound. A plain date as the upper bound
+// includes that whole day.
+func reportBound(value string, upper bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"redops/models"
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TimelineHandler struct {
+	operationRepo *repositories.OperationRepository
+	taskRepo      *repositories.TaskRepository
+	resultRepo    *repositories.ResultRepository
+	auditRepo     *repositories.AuditRepository
+}
+
+func NewTimelineHandler(operationRepo *repositories.OperationRepository, taskRepo *repositories.TaskRepository, resultRepo *repositories.ResultRepository, auditRepo *repositories.AuditRepository) *TimelineHandler {
+	return &TimelineHandler{
+		operationRepo: operationRepo,
+		taskRepo:      taskRepo,
+		resultRepo:    resultRepo,
+		auditRepo:     auditRepo,
+	}
+}
+
+// GetOperationTimeline merges an operation's results, task status changes and
+// phase changes into a single chronological activity timeline
+func (h *TimelineHandler) GetOperationTimeline(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if _, err := h.operationRepo.GetByID(objectID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	tasks, err := h.taskRepo.GetByOperationID(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	taskTitles := make(map[primitive.ObjectID]string, len(tasks))
+	taskIDs := make([]primitive.ObjectID, 0, len(tasks))
+	for _, task := range tasks {
+		taskTitles[task.ID] = task.Title
+		taskIDs = append(taskIDs, task.ID)
+	}
+
+	timeline := []models.TimelineEntry{}
+
+	if len(taskIDs) > 0 {
+		results, err := h.resultRepo.GetByTaskIDs(taskIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for i := range results {
+			result := &results[i]
+			at := result.StartTime
+			if at.IsZero() {
+				at = result.CreatedAt
+			}
+			timeline = append(timeline, models.TimelineEntry{
+				Time:      at,
+				EndTime:   result.EndTime,
+				Kind:      models.TimelineResult,
+				TaskID:    result.TaskID,
+				TaskTitle: taskTitles[result.TaskID],
+				ResultID:  result.ID,
+				Actor:     result.OperatorName,
+				Summary:   resultSummary(result),
+				Result:    result,
+			})
+		}
+	}
+
+	entries, err := h.auditRepo.GetByOperationID(objectID, models.AuditTaskStatusChanged, models.AuditPhaseChanged)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, entry := range entries {
+		item := models.TimelineEntry{
+			Time:  entry.Timestamp,
+			Actor: entry.ActorName,
+			From:  entry.From,
+			To:    entry.To,
+		}
+		switch entry.Action {
+		case models.AuditTaskStatusChanged:
+			item.Kind = models.TimelineTaskStatus
+			item.TaskID = entry.TaskID
+			item.TaskTitle = taskTitles[entry.TaskID]
+			item.Summary = fmt.Sprintf("Task %q moved from %s to %s", item.TaskTitle, entry.From, entry.To)
+		case models.AuditPhaseChanged:
+			item.Kind = models.TimelinePhaseChange
+			item.Summary = fmt.Sprintf("Operation phase changed from %s to %s", entry.From, entry.To)
+		}
+		timeline = append(timeline, item)
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// resultSummary describes a result in one line for the timeline
+func resultSummary(result *models.Result) string {
+	summary := result.ToolApp
+	if summary == "" {
+		summary = "Activity"
+	}
+	if result.SourceIP != "" {
+		summary += " from " + result.SourceIP
+	}
+	if result.PivotIP != "" {
+		summary += " via " + result.PivotIP
+	}
+	if result.DestinationIP != "" {
+		summary += " against " + result.DestinationIP
+		if result.DestinationPort != "" {
+			summary += ":" + result.DestinationPort
+		}
+	}
+	if result.Description != "" {
+		summary += ": " + result.Description
+	}
+	return summary
+}
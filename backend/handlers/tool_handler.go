@@ -1,20 +1,48 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"strings"
+	"time"
+
+	"redops/executor"
 	"redops/models"
 	"redops/repositories"
+	"redops/roe"
+	"redops/scope"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ToolHandler struct {
-	repo *repositories.ToolRepository
+	repo          *repositories.ToolRepository
+	executionRepo *repositories.ToolExecutionRepository
+	taskRepo      *repositories.TaskRepository
+	operationRepo *repositories.OperationRepository
+	assetRepo     *repositories.AssetRepository
+	auditRepo     *repositories.AuditRepository
+	runner        *executor.Runner
 }
 
-func NewToolHandler(repo *repositories.ToolRepository) *ToolHandler {
-	return &ToolHandler{repo: repo}
+type ExecuteToolRequest struct {
+	TaskID    string            `json:"task_id" binding:"required"`
+	Args      []string          `json:"args"`
+	Arguments map[string]string `json:"arguments"`
+}
+
+func NewToolHandler(repo *repositories.ToolRepository, executionRepo *repositories.ToolExecutionRepository, taskRepo *repositories.TaskRepository, operationRepo *repositories.OperationRepository, assetRepo *repositories.AssetRepository, auditRepo *repositories.AuditRepository, runner *executor.Runner) *ToolHandler {
+	return &ToolHandler{
+		repo:          repo,
+		executionRepo: executionRepo,
+		taskRepo:      taskRepo,
+		operationRepo: operationRepo,
+		assetRepo:     assetRepo,
+		auditRepo:     auditRepo,
+		runner:        runner,
+	}
 }
 
 func (h *ToolHandler) CreateTool(c *gin.Context) {
@@ -46,6 +74,7 @@ func (h *ToolHandler) GetTool(c *gin.Context) {
 		return
 	}
 
+	setETag(c, tool.Version)
 	c.JSON(http.StatusOK, tool)
 }
 
@@ -57,6 +86,12 @@ func (h *ToolHandler) UpdateTool(c *gin.Context) {
 		return
 	}
 
+	existing, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tool not found"})
+		return
+	}
+
 	var tool models.Tool
 	if err := c.ShouldBindJSON(&tool); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -64,14 +99,81 @@ func (h *ToolHandler) UpdateTool(c *gin.Context) {
 	}
 
 	tool.ID = objectID
+	tool.CreatedAt = existing.CreatedAt
+	version, ok := expectedVersion(c, tool.Version, existing.Version)
+	if !ok {
+		return
+	}
+	tool.Version = version
 	if err := h.repo.Update(&tool); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch err {
+		case repositories.ErrVersionConflict:
+			h.respondConflict(c, tool.ID)
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tool not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
+	setETag(c, tool.Version)
 	c.JSON(http.StatusOK, tool)
 }
 
+// PatchTool changes only the fields sent in a JSON Merge Patch body
+func (h *ToolHandler) PatchTool(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	tool, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tool not found"})
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	set, ok := bindPatch(c, tool, models.ToolPatchFields)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.repo.Patch(objectID, set, version)
+	if err != nil {
+		switch err {
+		case repositories.ErrVersionConflict:
+			h.respondConflict(c, objectID)
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tool not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	tool.Version = newVersion
+
+	setETag(c, tool.Version)
+	c.JSON(http.StatusOK, tool)
+}
+
+// respondConflict answers a write that lost to a concurrent change with the
+// tool as it is now stored
+func (h *ToolHandler) respondConflict(c *gin.Context, id primitive.ObjectID) {
+	current, err := h.repo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tool not found"})
+		return
+	}
+	respondConflict(c, current.Version, current)
+}
+
 func (h *ToolHandler) DeleteTool(c *gin.Context) {
 	id := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -81,6 +183,10 @@ func (h *ToolHandler) DeleteTool(c *gin.Context) {
 	}
 
 	if err := h.repo.Delete(objectID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tool not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -140,3 +246,227 @@ func (h *ToolHandler) UpdateToolStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Tool status updated successfully"})
 }
+
+// ExecuteTool queues a run of the tool for a task and returns immediately.
+// Placeholders such as {target} in the tool's command are filled from the
+// tool's default arguments overridden by the request's.
+func (h *ToolHandler) ExecuteTool(c *gin.Context) {
+	toolID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var req ExecuteToolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	taskID, err := primitive.ObjectIDFromHex(req.TaskID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	tool, err := h.repo.GetByID(toolID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tool not found"})
+		return
+	}
+	if !tool.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tool is not active"})
+		return
+	}
+
+	task, err := h.taskRepo.GetByID(taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	operation, err := h.operationRepo.GetByID(task.OperationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+	if !leadsOperation(c, operation) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the operation's team lead or an admin can run tools"})
+		return
+	}
+	if rejectHalted(c, operation) {
+		return
+	}
+
+	// Callers fill in values; the options come from the tool definition
+	values := append([]string{}, req.Args...)
+	for _, v := range req.Arguments {
+		values = append(values, v)
+	}
+	if err := executor.CheckValues(values); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	arguments := make(map[string]string, len(tool.Arguments)+len(req.Arguments))
+	for k, v := range tool.Arguments {
+		arguments[k] = v
+	}
+	for k, v := range req.Arguments {
+		arguments[k] = v
+	}
+
+	argv := buildCommand(tool.Command, arguments, req.Args)
+	if len(argv) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tool has no command"})
+		return
+	}
+	if err := executor.CheckProgram(argv); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Refuse to run against anything outside the engagement scope
+	if scopeDef := scope.Parse(operation.Scope); !scope.Empty(scopeDef) {
+		var outOfScope []models.ScopeVerdict
+		for _, verdict := range scope.EvaluateAll(scopeDef, executionTargets(arguments, argv), time.Now()) {
+			if !verdict.InScope {
+				outOfScope = append(outOfScope, verdict)
+			}
+		}
+		if len(outOfScope) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":    "Execution targets fall outside the operation scope",
+				"verdicts": outOfScope,
+			})
+			return
+		}
+	}
+
+	// Check the rules of engagement; warnings are kept on the execution
+	recent, err := h.executionRepo.CountStartedSince(operation.ID, time.Now().Add(-time.Minute))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	command := strings.Join(argv, " ")
+	violations := roe.Evaluate(operation.ROERules, roe.Action{
+		Phase:            operation.CurrentPhase,
+		ToolType:         tool.Type,
+		ToolName:         tool.Name,
+		Command:          command,
+		Arguments:        arguments,
+		Time:             time.Now(),
+		RecentExecutions: int(recent),
+	})
+	recordROEViolations(h.auditRepo, c, operation.ID, task.ID, tool.Name, violations)
+	if roe.Blocking(violations) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "Execution violates the rules of engagement",
+			"violations": violations,
+		})
+		return
+	}
+
+	execution := &models.ToolExecution{
+		ToolID:      tool.ID,
+		TaskID:      task.ID,
+		OperationID: task.OperationID,
+		UserID:      c.GetString("userID"),
+		Command:     command,
+		Arguments:   arguments,
+		Warnings:    violations,
+		Status:      models.ExecutionQueued,
+	}
+	if err := h.executionRepo.Create(execution); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.runner.Start(execution.ID, argv, func() bool {
+		started, err := h.executionRepo.MarkRunning(execution.ID)
+		if err != nil {
+			log.Printf("Failed to start execution %s: %v", execution.ID.Hex(), err)
+		}
+		return started
+	}, func(output string, runErr error) {
+		h.finishExecution(execution, output, runErr)
+	})
+
+	c.JSON(http.StatusAccepted, execution)
+}
+
+func (h *ToolHandler) GetExecution(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	execution, err := h.executionRepo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, execution)
+}
+
+func (h *ToolHandler) GetTaskExecutions(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("taskId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		return
+	}
+
+	executions, err := h.executionRepo.GetByTaskID(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, executions)
+}
+
+// finishExecution stores the outcome of a run and feeds the hosts it
+// reported into the asset inventory
+func (h *ToolHandler) finishExecution(execution *models.ToolExecution, output string, runErr error) {
+	status := models.ExecutionCompleted
+	errMsg := ""
+	switch {
+	case runErr == executor.ErrCancelled:
+		status = models.ExecutionCancelled
+		errMsg = runErr.Error()
+	case runErr != nil:
+		status = models.ExecutionFailed
+		errMsg = runErr.Error()
+	}
+
+	if err := h.executionRepo.Finish(execution.ID, status, output, errMsg); err != nil {
+		log.Printf("Failed to record execution %s: %v", execution.ID.Hex(), err)
+	}
+
+	if status == models.ExecutionCompleted {
+		execution.EndTime = time.Now()
+		recordAssets(h.assetRepo, execution.OperationID, observationsFromExecution(execution, output))
+	}
+}
+
+// buildCommand splits a tool command into argv, replacing {name}
+// placeholders with argument values, and appends any extra arguments. The
+// binary always comes from the tool definition, never from the caller.
+func buildCommand(command string, arguments map[string]string, extra []string) []string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var argv []string
+	for _, field := range fields {
+		for name, value := range arguments {
+			field = strings.ReplaceAll(field, "{"+name+"}", value)
+		}
+		argv = append(argv, field)
+	}
+	return append(argv, extra...)
+}
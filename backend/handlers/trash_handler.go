@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+
+	"redops/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TrashHandler struct {
+	operationRepo *repositories.OperationRepository
+	taskRepo      *repositories.TaskRepository
+	resultRepo    *repositories.ResultRepository
+	toolRepo      *repositories.ToolRepository
+	evidenceRepo  *repositories.EvidenceRepository
+}
+
+func NewTrashHandler(operationRepo *repositories.OperationRepository, taskRepo *repositories.TaskRepository, resultRepo *repositories.ResultRepository, toolRepo *repositories.ToolRepository, evidenceRepo *repositories.EvidenceRepository) *TrashHandler {
+	return &TrashHandler{
+		operationRepo: operationRepo,
+		taskRepo:      taskRepo,
+		resultRepo:    resultRepo,
+		toolRepo:      toolRepo,
+		evidenceRepo:  evidenceRepo,
+	}
+}
+
+// ListTrash returns the deleted operations, tasks, results, tools or evidence
+// files that can still be restored
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	var (
+		items interface{}
+		err   error
+	)
+	switch c.Param("type") {
+	case "operations":
+		items, err = h.operationRepo.ListDeleted()
+	case "tasks":
+		items, err = h.taskRepo.ListDeleted()
+	case "results":
+		items, err = h.resultRepo.ListDeleted()
+	case "tools":
+		items, err = h.toolRepo.ListDeleted()
+	case "evidence":
+		items, err = h.evidenceRepo.ListDeleted()
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown trash type, expected operations, tasks, results, tools or evidence"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// RestoreFromTrash takes a deleted item out of the trash. Operations and
+// tasks come back with the children that were deleted along with them.
+func (h *TrashHandler) RestoreFromTrash(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var restore func(primitive.ObjectID) error
+	switch c.Param("type") {
+	case "operations":
+		restore = h.operationRepo.Restore
+	case "tasks":
+		restore = h.taskRepo.Restore
+	case "results":
+		restore = h.resultRepo.Restore
+	case "tools":
+		restore = h.toolRepo.Restore
+	case "evidence":
+		restore = func(id primitive.ObjectID) error {
+			return h.evidenceRepo.Restore(id, custodyEvent(c))
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown trash type, expected operations, tasks, results, tools or evidence"})
+		return
+	}
+
+	if err := restore(objectID); err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in the trash"})
+		case repositories.ErrParentDeleted:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case repositories.ErrDuplicateResult:
+			c.JSON(http.StatusConflict, gin.H{"error": "A result with the same content has been added since, remove it before restoring"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Restored successfully"})
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -64,13 +65,22 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	existing, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// The password hash never travels in JSON, so keep the stored one
 	user.ID = objectID
+	user.Password = existing.Password
+	user.CreatedAt = existing.CreatedAt
 	if err := h.repo.Update(&user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -79,6 +89,50 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// PatchUser changes only the fields sent in a JSON Merge Patch body
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	user, err := h.repo.GetByID(objectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	set, ok := bindPatch(c, user, models.UserPatchFields)
+	if !ok {
+		return
+	}
+
+	if _, changesRole := set["role"]; changesRole {
+		if c.GetString("role") != string(models.RoleAdmin) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can change a user's role"})
+			return
+		}
+		switch user.Role {
+		case models.RoleAdmin, models.RoleTeamLead, models.RoleMember:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+			return
+		}
+	}
+
+	if err := h.repo.Patch(objectID, set); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -4,14 +4,18 @@ import (
 	"log"
 	"net/http"
 
+	"redops/middleware"
 	"redops/websocket"
 
+	"github.com/gin-gonic/gin"
 	gorilla "github.com/gorilla/websocket"
 )
 
 var upgrader = gorilla.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// Echo the protocol browsers use to pass their token
+	Subprotocols: []string{middleware.WebSocketProtocol},
 	CheckOrigin: func(r *http.Request) bool {
 		return true // TODO: Implement proper origin checking
 	},
@@ -30,7 +34,18 @@ func NewWebSocketHandler(hub *websocket.Hub) *WebSocketHandler {
 func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (assuming it's set by auth middleware)
 	userID := r.Context().Value("user_id").(string)
+	h.serve(w, r, userID)
+}
+
+// Connect upgrades an authenticated API request to a WebSocket that receives
+// the notifications addressed to the user. Browsers authenticate by offering
+// the subprotocols "bearer" and their token. Clients follow the live updates of
+// a task by sending {"action": "subscribe", "topic": "task:<id>"}.
+func (h *WebSocketHandler) Connect(c *gin.Context) {
+	h.serve(c.Writer, c.Request, c.GetString("userID"))
+}
 
+func (h *WebSocketHandler) serve(w http.ResponseWriter, r *http.Request, userID string) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Error upgrading connection: %v", err)
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"redops/models"
+	"redops/repositories"
+	"redops/workload"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WorkloadHandler struct {
+	taskRepo      *repositories.TaskRepository
+	operationRepo *repositories.OperationRepository
+	userRepo      *repositories.UserRepository
+}
+
+func NewWorkloadHandler(taskRepo *repositories.TaskRepository, operationRepo *repositories.OperationRepository, userRepo *repositories.UserRepository) *WorkloadHandler {
+	return &WorkloadHandler{
+		taskRepo:      taskRepo,
+		operationRepo: operationRepo,
+		userRepo:      userRepo,
+	}
+}
+
+// GetWorkload returns the open tasks and estimated hours of each user across
+// the operations still running. With operation_id only that operation's team
+// is listed, still with their work in every operation.
+func (h *WorkloadHandler) GetWorkload(c *gin.Context) {
+	var users []models.User
+	if operationID := c.Query("operation_id"); operationID != "" {
+		operationObjectID, err := primitive.ObjectIDFromHex(operationID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+			return
+		}
+		operation, err := h.operationRepo.GetByID(operationObjectID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+			return
+		}
+		if users, err = h.team(operation); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		var err error
+		if users, err = h.userRepo.List(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tasks, operations, ok := h.load(c, users)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, workload.Build(users, tasks, operations))
+}
+
+// GetAssigneeSuggestions ranks the operation's team for a new task described
+// by the phase, mitre_id, owasp_id and tools (comma separated) query
+// parameters. The first candidate is the suggestion.
+func (h *WorkloadHandler) GetAssigneeSuggestions(c *gin.Context) {
+	operationObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID format"})
+		return
+	}
+
+	criteria := models.AssignmentCriteria{
+		Phase:   models.OperationPhase(c.Query("phase")),
+		MITREID: strings.TrimSpace(c.Query("mitre_id")),
+		OWASPID: strings.TrimSpace(c.Query("owasp_id")),
+	}
+	if criteria.Phase != "" && !criteria.Phase.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phase"})
+		return
+	}
+	if tools := c.Query("tools"); tools != "" {
+		criteria.Tools = normalizeTags(strings.Split(tools, ","))
+	}
+
+	operation, err := h.operationRepo.GetByID(operationObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+		return
+	}
+
+	team, err := h.team(operation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, operations, ok := h.load(c, team)
+	if !ok {
+		return
+	}
+
+	candidates := workload.Suggest(team, tasks, operations, criteria)
+	var suggestion *models.AssignmentCandidate
+	if len(candidates) > 0 {
+		suggestion = &candidates[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"criteria":   criteria,
+		"suggestion": suggestion,
+		"candidates": candidates,
+	})
+}
+
+// team returns the operation's team lead and members in that order
+func (h *WorkloadHandler) team(operation *models.Operation) ([]models.User, error) {
+	ids := operation.Team()
+	users, err := h.userRepo.GetByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	team := make([]models.User, 0, len(users))
+	for _, id := range ids {
+		if user, ok := byID[id]; ok {
+			team = append(team, user)
+		}
+	}
+	return team, nil
+}
+
+// load fetches the tasks assigned to the users and the operations that are
+// not archived, answering the request itself when that fails
+func (h *WorkloadHandler) load(c *gin.Context, users []models.User) ([]models.Task, map[primitive.ObjectID]*models.Operation, bool) {
+	ids := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+
+	tasks, err := h.taskRepo.GetByAssignees(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	list, err := h.operationRepo.List(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	operations := make(map[primitive.ObjectID]*models.Operation, len(list))
+	for i := range list {
+		operations[list[i].ID] = &list[i]
+	}
+
+	return tasks, operations, true
+}
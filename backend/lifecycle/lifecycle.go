@@ -0,0 +1,182 @@
+// Package lifecycle defines the states an operation moves through and who may
+// move it between them.
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"redops/models"
+)
+
+var (
+	ErrUnknownStatus  = errors.New("unknown operation status")
+	ErrNotAllowed     = errors.New("transition not allowed")
+	ErrForbidden      = errors.New("not permitted to make this transition")
+	ErrMissingFields  = errors.New("operation is missing required fields")
+	ErrUseHaltControl = errors.New("use the halt and resume endpoints to halt or resume an operation")
+)
+
+// Approver says who may make a transition
+type Approver int
+
+const (
+	// TeamLead allows the operation's team lead and admins
+	TeamLead Approver = iota
+	// Admin allows admins only
+	Admin
+)
+
+// Rule describes one allowed transition
+type Rule struct {
+	To       models.OperationStatus
+	Approver Approver
+	// Requires names the operation fields that must be filled in first
+	Requires []string
+}
+
+// Statuses lists every status in lifecycle order
+var Statuses = []models.OperationStatus{
+	models.OperationStatusPlanning,
+	models.OperationStatusApproved,
+	models.OperationStatusActive,
+	models.OperationStatusPaused,
+	models.OperationStatusHalted,
+	models.OperationStatusReporting,
+	models.OperationStatusClosed,
+	models.OperationStatusArchived,
+}
+
+// transitions maps each status to the statuses reachable from it. Halted is
+// entered and left only through the kill switch, see CanHalt.
+var transitions = map[models.OperationStatus][]Rule{
+	models.OperationStatusPlanning: {
+		{To: models.OperationStatusApproved, Approver: Admin, Requires: []string{"scope", "roe", "team_lead"}},
+		{To: models.OperationStatusArchived, Approver: Admin},
+	},
+	models.OperationStatusApproved: {
+		{To: models.OperationStatusActive, Approver: TeamLead, Requires: []string{"scope", "roe", "team_lead"}},
+		{To: models.OperationStatusPlanning, Approver: TeamLead},
+	},
+	models.OperationStatusActive: {
+		{To: models.OperationStatusPaused, Approver: TeamLead},
+		{To: models.OperationStatusReporting, Approver: TeamLead},
+	},
+	models.OperationStatusPaused: {
+		{To: models.OperationStatusActive, Approver: TeamLead, Requires: []string{"scope", "roe"}},
+		{To: models.OperationStatusReporting, Approver: TeamLead},
+	},
+	models.OperationStatusReporting: {
+		{To: models.OperationStatusActive, Approver: TeamLead, Requires: []string{"scope", "roe"}},
+		{To: models.OperationStatusClosed, Approver: TeamLead},
+	},
+	models.OperationStatusClosed: {
+		{To: models.OperationStatusReporting, Approver: TeamLead},
+		{To: models.OperationStatusArchived, Approver: Admin},
+	},
+}
+
+// Actor is the user asking for a transition
+type Actor struct {
+	UserID string
+	Role   models.UserRole
+}
+
+// Valid reports whether status is a defined lifecycle state
+func Valid(status models.OperationStatus) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Current returns the operation's status, reading operations created before
+// the lifecycle existed as still being planned
+func Current(operation *models.Operation) models.OperationStatus {
+	if Valid(operation.Status) {
+		return operation.Status
+	}
+	return models.OperationStatusPlanning
+}
+
+// Next lists the statuses the actor may move the operation to
+func Next(operation *models.Operation, actor Actor) []models.OperationStatus {
+	next := []models.OperationStatus{}
+	for _, rule := range transitions[Current(operation)] {
+		if permitted(operation, actor, rule.Approver) {
+			next = append(next, rule.To)
+		}
+	}
+	return next
+}
+
+// Check returns nil when the actor may move the operation to status, or an
+// error wrapping one of the package's sentinel errors explaining why not
+func Check(operation *models.Operation, to models.OperationStatus, actor Actor) error {
+	if !Valid(to) {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, to)
+	}
+
+	from := Current(operation)
+	if from == models.OperationStatusHalted || to == models.OperationStatusHalted {
+		return ErrUseHaltControl
+	}
+
+	for _, rule := range transitions[from] {
+		if rule.To != to {
+			continue
+		}
+		if !permitted(operation, actor, rule.Approver) {
+			if rule.Approver == Admin {
+				return fmt.Errorf("%w: %s to %s needs an admin", ErrForbidden, from, to)
+			}
+			return fmt.Errorf("%w: %s to %s needs the team lead", ErrForbidden, from, to)
+		}
+		if missing := Missing(operation, rule.Requires); len(missing) > 0 {
+			return fmt.Errorf("%w: %s", ErrMissingFields, strings.Join(missing, ", "))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s to %s", ErrNotAllowed, from, to)
+}
+
+// CanHalt reports whether the kill switch may be pulled in a status. Closed
+// and archived operations have nothing left to stop.
+func CanHalt(status models.OperationStatus) bool {
+	switch status {
+	case models.OperationStatusHalted, models.OperationStatusClosed, models.OperationStatusArchived:
+		return false
+	}
+	return true
+}
+
+// Missing returns the named fields that are still empty on the operation
+func Missing(operation *models.Operation, fields []string) []string {
+	var missing []string
+	for _, field := range fields {
+		empty := false
+		switch field {
+		case "scope":
+			empty = strings.TrimSpace(operation.Scope) == ""
+		case "roe":
+			empty = strings.TrimSpace(operation.ROE) == "" && operation.ROERules == nil
+		case "team_lead":
+			empty = operation.TeamLead.IsZero()
+		}
+		if empty {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+func permitted(operation *models.Operation, actor Actor, approver Approver) bool {
+	if actor.Role == models.RoleAdmin {
+		return true
+	}
+	return approver == TeamLead && actor.UserID != "" && actor.UserID == operation.TeamLead.Hex()
+}
@@ -0,0 +1,97 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheck(t *testing.T) {
+	lead := primitive.NewObjectID()
+	ready := func(status models.OperationStatus) *models.Operation {
+		return &models.Operation{Status: status, Scope: "10.0.0.0/24", ROE: "business hours only", TeamLead: lead}
+	}
+
+	admin := Actor{UserID: primitive.NewObjectID().Hex(), Role: models.RoleAdmin}
+	teamLead := Actor{UserID: lead.Hex(), Role: models.RoleTeamLead}
+	otherLead := Actor{UserID: primitive.NewObjectID().Hex(), Role: models.RoleTeamLead}
+	member := Actor{UserID: primitive.NewObjectID().Hex(), Role: models.RoleMember}
+
+	tests := []struct {
+		name      string
+		operation *models.Operation
+		to        models.OperationStatus
+		actor     Actor
+		want      error
+	}{
+		{"admin approves plan", ready(models.OperationStatusPlanning), models.OperationStatusApproved, admin, nil},
+		{"team lead cannot approve", ready(models.OperationStatusPlanning), models.OperationStatusApproved, teamLead, ErrForbidden},
+		{"approval needs scope", &models.Operation{Status: models.OperationStatusPlanning, ROE: "x", TeamLead: lead}, models.OperationStatusApproved, admin, ErrMissingFields},
+		{"approval needs team lead", &models.Operation{Status: models.OperationStatusPlanning, Scope: "x", ROE: "x"}, models.OperationStatusApproved, admin, ErrMissingFields},
+		{"structured rules count as roe", &models.Operation{Status: models.OperationStatusPlanning, Scope: "x", ROERules: &models.ROERules{}, TeamLead: lead}, models.OperationStatusApproved, admin, nil},
+		{"team lead starts", ready(models.OperationStatusApproved), models.OperationStatusActive, teamLead, nil},
+		{"other lead cannot start", ready(models.OperationStatusApproved), models.OperationStatusActive, otherLead, ErrForbidden},
+		{"member cannot pause", ready(models.OperationStatusActive), models.OperationStatusPaused, member, ErrForbidden},
+		{"team lead pauses", ready(models.OperationStatusActive), models.OperationStatusPaused, teamLead, nil},
+		{"active cannot close", ready(models.OperationStatusActive), models.OperationStatusClosed, admin, ErrNotAllowed},
+		{"reporting closes", ready(models.OperationStatusReporting), models.OperationStatusClosed, teamLead, nil},
+		{"team lead cannot archive", ready(models.OperationStatusClosed), models.OperationStatusArchived, teamLead, ErrForbidden},
+		{"admin archives", ready(models.OperationStatusClosed), models.OperationStatusArchived, admin, nil},
+		{"archived is final", ready(models.OperationStatusArchived), models.OperationStatusClosed, admin, ErrNotAllowed},
+		{"halt has its own control", ready(models.OperationStatusActive), models.OperationStatusHalted, admin, ErrUseHaltControl},
+		{"resume has its own control", ready(models.OperationStatusHalted), models.OperationStatusActive, admin, ErrUseHaltControl},
+		{"unknown status", ready(models.OperationStatusActive), "finished", admin, ErrUnknownStatus},
+		{"legacy operation is planning", &models.Operation{Scope: "x", ROE: "x", TeamLead: lead}, models.OperationStatusApproved, admin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.operation, tt.to, tt.actor)
+			if tt.want == nil && err != nil {
+				t.Fatalf("Check() = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Check() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	lead := primitive.NewObjectID()
+	operation := &models.Operation{Status: models.OperationStatusClosed, TeamLead: lead}
+
+	tests := []struct {
+		name  string
+		actor Actor
+		want  []models.OperationStatus
+	}{
+		{"admin", Actor{Role: models.RoleAdmin}, []models.OperationStatus{models.OperationStatusReporting, models.OperationStatusArchived}},
+		{"team lead", Actor{UserID: lead.Hex(), Role: models.RoleTeamLead}, []models.OperationStatus{models.OperationStatusReporting}},
+		{"member", Actor{UserID: primitive.NewObjectID().Hex(), Role: models.RoleMember}, []models.OperationStatus{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Next(operation, tt.actor)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Next() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Next()[%d] = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCanHalt(t *testing.T) {
+	for _, status := range Statuses {
+		want := status != models.OperationStatusHalted && status != models.OperationStatusClosed && status != models.OperationStatusArchived
+		if got := CanHalt(status); got != want {
+			t.Errorf("CanHalt(%s) = %v, want %v", status, got, want)
+		}
+	}
+}
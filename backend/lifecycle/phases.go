@@ -0,0 +1,63 @@
+package lifecycle
+
+import (
+	"time"
+
+	"redops/models"
+)
+
+// PhaseMetrics works out how long the operation spent in each phase from its
+// phase history, in the order phases were first entered. The phase the
+// operation was created in is taken to start at its creation time.
+func PhaseMetrics(operation *models.Operation, now time.Time) []models.PhaseMetric {
+	type stint struct {
+		phase models.OperationPhase
+		start time.Time
+	}
+
+	var stints []stint
+	start := operation.CreatedAt
+	phase := operation.CurrentPhase
+	if len(operation.PhaseHistory) > 0 {
+		phase = operation.PhaseHistory[0].From
+	}
+	for _, change := range operation.PhaseHistory {
+		if phase != "" {
+			stints = append(stints, stint{phase: phase, start: start})
+		}
+		phase = change.To
+		start = change.ChangedAt
+	}
+	if phase != "" {
+		stints = append(stints, stint{phase: phase, start: start})
+	}
+
+	var metrics []models.PhaseMetric
+	index := make(map[models.OperationPhase]int)
+	for i, s := range stints {
+		end := now
+		current := i == len(stints)-1
+		if !current {
+			end = stints[i+1].start
+		}
+
+		n, seen := index[s.phase]
+		if !seen {
+			n = len(metrics)
+			index[s.phase] = n
+			metrics = append(metrics, models.PhaseMetric{Phase: s.phase, FirstEntered: s.start})
+		}
+
+		m := &metrics[n]
+		m.Visits++
+		if end.After(s.start) {
+			m.Duration += end.Sub(s.start).Seconds()
+		}
+		m.Current = current
+		if !current {
+			m.LastLeft = end
+		}
+	}
+
+	return metrics
+}
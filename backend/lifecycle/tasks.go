@@ -0,0 +1,88 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"redops/models"
+)
+
+var (
+	ErrUnknownTaskStatus = errors.New("unknown task status")
+	ErrWaiting           = errors.New("task is waiting on unfinished dependencies")
+)
+
+// TaskStatuses lists every task status in the order work usually goes
+var TaskStatuses = []models.TaskStatus{
+	models.StatusPending,
+	models.StatusInProgress,
+	models.StatusBlocked,
+	models.StatusCompleted,
+}
+
+// taskTransitions maps each task status to the statuses reachable from it.
+// Completed tasks can be reopened, which puts them back in progress.
+var taskTransitions = map[models.TaskStatus][]models.TaskStatus{
+	models.StatusPending:    {models.StatusInProgress, models.StatusBlocked},
+	models.StatusInProgress: {models.StatusCompleted, models.StatusBlocked, models.StatusPending},
+	models.StatusBlocked:    {models.StatusPending, models.StatusInProgress},
+	models.StatusCompleted:  {models.StatusInProgress},
+}
+
+// ValidTask reports whether status is a defined task status
+func ValidTask(status models.TaskStatus) bool {
+	for _, s := range TaskStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentTask returns the task's status, reading tasks saved without one as
+// pending
+func CurrentTask(task *models.Task) models.TaskStatus {
+	if ValidTask(task.Status) {
+		return task.Status
+	}
+	return models.StatusPending
+}
+
+// NextTask lists the statuses the task may move to
+func NextTask(task *models.Task) []models.TaskStatus {
+	next := []models.TaskStatus{}
+	next = append(next, taskTransitions[CurrentTask(task)]...)
+	return next
+}
+
+// CheckTask returns nil when the task may move to status, or an error wrapping
+// ErrUnknownTaskStatus or ErrNotAllowed explaining why not
+func CheckTask(task *models.Task, to models.TaskStatus) error {
+	if !ValidTask(to) {
+		return fmt.Errorf("%w: %q", ErrUnknownTaskStatus, to)
+	}
+
+	from := CurrentTask(task)
+	for _, next := range taskTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrNotAllowed, from, to)
+}
+
+// StampTask sets the task's dates for a move to status: work starting stamps
+// the start date once, completing stamps the end date and reopening a
+// completed task clears it again. Call it before changing the task's status.
+func StampTask(task *models.Task, to models.TaskStatus, now time.Time) {
+	if to == models.StatusInProgress && task.StartDate.IsZero() {
+		task.StartDate = now
+	}
+	switch {
+	case to == models.StatusCompleted:
+		task.EndDate = now
+	case CurrentTask(task) == models.StatusCompleted:
+		task.EndDate = time.Time{}
+	}
+}
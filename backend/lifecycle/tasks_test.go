@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"redops/models"
+)
+
+func TestCheckTask(t *testing.T) {
+	tests := []struct {
+		from models.TaskStatus
+		to   models.TaskStatus
+		want error
+	}{
+		{models.StatusPending, models.StatusInProgress, nil},
+		{models.StatusPending, models.StatusBlocked, nil},
+		{models.StatusPending, models.StatusCompleted, ErrNotAllowed},
+		{models.StatusInProgress, models.StatusCompleted, nil},
+		{models.StatusInProgress, models.StatusPending, nil},
+		{models.StatusBlocked, models.StatusPending, nil},
+		{models.StatusBlocked, models.StatusCompleted, ErrNotAllowed},
+		{models.StatusCompleted, models.StatusInProgress, nil},
+		{models.StatusCompleted, models.StatusPending, ErrNotAllowed},
+		{"", models.StatusInProgress, nil},
+		{models.StatusPending, "done", ErrUnknownTaskStatus},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			err := CheckTask(&models.Task{Status: tt.from}, tt.to)
+			if tt.want == nil && err != nil {
+				t.Fatalf("CheckTask() = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("CheckTask() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStampTask(t *testing.T) {
+	started := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	finished := time.Date(2024, 3, 2, 17, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		task      models.Task
+		to        models.TaskStatus
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"start stamps start date", models.Task{Status: models.StatusPending}, models.StatusInProgress, now, time.Time{}},
+		{"restart keeps start date", models.Task{Status: models.StatusBlocked, StartDate: started}, models.StatusInProgress, started, time.Time{}},
+		{"complete stamps end date", models.Task{Status: models.StatusInProgress, StartDate: started}, models.StatusCompleted, started, now},
+		{"reopen clears end date", models.Task{Status: models.StatusCompleted, StartDate: started, EndDate: finished}, models.StatusInProgress, started, time.Time{}},
+		{"block leaves dates", models.Task{Status: models.StatusPending}, models.StatusBlocked, time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			StampTask(&task, tt.to, now)
+			if !task.StartDate.Equal(tt.wantStart) {
+				t.Errorf("StartDate = %v, want %v", task.StartDate, tt.wantStart)
+			}
+			if !task.EndDate.Equal(tt.wantEnd) {
+				t.Errorf("EndDate = %v, want %v", task.EndDate, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"encoding/base64"
 	"log"
 
+	"redops/attack"
 	"redops/database"
+	"redops/executor"
 	"redops/handlers"
 	"redops/models"
 	"redops/repositories"
 	"redops/routes"
+	"redops/trash"
+	"redops/websocket"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -23,6 +27,12 @@ func main() {
 	}
 	log.Println("Connected to MongoDB!")
 
+	// Deduplication, the running timer guard and asset merging depend on
+	// unique indexes, so the server does not start without them
+	if err := database.EnsureIndexes(); err != nil {
+		log.Fatal("Failed to create indexes:", err)
+	}
+
 	// Auto-create team lead if users collection is empty
 	userRepo := repositories.NewUserRepository()
 	userCount, err := database.Users.CountDocuments(nil, map[string]interface{}{})
@@ -53,13 +63,69 @@ func main() {
 	taskRepo := repositories.NewTaskRepository()
 	toolRepo := repositories.NewToolRepository()
 	resultRepo := repositories.NewResultRepository()
+	auditRepo := repositories.NewAuditRepository()
+	executionRepo := repositories.NewToolExecutionRepository()
+	assetRepo := repositories.NewAssetRepository()
+	templateRepo := repositories.NewTemplateRepository()
+	notificationRepo := repositories.NewNotificationRepository(database.Database)
+	attackRepo := repositories.NewAttackRepository()
+	commentRepo := repositories.NewCommentRepository()
+	evidenceRepo := repositories.NewEvidenceRepository()
+	timeEntryRepo := repositories.NewTimeEntryRepository()
+
+	// Start the WebSocket hub that pushes notifications to connected users
+	hub := websocket.NewHub()
+	go hub.Run()
+	runner := executor.NewRunner()
+
+	// Fingerprint results imported before deduplication was introduced
+	if err := resultRepo.BackfillFingerprints(); err != nil {
+		log.Println("Failed to backfill result fingerprints:", err)
+	}
+
+	// Give documents stored before versioning their first version
+	if err := repositories.BackfillVersions(); err != nil {
+		log.Println("Failed to backfill document versions:", err)
+	}
+
+	// Load the ATT&CK catalogue on first start when the bundle is available
+	if count, err := attackRepo.Count(); err == nil && count == 0 {
+		if techniques, _, err := attack.LoadFile(attack.BundlePath()); err == nil {
+			if err := attackRepo.Import(techniques); err != nil {
+				log.Println("Failed to import the ATT&CK catalogue:", err)
+			} else {
+				log.Printf("Imported %d ATT&CK techniques from %s", len(techniques), attack.BundlePath())
+			}
+		} else {
+			log.Println("ATT&CK catalogue not loaded, task technique IDs are only checked for format:", err)
+		}
+	}
+
+	// Permanently remove deleted documents once their retention has passed
+	purger := trash.NewPurger(operationRepo, taskRepo, resultRepo, toolRepo, evidenceRepo)
+	log.Printf("Keeping deleted documents in the trash for %s", purger.Retention())
+	go purger.Run()
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userRepo)
-	operationHandler := handlers.NewOperationHandler(operationRepo)
-	taskHandler := handlers.NewTaskHandler(taskRepo)
-	toolHandler := handlers.NewToolHandler(toolRepo)
-	resultHandler := handlers.NewResultHandler(resultRepo)
+	operationHandler := handlers.NewOperationHandler(operationRepo, auditRepo)
+	taskHandler := handlers.NewTaskHandler(taskRepo, operationRepo, attackRepo, auditRepo, notificationRepo, hub)
+	toolHandler := handlers.NewToolHandler(toolRepo, executionRepo, taskRepo, operationRepo, assetRepo, auditRepo, runner)
+	resultHandler := handlers.NewResultHandler(resultRepo, taskRepo, operationRepo, toolRepo, assetRepo, auditRepo)
+	timelineHandler := handlers.NewTimelineHandler(operationRepo, taskRepo, resultRepo, auditRepo)
+	assetHandler := handlers.NewAssetHandler(assetRepo)
+	graphHandler := handlers.NewGraphHandler(operationRepo, taskRepo, resultRepo, assetRepo)
+	scopeHandler := handlers.NewScopeHandler(operationRepo)
+	haltHandler := handlers.NewHaltHandler(operationRepo, executionRepo, notificationRepo, auditRepo, runner, hub)
+	webSocketHandler := handlers.NewWebSocketHandler(hub)
+	templateHandler := handlers.NewTemplateHandler(templateRepo, operationRepo, taskRepo)
+	trashHandler := handlers.NewTrashHandler(operationRepo, taskRepo, resultRepo, toolRepo, evidenceRepo)
+	attackHandler := handlers.NewAttackHandler(attackRepo, operationRepo, taskRepo, resultRepo)
+	owaspHandler := handlers.NewOWASPHandler(operationRepo, taskRepo)
+	commentHandler := handlers.NewCommentHandler(commentRepo, taskRepo, operationRepo, userRepo, notificationRepo, hub)
+	evidenceHandler := handlers.NewEvidenceHandler(evidenceRepo, taskRepo, resultRepo, operationRepo)
+	timeHandler := handlers.NewTimeHandler(timeEntryRepo, taskRepo, operationRepo)
+	workloadHandler := handlers.NewWorkloadHandler(taskRepo, operationRepo, userRepo)
 
 	// Create router
 	router := gin.Default()
@@ -68,14 +134,14 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept"},
-		ExposeHeaders:    []string{"Authorization", "Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", "If-Match"},
+		ExposeHeaders:    []string{"Authorization", "Content-Length", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * 60 * 60, // 12 hours
 	}))
 
 	// Setup routes
-	routes.SetupRoutes(router, userHandler, operationHandler, taskHandler, toolHandler, resultHandler)
+	routes.SetupRoutes(router, userHandler, operationHandler, taskHandler, toolHandler, resultHandler, timelineHandler, assetHandler, graphHandler, scopeHandler, haltHandler, webSocketHandler, templateHandler, trashHandler, attackHandler, owaspHandler, commentHandler, evidenceHandler, timeHandler, workloadHandler)
 
 	// Start server
 	log.Println("Server starting on :8080")
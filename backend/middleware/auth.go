@@ -10,10 +10,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware checks for valid JWT token in Authorization header
+// WebSocketProtocol is the subprotocol a browser offers together with its
+// token when it opens a WebSocket, since it cannot set headers on the upgrade:
+// new WebSocket(url, ["bearer", token])
+const WebSocketProtocol = "bearer"
+
+// AuthMiddleware checks for valid JWT token in Authorization header, or in the
+// subprotocols of a WebSocket upgrade
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			authHeader = webSocketToken(c.Request)
+		}
 		log.Printf("Auth header: %s", authHeader)
 
 		if authHeader == "" {
@@ -55,3 +64,22 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// webSocketToken turns a "bearer, <token>" Sec-WebSocket-Protocol header of
+// an upgrade request into a bearer authorization, or returns ""
+func webSocketToken(r *http.Request) string {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return ""
+	}
+
+	var protocols []string
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(value, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+	if len(protocols) != 2 || protocols[0] != WebSocketProtocol || protocols[1] == "" {
+		return ""
+	}
+	return "Bearer " + protocols[1]
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AssetService is a port seen open on an asset
+type AssetService struct {
+	Port      string    `bson:"port" json:"port"`
+	Protocol  string    `bson:"protocol,omitempty" json:"protocol,omitempty"`
+	Name      string    `bson:"name,omitempty" json:"name,omitempty"`
+	FirstSeen time.Time `bson:"first_seen" json:"first_seen"`
+	LastSeen  time.Time `bson:"last_seen" json:"last_seen"`
+}
+
+// Asset is a host discovered during an operation
+type Asset struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OperationID primitive.ObjectID   `bson:"operation_id" json:"operation_id"`
+	Host        string               `bson:"host" json:"host"`
+	Aliases     []string             `bson:"aliases,omitempty" json:"aliases,omitempty"`
+	Hostnames   []string             `bson:"hostnames,omitempty" json:"hostnames,omitempty"`
+	Services    []AssetService       `bson:"services,omitempty" json:"services,omitempty"`
+	OS          string               `bson:"os,omitempty" json:"os,omitempty"`
+	TaskIDs     []primitive.ObjectID `bson:"task_ids,omitempty" json:"task_ids,omitempty"`
+	FirstSeen   time.Time            `bson:"first_seen" json:"first_seen"`
+	LastSeen    time.Time            `bson:"last_seen" json:"last_seen"`
+	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// AssetObservation is a single sighting of a host, taken from a result or a
+// tool execution, that is folded into the asset inventory
+type AssetObservation struct {
+	Host     string
+	Hostname string
+	OS       string
+	Port     string
+	Protocol string
+	Service  string
+	TaskID   primitive.ObjectID
+	SeenAt   time.Time
+}
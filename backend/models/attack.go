@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AttackTechnique is one ATT&CK Enterprise technique or sub-technique
+type AttackTechnique struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	TechniqueID    string             `bson:"technique_id" json:"technique_id"`
+	Name           string             `bson:"name" json:"name"`
+	Description    string             `bson:"description" json:"description"`
+	Tactics        []string           `bson:"tactics" json:"tactics"`
+	Platforms      []string           `bson:"platforms,omitempty" json:"platforms,omitempty"`
+	IsSubtechnique bool               `bson:"is_subtechnique" json:"is_subtechnique"`
+	ParentID       string             `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	URL            string             `bson:"url,omitempty" json:"url,omitempty"`
+	Deprecated     bool               `bson:"deprecated,omitempty" json:"deprecated,omitempty"`
+	ImportedAt     time.Time          `bson:"imported_at" json:"imported_at"`
+}
+
+// AttackImportSummary reports what an ATT&CK catalogue import did
+type AttackImportSummary struct {
+	Source        string `json:"source"`
+	Techniques    int    `json:"techniques"`
+	Subtechniques int    `json:"subtechniques"`
+	Skipped       int    `json:"skipped"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditAction string
+
+const (
+	AuditTaskStatusChanged AuditAction = "task_status_changed"
+	AuditPhaseChanged      AuditAction = "phase_changed"
+	AuditROEViolation      AuditAction = "roe_violation"
+	AuditStatusChanged     AuditAction = "operation_status_changed"
+	AuditOperationHalted   AuditAction = "operation_halted"
+	AuditOperationResumed  AuditAction = "operation_resumed"
+)
+
+// AuditEntry records a change made to an operation or one of its tasks
+type AuditEntry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OperationID primitive.ObjectID `bson:"operation_id" json:"operation_id"`
+	TaskID      primitive.ObjectID `bson:"task_id,omitempty" json:"task_id,omitempty"`
+	Action      AuditAction        `bson:"action" json:"action"`
+	ActorID     string             `bson:"actor_id" json:"actor_id"`
+	ActorName   string             `bson:"actor_name" json:"actor_name"`
+	From        string             `bson:"from,omitempty" json:"from,omitempty"`
+	To          string             `bson:"to,omitempty" json:"to,omitempty"`
+	Message     string             `bson:"message,omitempty" json:"message,omitempty"`
+	Timestamp   time.Time          `bson:"timestamp" json:"timestamp"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Comment is a markdown message in the discussion of a task. Replies name
+// the comment they answer in ParentID.
+type Comment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TaskID      primitive.ObjectID `bson:"task_id" json:"task_id"`
+	OperationID primitive.ObjectID `bson:"operation_id" json:"operation_id"`
+	ParentID    primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitzero"`
+	AuthorID    string             `bson:"author_id" json:"author_id"`
+	AuthorName  string             `bson:"author_name" json:"author_name"`
+	Body        string             `bson:"body" json:"body"`
+	Mentions    []string           `bson:"mentions,omitempty" json:"mentions,omitempty"`
+	Edits       []CommentEdit      `bson:"edits,omitempty" json:"edits,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt   time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitzero"`
+}
+
+// CommentEdit keeps the body a comment had before it was edited
+type CommentEdit struct {
+	Body     string    `bson:"body" json:"body"`
+	EditedAt time.Time `bson:"edited_at" json:"edited_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CustodyAction string
+
+const (
+	CustodyUploaded   CustodyAction = "uploaded"
+	CustodyDownloaded CustodyAction = "downloaded"
+	CustodyVerified   CustodyAction = "verified"
+	CustodyDeleted    CustodyAction = "deleted"
+	CustodyRestored   CustodyAction = "restored"
+)
+
+// Evidence describes a file attached to a task or to one of its results. The
+// content itself is kept in GridFS under FileID.
+type Evidence struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OperationID primitive.ObjectID `bson:"operation_id" json:"operation_id"`
+	TaskID      primitive.ObjectID `bson:"task_id" json:"task_id"`
+	ResultID    primitive.ObjectID `bson:"result_id,omitempty" json:"result_id,omitzero"`
+	FileID      primitive.ObjectID `bson:"file_id" json:"-"`
+	Filename    string             `bson:"filename" json:"filename"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `bson:"size" json:"size"`
+	SHA256      string             `bson:"sha256" json:"sha256"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	UploadedBy  string             `bson:"uploaded_by" json:"uploaded_by"`
+	Custody     []CustodyEvent     `bson:"custody" json:"custody"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	DeletedAt   time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitzero"`
+}
+
+// CustodyEvent records one handling of an evidence file in its chain of
+// custody
+type CustodyEvent struct {
+	Action   CustodyAction `bson:"action" json:"action"`
+	UserID   string        `bson:"user_id" json:"user_id"`
+	Username string        `bson:"username" json:"username"`
+	ClientIP string        `bson:"client_ip,omitempty" json:"client_ip,omitempty"`
+	// Intact is set by verifications and says whether the stored content
+	// still matches the hash taken at upload
+	Intact *bool     `bson:"intact,omitempty" json:"intact,omitempty"`
+	At     time.Time `bson:"at" json:"at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GraphNode is a host in an operation's attack-path graph
+type GraphNode struct {
+	ID        string    `json:"id"`
+	Label     string    `json:"label"`
+	Hostnames []string  `json:"hostnames,omitempty"`
+	OS        string    `json:"os,omitempty"`
+	Roles     []string  `json:"roles"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// GraphEdge is a connection between two hosts recorded by a result
+type GraphEdge struct {
+	ID       string             `json:"id"`
+	Source   string             `json:"source"`
+	Target   string             `json:"target"`
+	Kind     string             `json:"kind"`
+	Port     string             `json:"port,omitempty"`
+	Via      string             `json:"via,omitempty"`
+	Tool     string             `json:"tool,omitempty"`
+	Command  string             `json:"command,omitempty"`
+	Operator string             `json:"operator,omitempty"`
+	Time     time.Time          `json:"time"`
+	TaskID   primitive.ObjectID `json:"task_id"`
+	ResultID primitive.ObjectID `json:"result_id"`
+}
+
+// AttackGraph describes lateral movement during an operation
+type AttackGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
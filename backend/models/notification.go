@@ -13,6 +13,7 @@ const (
 	NotificationTypeError   NotificationType = "error"
 	NotificationTypeWarning NotificationType = "warning"
 	NotificationTypeInfo    NotificationType = "info"
+	NotificationTypeUrgent  NotificationType = "urgent"
 )
 
 type Notification struct {
@@ -32,21 +32,153 @@ const (
 	PhaseImpact              OperationPhase = "impact"
 )
 
+// OperationPhases lists the phases in the order an engagement moves through them
+var OperationPhases = []OperationPhase{
+	PhaseReconnaissance,
+	PhaseInitialAccess,
+	PhaseExecution,
+	PhasePersistence,
+	PhasePrivilegeEscalation,
+	PhaseDefenseEvasion,
+	PhaseCredentialAccess,
+	PhaseDiscovery,
+	PhaseLateralMovement,
+	PhaseCollection,
+	PhaseCommandAndControl,
+	PhaseExfiltration,
+	PhaseImpact,
+}
+
+// Index returns the position of the phase in OperationPhases, or -1 if the
+// phase is unknown
+func (p OperationPhase) Index() int {
+	for i, phase := range OperationPhases {
+		if phase == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// Valid reports whether the phase is one of the defined phases
+func (p OperationPhase) Valid() bool {
+	return p.Index() >= 0
+}
+
+type OperationStatus string
+
+const (
+	OperationStatusPlanning  OperationStatus = "planning"
+	OperationStatusApproved  OperationStatus = "approved"
+	OperationStatusActive    OperationStatus = "active"
+	OperationStatusPaused    OperationStatus = "paused"
+	OperationStatusReporting OperationStatus = "reporting"
+	OperationStatusClosed    OperationStatus = "closed"
+	OperationStatusArchived  OperationStatus = "archived"
+	// OperationStatusHalted marks an operation stopped by its emergency kill
+	// switch. Executions and result writes are refused until it is resumed.
+	OperationStatusHalted OperationStatus = "halted"
+)
+
+// OperationHalt records why an operation was halted and the status it had
+// before, which is restored when the team lead resumes it
+type OperationHalt struct {
+	Reason         string          `bson:"reason" json:"reason"`
+	HaltedBy       string          `bson:"halted_by" json:"halted_by"`
+	HaltedAt       time.Time       `bson:"halted_at" json:"halted_at"`
+	PreviousStatus OperationStatus `bson:"previous_status" json:"previous_status"`
+}
+
+// StatusChange is one entry in an operation's lifecycle history
+type StatusChange struct {
+	From      OperationStatus `bson:"from" json:"from"`
+	To        OperationStatus `bson:"to" json:"to"`
+	ChangedBy string          `bson:"changed_by" json:"changed_by"`
+	Reason    string          `bson:"reason,omitempty" json:"reason,omitempty"`
+	ChangedAt time.Time       `bson:"changed_at" json:"changed_at"`
+}
+
+// PhaseChange is one entry in an operation's phase history
+type PhaseChange struct {
+	From      OperationPhase `bson:"from,omitempty" json:"from,omitempty"`
+	To        OperationPhase `bson:"to" json:"to"`
+	ChangedBy string         `bson:"changed_by" json:"changed_by"`
+	ChangedAt time.Time      `bson:"changed_at" json:"changed_at"`
+}
+
+// PhaseMetric summarises the time an operation has spent in one phase.
+// Duration is in seconds and includes time up to now for the current phase.
+type PhaseMetric struct {
+	Phase        OperationPhase `json:"phase"`
+	Visits       int            `json:"visits"`
+	FirstEntered time.Time      `json:"first_entered"`
+	LastLeft     time.Time      `json:"last_left,omitzero"`
+	Duration     float64        `json:"duration_seconds"`
+	Current      bool           `json:"current"`
+}
+
 type Operation struct {
-	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"_id"`
-	Name         string               `bson:"name" json:"name"`
-	Type         OperationType        `bson:"type" json:"type"`
-	Description  string               `bson:"description" json:"description"`
-	Scope        string               `bson:"scope" json:"scope"`
-	ROE          string               `bson:"roe" json:"roe"` // Rules of Engagement
-	TeamLead     primitive.ObjectID   `bson:"team_lead" json:"team_lead"`
-	Members      []primitive.ObjectID `bson:"members" json:"members"`
-	CurrentPhase OperationPhase       `bson:"current_phase" json:"current_phase"`
-	Status       string               `bson:"status" json:"status"`
-	StartDate    time.Time            `bson:"start_date" json:"start_date"`
-	EndDate      time.Time            `bson:"end_date" json:"end_date"`
-	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
-	UpdatedAt    time.Time            `bson:"updated_at" json:"updated_at"`
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"_id"`
+	Name          string               `bson:"name" json:"name"`
+	Type          OperationType        `bson:"type" json:"type"`
+	Description   string               `bson:"description" json:"description"`
+	Scope         string               `bson:"scope" json:"scope"`
+	ROE           string               `bson:"roe" json:"roe"` // Rules of Engagement
+	ROERules      *ROERules            `bson:"roe_rules,omitempty" json:"roe_rules,omitempty"`
+	TeamLead      primitive.ObjectID   `bson:"team_lead" json:"team_lead"`
+	Members       []primitive.ObjectID `bson:"members" json:"members"`
+	CurrentPhase  OperationPhase       `bson:"current_phase" json:"current_phase"`
+	PhaseHistory  []PhaseChange        `bson:"phase_history,omitempty" json:"phase_history,omitempty"`
+	Status        OperationStatus      `bson:"status" json:"status"`
+	StatusHistory []StatusChange       `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	Halt          *OperationHalt       `bson:"halt,omitempty" json:"halt,omitempty"`
+	StartDate     time.Time            `bson:"start_date" json:"start_date"`
+	EndDate       time.Time            `bson:"end_date" json:"end_date"`
+	CreatedAt     time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updated_at"`
+	Version       int64                `bson:"version" json:"version"`
+	DeletedAt     time.Time            `bson:"deleted_at,omitempty" json:"deleted_at,omitzero"`
+}
+
+// OperationPatchFields lists the fields a PATCH may change. Status, phase and
+// the structured rules of engagement have endpoints of their own.
+var OperationPatchFields = []string{
+	"name", "type", "description", "scope", "roe", "team_lead", "members", "start_date", "end_date",
+}
+
+// Halted reports whether the operation's kill switch is engaged
+func (o *Operation) Halted() bool {
+	return o.Status == OperationStatusHalted
+}
+
+// Archived reports whether the operation has been archived and is read-only
+func (o *Operation) Archived() bool {
+	return o.Status == OperationStatusArchived
+}
+
+// Team returns the team lead followed by the members, each once
+func (o *Operation) Team() []primitive.ObjectID {
+	team := make([]primitive.ObjectID, 0, len(o.Members)+1)
+	seen := make(map[primitive.ObjectID]bool)
+	for _, id := range append([]primitive.ObjectID{o.TeamLead}, o.Members...) {
+		if id.IsZero() || seen[id] {
+			continue
+		}
+		seen[id] = true
+		team = append(team, id)
+	}
+	return team
+}
+
+// IsMember reports whether the user is the team lead or a member of the
+// operation
+func (o *Operation) IsMember(userID primitive.ObjectID) bool {
+	for _, id := range o.Team() {
+		if id == userID {
+			return true
+		}
+	}
+	return false
 }
 
 type OperationResponse struct {
@@ -59,7 +191,7 @@ type OperationResponse struct {
 	TeamLead     UserResponse       `json:"team_lead"`
 	Members      []UserResponse     `json:"members"`
 	CurrentPhase OperationPhase     `json:"current_phase"`
-	Status       string             `json:"status"`
+	Status       OperationStatus    `json:"status"`
 	StartDate    time.Time          `json:"start_date"`
 	EndDate      time.Time          `json:"end_date"`
 	CreatedAt    time.Time          `json:"created_at"`
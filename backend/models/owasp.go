@@ -0,0 +1,32 @@
+package models
+
+// OWASPCategory is one OWASP Top 10 (2021) category
+type OWASPCategory struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// WSTGTest is one test case of the OWASP Web Security Testing Guide
+type WSTGTest struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	// Top10 is the Top 10 category the test mostly exercises
+	Top10 string `json:"top10"`
+}
+
+// OWASPCoverage summarises an operation's tasks for one Top 10 category
+type OWASPCoverage struct {
+	Category   string `json:"category"`
+	Name       string `json:"name"`
+	Tasks      int    `json:"tasks"`
+	Pending    int    `json:"pending"`
+	InProgress int    `json:"in_progress"`
+	Completed  int    `json:"completed"`
+	Blocked    int    `json:"blocked"`
+	// WSTGTests counts the guide's tests mapped to the category and
+	// WSTGPlanned how many of them the operation has a task for
+	WSTGTests   int `json:"wstg_tests"`
+	WSTGPlanned int `json:"wstg_planned"`
+}
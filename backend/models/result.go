@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,6 +15,8 @@ type Result struct {
 	TaskID             primitive.ObjectID `bson:"taskId" json:"taskId"`
 	Start              string             `bson:"start,omitempty" json:"start,omitempty"`
 	End                string             `bson:"end,omitempty" json:"end,omitempty"`
+	StartTime          time.Time          `bson:"startTime,omitempty" json:"startTime,omitzero"`
+	EndTime            time.Time          `bson:"endTime,omitempty" json:"endTime,omitzero"`
 	SourceIP           string             `bson:"sourceIP,omitempty" json:"sourceIP,omitempty"`
 	DestinationIP      string             `bson:"destinationIP,omitempty" json:"destinationIP,omitempty"`
 	DestinationPort    string             `bson:"destinationPort,omitempty" json:"destinationPort,omitempty"`
@@ -27,6 +32,75 @@ type Result struct {
 	SystemModification string             `bson:"systemModification,omitempty" json:"systemModification,omitempty"`
 	Comments           string             `bson:"comments,omitempty" json:"comments,omitempty"`
 	OperatorName       string             `bson:"operatorName,omitempty" json:"operatorName,omitempty"`
+	Fingerprint        string             `bson:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+	OutOfScope         bool               `bson:"outOfScope,omitempty" json:"outOfScope,omitempty"`
+	ScopeReason        string             `bson:"scopeReason,omitempty" json:"scopeReason,omitempty"`
+	TrashedFingerprint string             `bson:"trashedFingerprint,omitempty" json:"-"`
 	CreatedAt          time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt          time.Time          `bson:"updatedAt" json:"updatedAt"`
+	DeletedAt          time.Time          `bson:"deletedAt,omitempty" json:"deletedAt,omitzero"`
+}
+
+// ImportMode controls how an import treats rows that already exist
+type ImportMode string
+
+const (
+	ImportAppend         ImportMode = "append"
+	ImportSkipDuplicates ImportMode = "skip-duplicates"
+	ImportReplace        ImportMode = "replace"
+)
+
+// Valid reports whether m is one of the known import modes
+func (m ImportMode) Valid() bool {
+	switch m {
+	case ImportAppend, ImportSkipDuplicates, ImportReplace:
+		return true
+	}
+	return false
+}
+
+// ImportSummary reports what an import did with each row of the sheet
+type ImportSummary struct {
+	Mode       ImportMode        `json:"mode"`
+	Total      int               `json:"total"`
+	New        int               `json:"new"`
+	Duplicate  int               `json:"duplicate"`
+	Updated    int               `json:"updated"`
+	Rejected   int               `json:"rejected"`
+	Violations []ImportViolation `json:"violations,omitempty"`
+}
+
+// ImportViolation lists the rules of engagement a sheet row breaks. Row is
+// the 1-based spreadsheet row number.
+type ImportViolation struct {
+	Row        int            `json:"row"`
+	Rejected   bool           `json:"rejected"`
+	Violations []ROEViolation `json:"violations"`
+}
+
+// ComputeFingerprint identifies a result by the content that makes it
+// unique: task, source, destination, port, command and start time.
+func (r *Result) ComputeFingerprint() string {
+	start := strings.TrimSpace(r.Start)
+	if !r.StartTime.IsZero() {
+		start = r.StartTime.UTC().Format(time.RFC3339)
+	}
+
+	parts := []string{
+		r.TaskID.Hex(),
+		strings.ToLower(strings.TrimSpace(r.SourceIP)),
+		strings.ToLower(strings.TrimSpace(r.DestinationIP)),
+		strings.TrimSpace(r.DestinationPort),
+		strings.TrimSpace(r.Command),
+		start,
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResultPage is a single page of results returned by a paginated listing
+type ResultPage struct {
+	Items      []Result `json:"items"`
+	NextCursor string   `json:"nextCursor,omitempty"`
 }
@@ -0,0 +1,56 @@
+package models
+
+type ROEEnforcement string
+
+const (
+	ROEWarn  ROEEnforcement = "warn"
+	ROEBlock ROEEnforcement = "block"
+)
+
+// ToolTypeRestriction forbids tools of a type until the operation reaches a phase
+type ToolTypeRestriction struct {
+	Type             ToolType       `bson:"type" json:"type"`
+	AllowedFromPhase OperationPhase `bson:"allowed_from_phase" json:"allowed_from_phase"`
+	Enforcement      ROEEnforcement `bson:"enforcement" json:"enforcement"`
+}
+
+// CommandPattern is a regular expression that commands must not match
+type CommandPattern struct {
+	Pattern     string         `bson:"pattern" json:"pattern"`
+	Description string         `bson:"description,omitempty" json:"description,omitempty"`
+	Enforcement ROEEnforcement `bson:"enforcement" json:"enforcement"`
+}
+
+// TestingHours limits activity to a daily window. Start and End are HH:MM in
+// Timezone; an End before Start spans midnight. Days holds lowercase
+// three-letter weekday names and allows every day when empty.
+type TestingHours struct {
+	Start       string         `bson:"start" json:"start"`
+	End         string         `bson:"end" json:"end"`
+	Days        []string       `bson:"days,omitempty" json:"days,omitempty"`
+	Timezone    string         `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	Enforcement ROEEnforcement `bson:"enforcement" json:"enforcement"`
+}
+
+// ScanRateLimit caps how hard the operation may hit the client. Zero values
+// leave the corresponding limit off.
+type ScanRateLimit struct {
+	MaxExecutionsPerMinute int            `bson:"max_executions_per_minute,omitempty" json:"max_executions_per_minute,omitempty"`
+	MaxPacketsPerSecond    int            `bson:"max_packets_per_second,omitempty" json:"max_packets_per_second,omitempty"`
+	Enforcement            ROEEnforcement `bson:"enforcement" json:"enforcement"`
+}
+
+// ROERules are the machine-checkable part of an operation's rules of engagement
+type ROERules struct {
+	ToolTypeRestrictions []ToolTypeRestriction `bson:"tool_type_restrictions,omitempty" json:"tool_type_restrictions,omitempty"`
+	DeniedCommands       []CommandPattern      `bson:"denied_commands,omitempty" json:"denied_commands,omitempty"`
+	TestingHours         *TestingHours         `bson:"testing_hours,omitempty" json:"testing_hours,omitempty"`
+	ScanRate             *ScanRateLimit        `bson:"scan_rate,omitempty" json:"scan_rate,omitempty"`
+}
+
+// ROEViolation describes one rule an action breaks
+type ROEViolation struct {
+	Rule        string         `bson:"rule" json:"rule"`
+	Message     string         `bson:"message" json:"message"`
+	Enforcement ROEEnforcement `bson:"enforcement" json:"enforcement"`
+}
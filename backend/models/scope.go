@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type ScopeRuleKind string
+
+const (
+	ScopeCIDR    ScopeRuleKind = "cidr"
+	ScopeIPRange ScopeRuleKind = "ip_range"
+	ScopeDomain  ScopeRuleKind = "domain"
+	ScopeURL     ScopeRuleKind = "url"
+)
+
+// ScopeRule is a single include or exclude entry of an engagement scope
+type ScopeRule struct {
+	Kind  ScopeRuleKind `json:"kind"`
+	Value string        `json:"value"`
+}
+
+// ScopeWindow is a period during which testing is permitted
+type ScopeWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ScopeDefinition is the structured form of Operation.Scope
+type ScopeDefinition struct {
+	Include  []ScopeRule   `json:"include"`
+	Exclude  []ScopeRule   `json:"exclude"`
+	Windows  []ScopeWindow `json:"windows"`
+	Unparsed []string      `json:"unparsed,omitempty"`
+}
+
+// ScopeVerdict is the outcome of checking one target against a scope
+type ScopeVerdict struct {
+	Target  string `json:"target"`
+	InScope bool   `json:"in_scope"`
+	Reason  string `json:"reason"`
+	Rule    string `json:"rule,omitempty"`
+}
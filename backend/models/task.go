@@ -16,21 +16,43 @@ const (
 )
 
 type Task struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	OperationID primitive.ObjectID `bson:"operation_id" json:"operation_id"`
-	Title       string             `bson:"title" json:"title"`
-	Description string             `bson:"description" json:"description"`
-	AssignedTo  primitive.ObjectID `bson:"assigned_to" json:"assigned_to"`
-	Status      TaskStatus         `bson:"status" json:"status"`
-	Phase       OperationPhase     `bson:"phase" json:"phase"`
-	MITREID     string             `bson:"mitre_id" json:"mitre_id"`
-	OWASPID     string             `bson:"owasp_id" json:"owasp_id"`
-	Results     string             `bson:"results" json:"results"`
-	Tools       []string           `bson:"tools" json:"tools"`
-	StartDate   time.Time          `bson:"start_date" json:"start_date"`
-	EndDate     time.Time          `bson:"end_date" json:"end_date"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID             primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OperationID    primitive.ObjectID   `bson:"operation_id" json:"operation_id"`
+	Title          string               `bson:"title" json:"title"`
+	Description    string               `bson:"description" json:"description"`
+	AssignedTo     primitive.ObjectID   `bson:"assigned_to" json:"assigned_to"`
+	Status         TaskStatus           `bson:"status" json:"status"`
+	StatusHistory  []TaskStatusChange   `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	Phase          OperationPhase       `bson:"phase" json:"phase"`
+	MITREID        string               `bson:"mitre_id" json:"mitre_id"`
+	OWASPID        string               `bson:"owasp_id" json:"owasp_id"`
+	Results        string               `bson:"results" json:"results"`
+	Tools          []string             `bson:"tools" json:"tools"`
+	Tags           []string             `bson:"tags,omitempty" json:"tags,omitempty"`
+	DependsOn      []primitive.ObjectID `bson:"depends_on,omitempty" json:"depends_on,omitempty"`
+	EstimatedHours float64              `bson:"estimated_hours,omitempty" json:"estimated_hours,omitempty"`
+	StartDate      time.Time            `bson:"start_date" json:"start_date"`
+	EndDate        time.Time            `bson:"end_date" json:"end_date"`
+	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+	Version        int64                `bson:"version" json:"version"`
+	DeletedAt      time.Time            `bson:"deleted_at,omitempty" json:"deleted_at,omitzero"`
+}
+
+// TaskStatusChange is one entry in a task's status history
+type TaskStatusChange struct {
+	From      TaskStatus `bson:"from,omitempty" json:"from,omitempty"`
+	To        TaskStatus `bson:"to" json:"to"`
+	ChangedBy string     `bson:"changed_by" json:"changed_by"`
+	Reason    string     `bson:"reason,omitempty" json:"reason,omitempty"`
+	ChangedAt time.Time  `bson:"changed_at" json:"changed_at"`
+}
+
+// TaskPatchFields lists the fields a PATCH may change. Status has an endpoint
+// of its own so that its transitions are checked.
+var TaskPatchFields = []string{
+	"title", "description", "assigned_to", "phase", "mitre_id", "owasp_id", "results", "tools", "tags",
+	"depends_on", "estimated_hours", "start_date", "end_date",
 }
 
 type TaskResponse struct {
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplateTask is a task in an engagement template's plan
+type TemplateTask struct {
+	Title       string         `bson:"title" json:"title"`
+	Description string         `bson:"description" json:"description"`
+	Phase       OperationPhase `bson:"phase" json:"phase"`
+	MITREID     string         `bson:"mitre_id" json:"mitre_id"`
+	OWASPID     string         `bson:"owasp_id" json:"owasp_id"`
+	Tools       []string       `bson:"tools" json:"tools"`
+}
+
+// OperationTemplate is a reusable engagement plan that new operations can be
+// created from
+type OperationTemplate struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name" binding:"required"`
+	Description string             `bson:"description" json:"description"`
+	Type        OperationType      `bson:"type" json:"type"`
+	ROE         string             `bson:"roe" json:"roe"`
+	ROERules    *ROERules          `bson:"roe_rules,omitempty" json:"roe_rules,omitempty"`
+	Tasks       []TemplateTask     `bson:"tasks" json:"tasks"`
+	CreatedBy   string             `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TimeEntry is a stretch of time an operator spent on a task, either
+// recorded with a timer or entered by hand. A running timer has no end yet
+// and is the only entry with Running set.
+type TimeEntry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OperationID primitive.ObjectID `bson:"operation_id" json:"operation_id"`
+	TaskID      primitive.ObjectID `bson:"task_id" json:"task_id"`
+	UserID      string             `bson:"user_id" json:"user_id"`
+	Username    string             `bson:"username" json:"username"`
+	Start       time.Time          `bson:"start" json:"start"`
+	End         time.Time          `bson:"end,omitempty" json:"end,omitzero"`
+	Running     bool               `bson:"running,omitempty" json:"running"`
+	Manual      bool               `bson:"manual" json:"manual"`
+	Note        string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt   time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitzero"`
+}
+
+// Hours returns the length of the entry, counting a running timer up to now
+func (e *TimeEntry) Hours(now time.Time) float64 {
+	end := e.End
+	if e.Running {
+		end = now
+	}
+	if end.Before(e.Start) {
+		return 0
+	}
+	return end.Sub(e.Start).Hours()
+}
+
+// TimeTotal is the number of hours booked against one task, phase or
+// operator
+type TimeTotal struct {
+	Key     string  `json:"key"`
+	Name    string  `json:"name"`
+	Hours   float64 `json:"hours"`
+	Entries int     `json:"entries"`
+}
+
+// TimeReport rolls up the time booked on an operation
+type TimeReport struct {
+	OperationID primitive.ObjectID `json:"operation_id"`
+	TotalHours  float64            `json:"total_hours"`
+	Running     int                `json:"running"`
+	ByTask      []TimeTotal        `json:"by_task"`
+	ByPhase     []TimeTotal        `json:"by_phase"`
+	ByOperator  []TimeTotal        `json:"by_operator"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TimelineKind string
+
+const (
+	TimelineResult      TimelineKind = "result"
+	TimelineTaskStatus  TimelineKind = "task_status"
+	TimelinePhaseChange TimelineKind = "phase_change"
+)
+
+// TimelineEntry is a single event in an operation's activity timeline
+type TimelineEntry struct {
+	Time      time.Time          `json:"time"`
+	EndTime   time.Time          `json:"end_time,omitzero"`
+	Kind      TimelineKind       `json:"kind"`
+	TaskID    primitive.ObjectID `json:"task_id,omitzero"`
+	TaskTitle string             `json:"task_title,omitempty"`
+	ResultID  primitive.ObjectID `json:"result_id,omitzero"`
+	Actor     string             `json:"actor,omitempty"`
+	From      string             `json:"from,omitempty"`
+	To        string             `json:"to,omitempty"`
+	Summary   string             `json:"summary"`
+	Result    *Result            `json:"result,omitempty"`
+}
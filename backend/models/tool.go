@@ -26,17 +26,38 @@ type Tool struct {
 	IsActive     bool               `bson:"is_active" json:"is_active"`
 	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	Version      int64              `bson:"version" json:"version"`
+	DeletedAt    time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitzero"`
 }
 
+// ToolPatchFields lists the fields a PATCH may change
+var ToolPatchFields = []string{
+	"name", "type", "description", "command", "arguments", "output_format", "is_active",
+}
+
+type ExecutionStatus string
+
+const (
+	ExecutionQueued    ExecutionStatus = "queued"
+	ExecutionRunning   ExecutionStatus = "running"
+	ExecutionCompleted ExecutionStatus = "completed"
+	ExecutionFailed    ExecutionStatus = "failed"
+	ExecutionCancelled ExecutionStatus = "cancelled"
+)
+
 type ToolExecution struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	ToolID    primitive.ObjectID `bson:"tool_id" json:"tool_id"`
-	TaskID    primitive.ObjectID `bson:"task_id" json:"task_id"`
-	Command   string             `bson:"command" json:"command"`
-	Arguments map[string]string  `bson:"arguments" json:"arguments"`
-	Output    string             `bson:"output" json:"output"`
-	Status    string             `bson:"status" json:"status"`
-	StartTime time.Time          `bson:"start_time" json:"start_time"`
-	EndTime   time.Time          `bson:"end_time" json:"end_time"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ToolID      primitive.ObjectID `bson:"tool_id" json:"tool_id"`
+	TaskID      primitive.ObjectID `bson:"task_id" json:"task_id"`
+	OperationID primitive.ObjectID `bson:"operation_id" json:"operation_id"`
+	UserID      string             `bson:"user_id" json:"user_id"`
+	Command     string             `bson:"command" json:"command"`
+	Arguments   map[string]string  `bson:"arguments" json:"arguments"`
+	Output      string             `bson:"output" json:"output"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	Warnings    []ROEViolation     `bson:"warnings,omitempty" json:"warnings,omitempty"`
+	Status      ExecutionStatus    `bson:"status" json:"status"`
+	StartTime   time.Time          `bson:"start_time" json:"start_time"`
+	EndTime     time.Time          `bson:"end_time" json:"end_time"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 }
@@ -24,6 +24,10 @@ type User struct {
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// UserPatchFields lists the fields a PATCH may change. Only admins may change
+// a role.
+var UserPatchFields = []string{"username", "email", "role"}
+
 type UserResponse struct {
 	ID        primitive.ObjectID `json:"id"`
 	Username  string             `json:"username"`
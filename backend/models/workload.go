@@ -0,0 +1,43 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MemberWorkload is the open work assigned to one user across the
+// operations still running. Tasks without an estimate are counted in
+// Unestimated and weigh one hour in LoadHours.
+type MemberWorkload struct {
+	UserID         primitive.ObjectID  `json:"user_id"`
+	Username       string              `json:"username"`
+	OpenTasks      int                 `json:"open_tasks"`
+	InProgress     int                 `json:"in_progress"`
+	Blocked        int                 `json:"blocked"`
+	EstimatedHours float64             `json:"estimated_hours"`
+	Unestimated    int                 `json:"unestimated"`
+	LoadHours      float64             `json:"load_hours"`
+	Operations     []OperationWorkload `json:"operations"`
+}
+
+// OperationWorkload is a member's share of the open work of one operation
+type OperationWorkload struct {
+	OperationID    primitive.ObjectID `json:"operation_id"`
+	Name           string             `json:"name"`
+	OpenTasks      int                `json:"open_tasks"`
+	EstimatedHours float64            `json:"estimated_hours"`
+}
+
+// AssignmentCriteria describes the task an assignee is wanted for
+type AssignmentCriteria struct {
+	Phase   OperationPhase `json:"phase,omitempty"`
+	MITREID string         `json:"mitre_id,omitempty"`
+	OWASPID string         `json:"owasp_id,omitempty"`
+	Tools   []string       `json:"tools,omitempty"`
+}
+
+// AssignmentCandidate is a team member ranked for a new task. Experience
+// counts the member's completed tasks that resemble it.
+type AssignmentCandidate struct {
+	MemberWorkload
+	Qualified  bool     `json:"qualified"`
+	Experience int      `json:"experience"`
+	Reasons    []string `json:"reasons"`
+}
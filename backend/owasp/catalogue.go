@@ -0,0 +1,170 @@
+// Package owasp holds the OWASP Top 10 (2021) and Web Security Testing
+// Guide (v4.2) catalogues that web application tasks are tagged with.
+package owasp
+
+import (
+	"redops/models"
+)
+
+var top10 = []models.OWASPCategory{
+	{ID: "A01:2021", Name: "Broken Access Control", URL: "https://owasp.org/Top10/A01_2021-Broken_Access_Control/"},
+	{ID: "A02:2021", Name: "Cryptographic Failures", URL: "https://owasp.org/Top10/A02_2021-Cryptographic_Failures/"},
+	{ID: "A03:2021", Name: "Injection", URL: "https://owasp.org/Top10/A03_2021-Injection/"},
+	{ID: "A04:2021", Name: "Insecure Design", URL: "https://owasp.org/Top10/A04_2021-Insecure_Design/"},
+	{ID: "A05:2021", Name: "Security Misconfiguration", URL: "https://owasp.org/Top10/A05_2021-Security_Misconfiguration/"},
+	{ID: "A06:2021", Name: "Vulnerable and Outdated Components", URL: "https://owasp.org/Top10/A06_2021-Vulnerable_and_Outdated_Components/"},
+	{ID: "A07:2021", Name: "Identification and Authentication Failures", URL: "https://owasp.org/Top10/A07_2021-Identification_and_Authentication_Failures/"},
+	{ID: "A08:2021", Name: "Software and Data Integrity Failures", URL: "https://owasp.org/Top10/A08_2021-Software_and_Data_Integrity_Failures/"},
+	{ID: "A09:2021", Name: "Security Logging and Monitoring Failures", URL: "https://owasp.org/Top10/A09_2021-Security_Logging_and_Monitoring_Failures/"},
+	{ID: "A10:2021", Name: "Server-Side Request Forgery (SSRF)", URL: "https://owasp.org/Top10/A10_2021-Server-Side_Request_Forgery_%28SSRF%29/"},
+}
+
+// wstgCategories lists the guide's test categories in order, with the Top
+// 10 category their tests map to unless overridden below and the operation
+// phase generated tasks are filed under
+var wstgCategories = []struct {
+	Code  string
+	Name  string
+	Top10 string
+	Phase models.OperationPhase
+}{
+	{"INFO", "Information Gathering", "A05:2021", models.PhaseReconnaissance},
+	{"CONF", "Configuration and Deployment Management Testing", "A05:2021", models.PhaseDiscovery},
+	{"IDNT", "Identity Management Testing", "A07:2021", models.PhaseDiscovery},
+	{"ATHN", "Authentication Testing", "A07:2021", models.PhaseCredentialAccess},
+	{"ATHZ", "Authorization Testing", "A01:2021", models.PhasePrivilegeEscalation},
+	{"SESS", "Session Management Testing", "A07:2021", models.PhaseCredentialAccess},
+	{"INPV", "Input Validation Testing", "A03:2021", models.PhaseInitialAccess},
+	{"ERRH", "Testing for Error Handling", "A05:2021", models.PhaseDiscovery},
+	{"CRYP", "Testing for Weak Cryptography", "A02:2021", models.PhaseDiscovery},
+	{"BUSL", "Business Logic Testing", "A04:2021", models.PhaseInitialAccess},
+	{"CLNT", "Client-side Testing", "A03:2021", models.PhaseInitialAccess},
+	{"APIT", "API Testing", "A03:2021", models.PhaseInitialAccess},
+}
+
+// wstg lists the guide's tests. Top10 is only given where a test maps
+// elsewhere than the rest of its category.
+var wstg = []models.WSTGTest{
+	{ID: "WSTG-INFO-01", Name: "Conduct Search Engine Discovery Reconnaissance for Information Leakage"},
+	{ID: "WSTG-INFO-02", Name: "Fingerprint Web Server", Top10: "A06:2021"},
+	{ID: "WSTG-INFO-03", Name: "Review Webserver Metafiles for Information Leakage"},
+	{ID: "WSTG-INFO-04", Name: "Enumerate Applications on Webserver"},
+	{ID: "WSTG-INFO-05", Name: "Review Webpage Content for Information Leakage"},
+	{ID: "WSTG-INFO-06", Name: "Identify Application Entry Points"},
+	{ID: "WSTG-INFO-07", Name: "Map Execution Paths Through Application"},
+	{ID: "WSTG-INFO-08", Name: "Fingerprint Web Application Framework", Top10: "A06:2021"},
+	{ID: "WSTG-INFO-09", Name: "Fingerprint Web Application", Top10: "A06:2021"},
+	{ID: "WSTG-INFO-10", Name: "Map Application Architecture"},
+
+	{ID: "WSTG-CONF-01", Name: "Test Network Infrastructure Configuration"},
+	{ID: "WSTG-CONF-02", Name: "Test Application Platform Configuration"},
+	{ID: "WSTG-CONF-03", Name: "Test File Extensions Handling for Sensitive Information"},
+	{ID: "WSTG-CONF-04", Name: "Review Old Backup and Unreferenced Files for Sensitive Information"},
+	{ID: "WSTG-CONF-05", Name: "Enumerate Infrastructure and Application Admin Interfaces"},
+	{ID: "WSTG-CONF-06", Name: "Test HTTP Methods"},
+	{ID: "WSTG-CONF-07", Name: "Test HTTP Strict Transport Security", Top10: "A02:2021"},
+	{ID: "WSTG-CONF-08", Name: "Test RIA Cross Domain Policy"},
+	{ID: "WSTG-CONF-09", Name: "Test File Permission"},
+	{ID: "WSTG-CONF-10", Name: "Test for Subdomain Takeover"},
+	{ID: "WSTG-CONF-11", Name: "Test Cloud Storage"},
+
+	{ID: "WSTG-IDNT-01", Name: "Test Role Definitions", Top10: "A01:2021"},
+	{ID: "WSTG-IDNT-02", Name: "Test User Registration Process"},
+	{ID: "WSTG-IDNT-03", Name: "Test Account Provisioning Process"},
+	{ID: "WSTG-IDNT-04", Name: "Testing for Account Enumeration and Guessable User Account"},
+	{ID: "WSTG-IDNT-05", Name: "Testing for Weak or Unenforced Username Policy"},
+
+	{ID: "WSTG-ATHN-01", Name: "Testing for Credentials Transported over an Encrypted Channel", Top10: "A02:2021"},
+	{ID: "WSTG-ATHN-02", Name: "Testing for Default Credentials"},
+	{ID: "WSTG-ATHN-03", Name: "Testing for Weak Lock Out Mechanism"},
+	{ID: "WSTG-ATHN-04", Name: "Testing for Bypassing Authentication Schema"},
+	{ID: "WSTG-ATHN-05", Name: "Testing for Vulnerable Remember Password"},
+	{ID: "WSTG-ATHN-06", Name: "Testing for Browser Cache Weaknesses"},
+	{ID: "WSTG-ATHN-07", Name: "Testing for Weak Password Policy"},
+	{ID: "WSTG-ATHN-08", Name: "Testing for Weak Security Question Answer"},
+	{ID: "WSTG-ATHN-09", Name: "Testing for Weak Password Change or Reset Functionalities"},
+	{ID: "WSTG-ATHN-10", Name: "Testing for Weaker Authentication in Alternative Channel"},
+
+	{ID: "WSTG-ATHZ-01", Name: "Testing Directory Traversal File Include"},
+	{ID: "WSTG-ATHZ-02", Name: "Testing for Bypassing Authorization Schema"},
+	{ID: "WSTG-ATHZ-03", Name: "Testing for Privilege Escalation"},
+	{ID: "WSTG-ATHZ-04", Name: "Testing for Insecure Direct Object References"},
+
+	{ID: "WSTG-SESS-01", Name: "Testing for Session Management Schema"},
+	{ID: "WSTG-SESS-02", Name: "Testing for Cookies Attributes", Top10: "A05:2021"},
+	{ID: "WSTG-SESS-03", Name: "Testing for Session Fixation"},
+	{ID: "WSTG-SESS-04", Name: "Testing for Exposed Session Variables"},
+	{ID: "WSTG-SESS-05", Name: "Testing for Cross Site Request Forgery", Top10: "A01:2021"},
+	{ID: "WSTG-SESS-06", Name: "Testing for Logout Functionality"},
+	{ID: "WSTG-SESS-07", Name: "Testing Session Timeout"},
+	{ID: "WSTG-SESS-08", Name: "Testing for Session Puzzling"},
+	{ID: "WSTG-SESS-09", Name: "Testing for Session Hijacking"},
+
+	{ID: "WSTG-INPV-01", Name: "Testing for Reflected Cross Site Scripting"},
+	{ID: "WSTG-INPV-02", Name: "Testing for Stored Cross Site Scripting"},
+	{ID: "WSTG-INPV-03", Name: "Testing for HTTP Verb Tampering", Top10: "A01:2021"},
+	{ID: "WSTG-INPV-04", Name: "Testing for HTTP Parameter Pollution"},
+	{ID: "WSTG-INPV-05", Name: "Testing for SQL Injection"},
+	{ID: "WSTG-INPV-06", Name: "Testing for LDAP Injection"},
+	{ID: "WSTG-INPV-07", Name: "Testing for XML Injection"},
+	{ID: "WSTG-INPV-08", Name: "Testing for SSI Injection"},
+	{ID: "WSTG-INPV-09", Name: "Testing for XPath Injection"},
+	{ID: "WSTG-INPV-10", Name: "Testing for IMAP SMTP Injection"},
+	{ID: "WSTG-INPV-11", Name: "Testing for Code Injection"},
+	{ID: "WSTG-INPV-12", Name: "Testing for Command Injection"},
+	{ID: "WSTG-INPV-13", Name: "Testing for Format String Injection"},
+	{ID: "WSTG-INPV-14", Name: "Testing for Incubated Vulnerability"},
+	{ID: "WSTG-INPV-15", Name: "Testing for HTTP Splitting Smuggling"},
+	{ID: "WSTG-INPV-16", Name: "Testing for HTTP Incoming Requests", Top10: "A09:2021"},
+	{ID: "WSTG-INPV-17", Name: "Testing for Host Header Injection"},
+	{ID: "WSTG-INPV-18", Name: "Testing for Server-side Template Injection"},
+	{ID: "WSTG-INPV-19", Name: "Testing for Server-Side Request Forgery", Top10: "A10:2021"},
+
+	{ID: "WSTG-ERRH-01", Name: "Testing for Improper Error Handling"},
+	{ID: "WSTG-ERRH-02", Name: "Testing for Stack Traces"},
+
+	{ID: "WSTG-CRYP-01", Name: "Testing for Weak Transport Layer Security"},
+	{ID: "WSTG-CRYP-02", Name: "Testing for Padding Oracle"},
+	{ID: "WSTG-CRYP-03", Name: "Testing for Sensitive Information Sent via Unencrypted Channels"},
+	{ID: "WSTG-CRYP-04", Name: "Testing for Weak Encryption"},
+
+	{ID: "WSTG-BUSL-01", Name: "Test Business Logic Data Validation"},
+	{ID: "WSTG-BUSL-02", Name: "Test Ability to Forge Requests"},
+	{ID: "WSTG-BUSL-03", Name: "Test Integrity Checks", Top10: "A08:2021"},
+	{ID: "WSTG-BUSL-04", Name: "Test for Process Timing"},
+	{ID: "WSTG-BUSL-05", Name: "Test Number of Times a Function Can Be Used Limits"},
+	{ID: "WSTG-BUSL-06", Name: "Testing for the Circumvention of Work Flows"},
+	{ID: "WSTG-BUSL-07", Name: "Test Defenses Against Application Misuse", Top10: "A09:2021"},
+	{ID: "WSTG-BUSL-08", Name: "Test Upload of Unexpected File Types"},
+	{ID: "WSTG-BUSL-09", Name: "Test Upload of Malicious Files"},
+
+	{ID: "WSTG-CLNT-01", Name: "Testing for DOM-Based Cross Site Scripting"},
+	{ID: "WSTG-CLNT-02", Name: "Testing for JavaScript Execution"},
+	{ID: "WSTG-CLNT-03", Name: "Testing for HTML Injection"},
+	{ID: "WSTG-CLNT-04", Name: "Testing for Client-side URL Redirect", Top10: "A01:2021"},
+	{ID: "WSTG-CLNT-05", Name: "Testing for CSS Injection"},
+	{ID: "WSTG-CLNT-06", Name: "Testing for Client-side Resource Manipulation"},
+	{ID: "WSTG-CLNT-07", Name: "Testing Cross Origin Resource Sharing", Top10: "A05:2021"},
+	{ID: "WSTG-CLNT-08", Name: "Testing for Cross Site Flashing"},
+	{ID: "WSTG-CLNT-09", Name: "Testing for Clickjacking", Top10: "A05:2021"},
+	{ID: "WSTG-CLNT-10", Name: "Testing WebSockets"},
+	{ID: "WSTG-CLNT-11", Name: "Testing Web Messaging"},
+	{ID: "WSTG-CLNT-12", Name: "Testing Browser Storage", Top10: "A02:2021"},
+	{ID: "WSTG-CLNT-13", Name: "Testing for Cross Site Script Inclusion"},
+
+	{ID: "WSTG-APIT-01", Name: "Testing GraphQL"},
+}
+
+func init() {
+	for i := range wstg {
+		code := wstg[i].ID[len("WSTG-") : len("WSTG-")+4]
+		wstg[i].Category = code
+		if wstg[i].Top10 == "" {
+			for _, category := range wstgCategories {
+				if category.Code == code {
+					wstg[i].Top10 = category.Top10
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package owasp
+
+import (
+	"regexp"
+	"strings"
+
+	"redops/models"
+)
+
+var shortTop10Pattern = regexp.MustCompile(`^A(0[1-9]|10)$`)
+
+// Top10 returns the OWASP Top 10 (2021) categories in order
+func Top10() []models.OWASPCategory {
+	return append([]models.OWASPCategory(nil), top10...)
+}
+
+// WSTG returns the guide's tests in order, limited to the given category
+// codes (such as "INPV") when any are passed
+func WSTG(categories ...string) []models.WSTGTest {
+	tests := []models.WSTGTest{}
+	for _, test := range wstg {
+		if len(categories) == 0 || containsFold(categories, test.Category) {
+			tests = append(tests, test)
+		}
+	}
+	return tests
+}
+
+// Normalize tidies an OWASP ID as typed by an operator. Top 10 IDs without
+// a year, such as "a03", are read as the 2021 edition.
+func Normalize(id string) string {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if shortTop10Pattern.MatchString(id) {
+		id += ":2021"
+	}
+	return id
+}
+
+// Valid reports whether id names a Top 10 category or a WSTG test
+func Valid(id string) bool {
+	_, ok := Category(id)
+	return ok
+}
+
+// Category returns the Top 10 category an OWASP ID belongs to: the ID itself
+// for a Top 10 category and the mapped category for a WSTG test
+func Category(id string) (string, bool) {
+	id = Normalize(id)
+	for _, category := range top10 {
+		if category.ID == id {
+			return id, true
+		}
+	}
+	for _, test := range wstg {
+		if test.ID == id {
+			return test.Top10, true
+		}
+	}
+	return "", false
+}
+
+// Phase returns the operation phase tasks for a WSTG test are filed under
+func Phase(test models.WSTGTest) models.OperationPhase {
+	for _, category := range wstgCategories {
+		if category.Code == test.Category {
+			return category.Phase
+		}
+	}
+	return ""
+}
+
+// Coverage summarises tasks by the Top 10 category their OWASP ID falls in.
+// Every category is listed, in order, even when no task covers it.
+func Coverage(tasks []models.Task) []models.OWASPCoverage {
+	coverage := make([]models.OWASPCoverage, len(top10))
+	index := make(map[string]int, len(top10))
+	for i, category := range top10 {
+		coverage[i] = models.OWASPCoverage{Category: category.ID, Name: category.Name}
+		index[category.ID] = i
+	}
+	for _, test := range wstg {
+		coverage[index[test.Top10]].WSTGTests++
+	}
+
+	planned := make(map[string]bool)
+	for _, task := range tasks {
+		category, ok := Category(task.OWASPID)
+		if !ok {
+			continue
+		}
+
+		c := &coverage[index[category]]
+		c.Tasks++
+		switch task.Status {
+		case models.StatusInProgress:
+			c.InProgress++
+		case models.StatusCompleted:
+			c.Completed++
+		case models.StatusBlocked:
+			c.Blocked++
+		default:
+			c.Pending++
+		}
+
+		id := Normalize(task.OWASPID)
+		if strings.HasPrefix(id, "WSTG-") && !planned[id] {
+			planned[id] = true
+			c.WSTGPlanned++
+		}
+	}
+
+	return coverage
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
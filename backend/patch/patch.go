@@ -0,0 +1,132 @@
+// Package patch applies JSON Merge Patch (RFC 7396) documents to models so
+// that a partial update only touches the fields the client sent.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var null = []byte("null")
+
+// FieldError reports a field the patch may not change or whose value does
+// not fit the field
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// Apply merges body into target, which must point to a struct, and returns
+// the changed fields keyed by their bson names ready for a $set. Only fields
+// whose JSON names are listed in allowed may appear in the patch. A null
+// resets a field to its zero value and objects sent for map fields are
+// merged key by key, with null removing a key.
+func Apply(target interface{}, body []byte, allowed []string) (bson.M, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("patch must be a JSON object")
+	}
+
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("patch target must point to a struct")
+	}
+	v = v.Elem()
+
+	set := bson.M{}
+	for name, raw := range fields {
+		if !contains(allowed, name) {
+			return nil, &FieldError{Field: name, Reason: "cannot be changed with a patch"}
+		}
+
+		index, bsonName, ok := lookup(v.Type(), name)
+		if !ok {
+			return nil, &FieldError{Field: name, Reason: "unknown field"}
+		}
+
+		field := v.Field(index)
+		if err := merge(field, raw); err != nil {
+			return nil, &FieldError{Field: name, Reason: err.Error()}
+		}
+		set[bsonName] = field.Interface()
+	}
+
+	return set, nil
+}
+
+// merge writes one patched value into field
+func merge(field reflect.Value, raw json.RawMessage) error {
+	if bytes.Equal(bytes.TrimSpace(raw), null) {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	if field.Kind() == reflect.Map && field.Type().Key().Kind() == reflect.String {
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return err
+		}
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		for key, value := range entries {
+			k := reflect.ValueOf(key).Convert(field.Type().Key())
+			if bytes.Equal(bytes.TrimSpace(value), null) {
+				field.SetMapIndex(k, reflect.Value{})
+				continue
+			}
+			elem := reflect.New(field.Type().Elem())
+			if err := json.Unmarshal(value, elem.Interface()); err != nil {
+				return err
+			}
+			field.SetMapIndex(k, elem.Elem())
+		}
+		return nil
+	}
+
+	value := reflect.New(field.Type())
+	if err := json.Unmarshal(raw, value.Interface()); err != nil {
+		return err
+	}
+	field.Set(value.Elem())
+	return nil
+}
+
+// lookup finds the struct field with the given JSON name and its bson name
+func lookup(t reflect.Type, jsonName string) (int, string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tagName(f.Tag.Get("json")) != jsonName {
+			continue
+		}
+		bsonName := tagName(f.Tag.Get("bson"))
+		if bsonName == "" || bsonName == "-" {
+			return 0, "", false
+		}
+		return i, bsonName, true
+	}
+	return 0, "", false
+}
+
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
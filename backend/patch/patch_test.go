@@ -0,0 +1,157 @@
+package patch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type sample struct {
+	Name     string            `bson:"name" json:"name"`
+	Count    int               `bson:"count" json:"count"`
+	Tags     []string          `bson:"tags" json:"tags"`
+	Labels   map[string]string `bson:"labels" json:"labels"`
+	Secret   string            `bson:"secret" json:"secret"`
+	Computed string            `bson:"-" json:"computed"`
+}
+
+var allowed = []string{"name", "count", "tags", "labels", "computed"}
+
+func fixture() sample {
+	return sample{
+		Name:   "recon",
+		Count:  3,
+		Tags:   []string{"web"},
+		Labels: map[string]string{"env": "prod", "owner": "alice"},
+		Secret: "hunter2",
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    sample
+		wantSet []string
+	}{
+		{
+			name:    "scalar",
+			body:    `{"name":"exploit"}`,
+			want:    func() sample { s := fixture(); s.Name = "exploit"; return s }(),
+			wantSet: []string{"name"},
+		},
+		{
+			name:    "null resets scalar",
+			body:    `{"count":null}`,
+			want:    func() sample { s := fixture(); s.Count = 0; return s }(),
+			wantSet: []string{"count"},
+		},
+		{
+			name:    "array is replaced",
+			body:    `{"tags":["api","internal"]}`,
+			want:    func() sample { s := fixture(); s.Tags = []string{"api", "internal"}; return s }(),
+			wantSet: []string{"tags"},
+		},
+		{
+			name:    "null clears array",
+			body:    `{"tags":null}`,
+			want:    func() sample { s := fixture(); s.Tags = nil; return s }(),
+			wantSet: []string{"tags"},
+		},
+		{
+			name: "map merges keys",
+			body: `{"labels":{"env":"staging","team":"red"}}`,
+			want: func() sample {
+				s := fixture()
+				s.Labels = map[string]string{"env": "staging", "owner": "alice", "team": "red"}
+				return s
+			}(),
+			wantSet: []string{"labels"},
+		},
+		{
+			name:    "null removes map key",
+			body:    `{"labels":{"owner":null}}`,
+			want:    func() sample { s := fixture(); s.Labels = map[string]string{"env": "prod"}; return s }(),
+			wantSet: []string{"labels"},
+		},
+		{
+			name:    "null clears map",
+			body:    `{"labels":null}`,
+			want:    func() sample { s := fixture(); s.Labels = nil; return s }(),
+			wantSet: []string{"labels"},
+		},
+		{
+			name:    "empty patch",
+			body:    `{}`,
+			want:    fixture(),
+			wantSet: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := fixture()
+			set, err := Apply(&target, []byte(tt.body), allowed)
+			if err != nil {
+				t.Fatalf("Apply() = %v", err)
+			}
+			if !reflect.DeepEqual(target, tt.want) {
+				t.Errorf("target = %+v, want %+v", target, tt.want)
+			}
+			if len(set) != len(tt.wantSet) {
+				t.Fatalf("set = %v, want fields %v", set, tt.wantSet)
+			}
+			for _, field := range tt.wantSet {
+				if _, ok := set[field]; !ok {
+					t.Errorf("set is missing %s", field)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyMapIntoNil(t *testing.T) {
+	var target sample
+	set, err := Apply(&target, []byte(`{"labels":{"env":"prod","gone":null}}`), allowed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"env": "prod"}
+	if !reflect.DeepEqual(target.Labels, want) || !reflect.DeepEqual(set["labels"], want) {
+		t.Errorf("Labels = %v, set = %v, want %v", target.Labels, set["labels"], want)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantField string
+	}{
+		{"not an object", `["name"]`, ""},
+		{"null body", `null`, ""},
+		{"invalid json", `{"name":`, ""},
+		{"field not allowed", `{"secret":"x"}`, "secret"},
+		{"field not stored", `{"computed":"x"}`, "computed"},
+		{"wrong type", `{"count":"three"}`, "count"},
+		{"wrong map value", `{"labels":{"env":1}}`, "labels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := fixture()
+			_, err := Apply(&target, []byte(tt.body), allowed)
+			if err == nil {
+				t.Fatal("Apply() = nil, want an error")
+			}
+			var fieldErr *FieldError
+			if tt.wantField == "" {
+				if errors.As(err, &fieldErr) {
+					t.Errorf("Apply() = %v, want a body error", err)
+				}
+				return
+			}
+			if !errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField {
+				t.Errorf("Apply() = %v, want a field error for %s", err, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"redops/database"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrAssetOperationMismatch = errors.New("assets belong to different operations")
+
+// AssetFilter narrows an operation's asset listing
+type AssetFilter struct {
+	Host  string
+	Port  string
+	OS    string
+	Query string
+}
+
+type AssetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAssetRepository() *AssetRepository {
+	return &AssetRepository{
+		collection: database.Assets,
+	}
+}
+
+// Record folds an observation into the inventory, creating the asset on
+// first sight. Hosts that were merged into another asset resolve to it.
+func (r *AssetRepository) Record(operationID primitive.ObjectID, obs models.AssetObservation) error {
+	host := strings.ToLower(strings.TrimSpace(obs.Host))
+	if host == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	seen := obs.SeenAt
+	if seen.IsZero() {
+		seen = now
+	}
+
+	filter := bson.M{"operation_id": operationID, "host": host}
+	var survivor models.Asset
+	err := r.collection.FindOne(ctx, bson.M{"operation_id": operationID, "aliases": host}).Decode(&survivor)
+	if err == nil {
+		filter = bson.M{"_id": survivor.ID}
+	} else if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	set := bson.M{"updated_at": now}
+	if obs.OS != "" {
+		set["os"] = obs.OS
+	}
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"created_at": now},
+		"$min":         bson.M{"first_seen": seen},
+		"$max":         bson.M{"last_seen": seen},
+	}
+
+	addToSet := bson.M{}
+	if !obs.TaskID.IsZero() {
+		addToSet["task_ids"] = obs.TaskID
+	}
+	if hostname := strings.ToLower(strings.TrimSpace(obs.Hostname)); hostname != "" && hostname != host {
+		addToSet["hostnames"] = hostname
+	}
+	if len(addToSet) > 0 {
+		update["$addToSet"] = addToSet
+	}
+
+	_, err = r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent sighting inserted the asset first, so this one
+		// now matches it and updates instead
+		_, err = r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	}
+	if err != nil {
+		return err
+	}
+
+	if obs.Port == "" {
+		return nil
+	}
+
+	// Add the service if this port is new, then refresh its sighting window
+	pushFilter := bson.M{"services.port": bson.M{"$ne": obs.Port}}
+	for k, v := range filter {
+		pushFilter[k] = v
+	}
+	_, err = r.collection.UpdateOne(ctx, pushFilter, bson.M{
+		"$push": bson.M{"services": models.AssetService{
+			Port:      obs.Port,
+			Protocol:  obs.Protocol,
+			Name:      obs.Service,
+			FirstSeen: seen,
+			LastSeen:  seen,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	serviceFilter := bson.M{"services.port": obs.Port}
+	for k, v := range filter {
+		serviceFilter[k] = v
+	}
+	serviceUpdate := bson.M{
+		"$min": bson.M{"services.$.first_seen": seen},
+		"$max": bson.M{"services.$.last_seen": seen},
+	}
+	serviceSet := bson.M{}
+	if obs.Protocol != "" {
+		serviceSet["services.$.protocol"] = obs.Protocol
+	}
+	if obs.Service != "" {
+		serviceSet["services.$.name"] = obs.Service
+	}
+	if len(serviceSet) > 0 {
+		serviceUpdate["$set"] = serviceSet
+	}
+
+	_, err = r.collection.UpdateOne(ctx, serviceFilter, serviceUpdate)
+	return err
+}
+
+func (r *AssetRepository) GetByID(id primitive.ObjectID) (*models.Asset, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var asset models.Asset
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// GetByOperationID lists an operation's assets ordered by host
+func (r *AssetRepository) GetByOperationID(operationID primitive.ObjectID, filter AssetFilter) ([]models.Asset, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := bson.M{"operation_id": operationID}
+	var clauses []bson.M
+
+	if filter.Host != "" {
+		host := strings.ToLower(filter.Host)
+		clauses = append(clauses, bson.M{"$or": []bson.M{
+			{"host": host},
+			{"aliases": host},
+			{"hostnames": host},
+		}})
+	}
+	if filter.Port != "" {
+		query["services.port"] = filter.Port
+	}
+	if filter.OS != "" {
+		query["os"] = primitive.Regex{Pattern: regexp.QuoteMeta(filter.OS), Options: "i"}
+	}
+	if filter.Query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(strings.ToLower(filter.Query))}
+		clauses = append(clauses, bson.M{"$or": []bson.M{
+			{"host": pattern},
+			{"aliases": pattern},
+			{"hostnames": pattern},
+		}})
+	}
+	if len(clauses) > 0 {
+		query["$and"] = clauses
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "host", Value: 1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	assets := []models.Asset{}
+	if err = cursor.All(ctx, &assets); err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+// Merge folds the source assets into the target and removes them. The
+// sources' hosts become aliases of the target so later sightings land there.
+// It runs in a single transaction, so a failure leaves every asset as it was.
+func (r *AssetRepository) Merge(targetID primitive.ObjectID, sourceIDs []primitive.ObjectID) (*models.Asset, error) {
+	var merged *models.Asset
+	err := database.WithTransaction(func(ctx mongo.SessionContext) error {
+		target, err := r.merge(ctx, targetID, sourceIDs)
+		merged = target
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+func (r *AssetRepository) merge(ctx mongo.SessionContext, targetID primitive.ObjectID, sourceIDs []primitive.ObjectID) (*models.Asset, error) {
+	target := &models.Asset{}
+	if err := r.collection.FindOne(ctx, bson.M{"_id": targetID}).Decode(target); err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": sourceIDs, "$ne": targetID}})
+	if err != nil {
+		return nil, err
+	}
+	var sources []models.Asset
+	if err = cursor.All(ctx, &sources); err != nil {
+		return nil, err
+	}
+	if len(sources) != len(sourceIDs) {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	aliases := stringSet(target.Aliases)
+	hostnames := stringSet(target.Hostnames)
+	taskIDs := make(map[primitive.ObjectID]bool)
+	for _, id := range target.TaskIDs {
+		taskIDs[id] = true
+	}
+	services := make(map[string]models.AssetService)
+	for _, service := range target.Services {
+		services[service.Port] = service
+	}
+
+	for _, source := range sources {
+		if source.OperationID != target.OperationID {
+			return nil, ErrAssetOperationMismatch
+		}
+
+		aliases[source.Host] = true
+		for _, alias := range source.Aliases {
+			aliases[alias] = true
+		}
+		for _, hostname := range source.Hostnames {
+			hostnames[hostname] = true
+		}
+		for _, id := range source.TaskIDs {
+			taskIDs[id] = true
+		}
+		if target.OS == "" {
+			target.OS = source.OS
+		}
+		if source.FirstSeen.Before(target.FirstSeen) {
+			target.FirstSeen = source.FirstSeen
+		}
+		if source.LastSeen.After(target.LastSeen) {
+			target.LastSeen = source.LastSeen
+		}
+
+		for _, service := range source.Services {
+			existing, ok := services[service.Port]
+			if !ok {
+				services[service.Port] = service
+				continue
+			}
+			if service.FirstSeen.Before(existing.FirstSeen) {
+				existing.FirstSeen = service.FirstSeen
+			}
+			if service.LastSeen.After(existing.LastSeen) {
+				existing.LastSeen = service.LastSeen
+			}
+			if existing.Name == "" {
+				existing.Name = service.Name
+			}
+			if existing.Protocol == "" {
+				existing.Protocol = service.Protocol
+			}
+			services[service.Port] = existing
+		}
+	}
+
+	delete(aliases, target.Host)
+	target.Aliases = sortedKeys(aliases)
+	target.Hostnames = sortedKeys(hostnames)
+	target.TaskIDs = target.TaskIDs[:0]
+	for id := range taskIDs {
+		target.TaskIDs = append(target.TaskIDs, id)
+	}
+	target.Services = target.Services[:0]
+	for _, service := range services {
+		target.Services = append(target.Services, service)
+	}
+	sort.Slice(target.Services, func(i, j int) bool {
+		return target.Services[i].Port < target.Services[j].Port
+	})
+	target.UpdatedAt = time.Now()
+
+	if _, err := r.collection.ReplaceOne(ctx, bson.M{"_id": target.ID}, target); err != nil {
+		return nil, err
+	}
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": sourceIDs, "$ne": targetID}}); err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"redops/database"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttackRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAttackRepository() *AttackRepository {
+	return &AttackRepository{
+		collection: database.Techniques,
+	}
+}
+
+// Import stores the techniques of a catalogue, replacing the stored copy of
+// each technique it contains. Techniques missing from the catalogue are kept
+// so tasks that reference them still resolve.
+func (r *AttackRepository) Import(techniques []models.AttackTechnique) error {
+	if len(techniques) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, len(techniques))
+	for i := range techniques {
+		techniques[i].ImportedAt = now
+		writes[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"technique_id": techniques[i].TechniqueID}).
+			SetReplacement(techniques[i]).
+			SetUpsert(true)
+	}
+
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// Count returns how many techniques the catalogue holds
+func (r *AttackRepository) Count() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{})
+}
+
+func (r *AttackRepository) GetByTechniqueID(techniqueID string) (*models.AttackTechnique, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var technique models.AttackTechnique
+	err := r.collection.FindOne(ctx, bson.M{"technique_id": techniqueID}).Decode(&technique)
+	if err != nil {
+		return nil, err
+	}
+
+	return &technique, nil
+}
+
+// GetByTechniqueIDs returns the stored techniques among the given IDs
+func (r *AttackRepository) GetByTechniqueIDs(techniqueIDs []string) ([]models.AttackTechnique, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"technique_id": bson.M{"$in": techniqueIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	techniques := []models.AttackTechnique{}
+	if err = cursor.All(ctx, &techniques); err != nil {
+		return nil, err
+	}
+
+	return techniques, nil
+}
+
+// Subtechniques returns the sub-techniques of a technique in ID order
+func (r *AttackRepository) Subtechniques(parentID string) ([]models.AttackTechnique, error) {
+	return r.find(bson.M{"parent_id": parentID}, 0)
+}
+
+// Search finds techniques whose ID starts with query or whose name contains
+// it, optionally limited to one tactic. Deprecated techniques are left out
+// unless asked for.
+func (r *AttackRepository) Search(query, tactic string, includeDeprecated bool, limit int64) ([]models.AttackTechnique, error) {
+	filter := bson.M{}
+	if query != "" {
+		quoted := regexp.QuoteMeta(query)
+		filter["$or"] = bson.A{
+			bson.M{"technique_id": primitive.Regex{Pattern: "^" + quoted, Options: "i"}},
+			bson.M{"name": primitive.Regex{Pattern: quoted, Options: "i"}},
+		}
+	}
+	if tactic != "" {
+		filter["tactics"] = tactic
+	}
+	if !includeDeprecated {
+		filter["deprecated"] = bson.M{"$ne": true}
+	}
+
+	return r.find(filter, limit)
+}
+
+func (r *AttackRepository) find(filter bson.M, limit int64) ([]models.AttackTechnique, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "technique_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	techniques := []models.AttackTechnique{}
+	if err = cursor.All(ctx, &techniques); err != nil {
+		return nil, err
+	}
+
+	return techniques, nil
+}
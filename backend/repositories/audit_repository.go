@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"redops/database"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{
+		collection: database.AuditLog,
+	}
+}
+
+func (r *AuditRepository) Create(entry *models.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	result, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetByOperationID returns the audit trail of an operation in chronological
+// order, optionally restricted to the given actions.
+func (r *AuditRepository) GetByOperationID(operationID primitive.ObjectID, actions ...models.AuditAction) ([]models.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"operation_id": operationID}
+	if len(actions) > 0 {
+		filter["action"] = bson.M{"$in": actions}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.AuditEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"redops/database"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CommentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCommentRepository() *CommentRepository {
+	return &CommentRepository{
+		collection: database.Comments,
+	}
+}
+
+func (r *CommentRepository) Create(comment *models.Comment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = comment.CreatedAt
+
+	result, err := r.collection.InsertOne(ctx, comment)
+	if err != nil {
+		return err
+	}
+
+	comment.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *CommentRepository) GetByID(id primitive.ObjectID) (*models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var comment models.Comment
+	if err := r.collection.FindOne(ctx, live(bson.M{"_id": id})).Decode(&comment); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// GetByTaskID returns the discussion of a task, oldest comment first
+func (r *CommentRepository) GetByTaskID(taskID primitive.ObjectID) ([]models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, live(bson.M{"task_id": taskID}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []models.Comment{}
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// Edit replaces the body of a comment and keeps the previous one in its edit
+// history. It returns mongo.ErrNoDocuments when the comment does not exist.
+func (r *CommentRepository) Edit(comment *models.Comment, body string, mentions []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	edit := models.CommentEdit{Body: comment.Body, EditedAt: now}
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		live(bson.M{"_id": comment.ID}),
+		bson.M{
+			"$set": bson.M{
+				"body":       body,
+				"mentions":   mentions,
+				"updated_at": now,
+			},
+			"$push": bson.M{"edits": edit},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	comment.Body = body
+	comment.Mentions = mentions
+	comment.Edits = append(comment.Edits, edit)
+	comment.UpdatedAt = now
+	return nil
+}
+
+// Delete removes a comment. Replies to it stay in the discussion.
+func (r *CommentRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, live(bson.M{"_id": id}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"redops/database"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// evidenceBucket is the GridFS bucket holding the content of evidence files
+const evidenceBucket = "evidence_files"
+
+var ErrEvidenceTooLarge = errors.New("evidence file is too large")
+
+type EvidenceRepository struct {
+	collection *mongo.Collection
+	bucket     *gridfs.Bucket
+}
+
+func NewEvidenceRepository() *EvidenceRepository {
+	bucket, err := gridfs.NewBucket(database.Database, options.GridFSBucket().SetName(evidenceBucket))
+	if err != nil {
+		// Only invalid bucket options make this fail
+		panic(err)
+	}
+
+	return &EvidenceRepository{
+		collection: database.Evidence,
+		bucket:     bucket,
+	}
+}
+
+// Upload stores the content read from src in GridFS, hashing it on the way,
+// and saves the evidence record with the upload as the first entry of its
+// chain of custody. Content longer than maxSize is discarded and
+// ErrEvidenceTooLarge returned.
+func (r *EvidenceRepository) Upload(evidence *models.Evidence, src io.Reader, maxSize int64, uploader models.CustodyEvent) error {
+	stream, err := r.bucket.OpenUploadStream(evidence.Filename, options.GridFSUpload().SetMetadata(bson.M{
+		"task_id":      evidence.TaskID,
+		"content_type": evidence.ContentType,
+	}))
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(stream, hash), io.LimitReader(src, maxSize+1))
+	if err == nil && size > maxSize {
+		err = ErrEvidenceTooLarge
+	}
+	if err != nil {
+		stream.Abort()
+		return err
+	}
+	if err := stream.Close(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	uploader.Action = models.CustodyUploaded
+	uploader.At = now
+
+	evidence.FileID = stream.FileID.(primitive.ObjectID)
+	evidence.Size = size
+	evidence.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	evidence.UploadedBy = uploader.Username
+	evidence.Custody = []models.CustodyEvent{uploader}
+	evidence.CreatedAt = now
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.InsertOne(ctx, evidence)
+	if err != nil {
+		r.bucket.DeleteContext(ctx, evidence.FileID)
+		return err
+	}
+
+	evidence.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *EvidenceRepository) GetByID(id primitive.ObjectID) (*models.Evidence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var evidence models.Evidence
+	if err := r.collection.FindOne(ctx, live(bson.M{"_id": id})).Decode(&evidence); err != nil {
+		return nil, err
+	}
+
+	return &evidence, nil
+}
+
+// GetByTaskID returns the evidence of a task, including that attached to its
+// results that are not in the trash, oldest first
+func (r *EvidenceRepository) GetByTaskID(taskID primitive.ObjectID) ([]models.Evidence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resultIDs, err := database.Results.Distinct(ctx, "_id", liveResults(bson.M{"taskId": taskID}))
+	if err != nil {
+		return nil, err
+	}
+
+	return r.find(live(bson.M{
+		"task_id": taskID,
+		"$or": bson.A{
+			bson.M{"result_id": bson.M{"$exists": false}},
+			bson.M{"result_id": bson.M{"$in": resultIDs}},
+		},
+	}))
+}
+
+// GetByResultID returns the evidence attached to a result, oldest first
+func (r *EvidenceRepository) GetByResultID(resultID primitive.ObjectID) ([]models.Evidence, error) {
+	return r.find(live(bson.M{"result_id": resultID}))
+}
+
+func (r *EvidenceRepository) find(filter bson.M) ([]models.Evidence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	evidence := []models.Evidence{}
+	if err = cursor.All(ctx, &evidence); err != nil {
+		return nil, err
+	}
+
+	return evidence, nil
+}
+
+// Open returns a stream over the stored content of an evidence file. The
+// caller must close it.
+func (r *EvidenceRepository) Open(evidence *models.Evidence) (*gridfs.DownloadStream, error) {
+	return r.bucket.OpenDownloadStream(evidence.FileID)
+}
+
+// Verify hashes the stored content again and reports whether it still
+// matches the hash taken at upload
+func (r *EvidenceRepository) Verify(evidence *models.Evidence) (bool, error) {
+	stream, err := r.Open(evidence)
+	if err != nil {
+		return false, err
+	}
+	defer stream.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, stream); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)) == evidence.SHA256, nil
+}
+
+// RecordCustody appends an event to the chain of custody of an evidence file
+func (r *EvidenceRepository) RecordCustody(id primitive.ObjectID, event models.CustodyEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"custody": event}})
+	return err
+}
+
+// Delete moves an evidence file to the trash and records who did it. The
+// content is kept until the file is purged.
+func (r *EvidenceRepository) Delete(id primitive.ObjectID, event models.CustodyEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	event.Action = models.CustodyDeleted
+	event.At = now
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		live(bson.M{"_id": id}),
+		bson.M{
+			"$set":  bson.M{deletedAtField: now},
+			"$push": bson.M{"custody": event},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Restore takes an evidence file out of the trash. It fails with
+// ErrParentDeleted while the file's task or result is in the trash.
+func (r *EvidenceRepository) Restore(id primitive.ObjectID, event models.CustodyEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var evidence models.Evidence
+	if err := r.collection.FindOne(ctx, trashed(bson.M{"_id": id})).Decode(&evidence); err != nil {
+		return err
+	}
+
+	parents, err := database.Tasks.CountDocuments(ctx, live(bson.M{"_id": evidence.TaskID}))
+	if err != nil {
+		return err
+	}
+	if parents == 0 {
+		return ErrParentDeleted
+	}
+	if !evidence.ResultID.IsZero() {
+		parents, err := database.Results.CountDocuments(ctx, liveResults(bson.M{"_id": evidence.ResultID}))
+		if err != nil {
+			return err
+		}
+		if parents == 0 {
+			return ErrParentDeleted
+		}
+	}
+
+	event.Action = models.CustodyRestored
+	event.At = time.Now()
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$unset": bson.M{deletedAtField: ""},
+		"$push":  bson.M{"custody": event},
+	})
+	return err
+}
+
+// ListDeleted returns the evidence files in the trash, most recently deleted
+// first
+func (r *EvidenceRepository) ListDeleted() ([]models.Evidence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, trashed(bson.M{}), trashOptions(deletedAtField))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	evidence := []models.Evidence{}
+	if err = cursor.All(ctx, &evidence); err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// Purge permanently removes the evidence files that went into the trash
+// before cutoff, together with those whose task or result has been purged,
+// and their content
+func (r *EvidenceRepository) Purge(cutoff time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	expired, err := r.collection.Find(ctx, bson.M{deletedAtField: bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	var files []models.Evidence
+	if err := expired.All(ctx, &files); err != nil {
+		return 0, err
+	}
+
+	orphans, err := r.orphans(ctx)
+	if err != nil {
+		return 0, err
+	}
+	files = append(files, orphans...)
+
+	var purged int64
+	for _, evidence := range files {
+		if err := r.bucket.DeleteContext(ctx, evidence.FileID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return purged, err
+		}
+		result, err := r.collection.DeleteOne(ctx, bson.M{"_id": evidence.ID})
+		if err != nil {
+			return purged, err
+		}
+		purged += result.DeletedCount
+	}
+	return purged, nil
+}
+
+// removeEvidence permanently deletes the evidence records matching filter
+// together with their content, removing the GridFS files and chunks directly
+// so it can run inside the caller's transaction
+func removeEvidence(ctx mongo.SessionContext, filter bson.M) error {
+	fileIDs, err := database.Evidence.Distinct(ctx, "file_id", filter)
+	if err != nil {
+		return err
+	}
+	if len(fileIDs) > 0 {
+		if _, err := database.Database.Collection(evidenceBucket+".chunks").DeleteMany(ctx, bson.M{"files_id": bson.M{"$in": fileIDs}}); err != nil {
+			return err
+		}
+		if _, err := database.Database.Collection(evidenceBucket+".files").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": fileIDs}}); err != nil {
+			return err
+		}
+	}
+	_, err = database.Evidence.DeleteMany(ctx, filter)
+	return err
+}
+
+// orphans finds the evidence files whose task or result no longer exists at
+// all, not even in the trash
+func (r *EvidenceRepository) orphans(ctx context.Context) ([]models.Evidence, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{"from": database.Tasks.Name(), "localField": "task_id", "foreignField": "_id", "as": "task"}}},
+		{{Key: "$lookup", Value: bson.M{"from": database.Results.Name(), "localField": "result_id", "foreignField": "_id", "as": "result"}}},
+		{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"task": bson.M{"$size": 0}},
+			bson.M{"result_id": bson.M{"$exists": true}, "result": bson.M{"$size": 0}},
+		}}}},
+		{{Key: "$project", Value: bson.M{"task": 0, "result": 0}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orphans []models.Evidence
+	if err := cursor.All(ctx, &orphans); err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
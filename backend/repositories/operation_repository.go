@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type OperationRepository struct {
@@ -28,6 +29,8 @@ func (r *OperationRepository) Create(operation *models.Operation) error {
 
 	operation.CreatedAt = time.Now()
 	operation.UpdatedAt = time.Now()
+	operation.DeletedAt = time.Time{}
+	operation.Version = 1
 
 	result, err := r.collection.InsertOne(ctx, operation)
 	if err != nil {
@@ -43,7 +46,7 @@ func (r *OperationRepository) GetByID(id primitive.ObjectID) (*models.Operation,
 	defer cancel()
 
 	var operation models.Operation
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&operation)
+	err := r.collection.FindOne(ctx, live(bson.M{"_id": id})).Decode(&operation)
 	if err != nil {
 		return nil, err
 	}
@@ -51,33 +54,232 @@ func (r *OperationRepository) GetByID(id primitive.ObjectID) (*models.Operation,
 	return &operation, nil
 }
 
-func (r *OperationRepository) Update(operation *models.Operation) error {
+// Update replaces the stored operation. It fails with ErrVersionConflict unless
+// the stored version still equals operation.Version, which is bumped on success.
+// A non-nil change is added to the phase history in the same write.
+func (r *OperationRepository) Update(operation *models.Operation, change *models.PhaseChange) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	expected := operation.Version
 	operation.UpdatedAt = time.Now()
+	operation.DeletedAt = time.Time{}
+	operation.Version = expected + 1
 
-	_, err := r.collection.UpdateOne(
+	update := bson.M{"$set": operation}
+	if change != nil {
+		update["$push"] = bson.M{"phase_history": change}
+	}
+	result, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": operation.ID},
-		bson.M{"$set": operation},
+		versioned(live(bson.M{"_id": operation.ID}), expected),
+		update,
 	)
-	return err
+	if err == nil && result.MatchedCount == 0 {
+		err = missOrConflict(ctx, r.collection, operation.ID)
+	}
+	if err != nil {
+		operation.Version = expected
+		return err
+	}
+	return nil
 }
 
+// Patch sets only the given fields, leaving the rest of the operation untouched,
+// and returns the operation's new version. A non-zero version must match the
+// stored one or the patch fails with ErrVersionConflict. It returns
+// mongo.ErrNoDocuments when the operation does not exist.
+func (r *OperationRepository) Patch(id primitive.ObjectID, set bson.M, version int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := bson.M{}
+	for k, v := range set {
+		fields[k] = v
+	}
+	fields["updated_at"] = time.Now()
+
+	var updated struct {
+		Version int64 `bson:"version"`
+	}
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		versioned(live(bson.M{"_id": id}), version),
+		bumpVersion(bson.M{"$set": fields}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{versionField: 1}),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return 0, missOrConflict(ctx, r.collection, id)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return updated.Version, nil
+}
+
+// Delete moves an operation to the trash together with its live tasks and
+// their results, evidence, comments and time entries in a single
+// transaction. Everything is stamped with the same
+// time so Restore brings back exactly what went out together. It refuses
+// with ErrActiveExecutions while tools are still queued or running and
+// returns mongo.ErrNoDocuments when the operation does not exist or is
+// already in the trash.
 func (r *OperationRepository) Delete(id primitive.ObjectID) error {
+	return database.WithTransaction(func(ctx mongo.SessionContext) error {
+		active, err := database.Executions.CountDocuments(ctx, bson.M{
+			"operation_id": id,
+			"status":       bson.M{"$in": bson.A{models.ExecutionQueued, models.ExecutionRunning}},
+		})
+		if err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrActiveExecutions
+		}
+
+		now := time.Now()
+		result, err := r.collection.UpdateOne(ctx, live(bson.M{"_id": id}), bson.M{"$set": bson.M{deletedAtField: now}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		taskIDs, err := database.Tasks.Distinct(ctx, "_id", live(bson.M{"operation_id": id}))
+		if err != nil || len(taskIDs) == 0 {
+			return err
+		}
+		if _, err := database.Tasks.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": taskIDs}}, bson.M{"$set": bson.M{deletedAtField: now}}); err != nil {
+			return err
+		}
+		if _, err := database.Results.UpdateMany(ctx, liveResults(bson.M{"taskId": bson.M{"$in": taskIDs}}), trashResults(now)); err != nil {
+			return err
+		}
+		return trashTaskContent(ctx, taskIDs, now)
+	})
+}
+
+// Restore takes an operation out of the trash together with the tasks,
+// results, evidence, comments and time entries that were deleted with it. Tasks deleted on their own beforehand
+// stay in the trash.
+func (r *OperationRepository) Restore(id primitive.ObjectID) error {
+	return database.WithTransaction(func(ctx mongo.SessionContext) error {
+		var operation models.Operation
+		if err := r.collection.FindOne(ctx, trashed(bson.M{"_id": id})).Decode(&operation); err != nil {
+			return err
+		}
+
+		taskIDs, err := database.Tasks.Distinct(ctx, "_id", bson.M{"operation_id": id, deletedAtField: operation.DeletedAt})
+		if err != nil {
+			return err
+		}
+		if len(taskIDs) > 0 {
+			_, err = database.Results.UpdateMany(ctx, bson.M{"taskId": bson.M{"$in": taskIDs}, resultDeletedAtField: operation.DeletedAt}, restoreResults())
+			if mongo.IsDuplicateKeyError(err) {
+				return ErrDuplicateResult
+			}
+			if err != nil {
+				return err
+			}
+			if err := restoreTaskContent(ctx, taskIDs, operation.DeletedAt); err != nil {
+				return err
+			}
+			if _, err := database.Tasks.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": taskIDs}}, bson.M{"$unset": bson.M{deletedAtField: ""}}); err != nil {
+				return err
+			}
+		}
+
+		_, err = r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{deletedAtField: ""}})
+		return err
+	})
+}
+
+// ListDeleted returns the operations in the trash, most recently deleted first
+func (r *OperationRepository) ListDeleted() ([]models.Operation, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	cursor, err := r.collection.Find(ctx, trashed(bson.M{}), trashOptions(deletedAtField))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	operations := []models.Operation{}
+	if err = cursor.All(ctx, &operations); err != nil {
+		return nil, err
+	}
+
+	return operations, nil
+}
+
+// Purge permanently removes operations that were moved to the trash before
+// cutoff, together with everything that belongs to them
+func (r *OperationRepository) Purge(cutoff time.Time) (int64, error) {
+	var purged int64
+	err := database.WithTransaction(func(ctx mongo.SessionContext) error {
+		ids, err := r.collection.Distinct(ctx, "_id", bson.M{deletedAtField: bson.M{"$lt": cutoff}})
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := removeOperation(ctx, id.(primitive.ObjectID)); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(ids))
+		return nil
+	})
+	return purged, err
+}
+
+// Remove permanently deletes an operation and everything that belongs to it
+// without going through the trash. It is meant for undoing a create that
+// failed half way.
+func (r *OperationRepository) Remove(id primitive.ObjectID) error {
+	return database.WithTransaction(func(ctx mongo.SessionContext) error {
+		return removeOperation(ctx, id)
+	})
+}
+
+// removeOperation deletes an operation with its tasks, results, evidence and
+// its stored content, executions, assets, comments and time entries
+func removeOperation(ctx mongo.SessionContext, id primitive.ObjectID) error {
+	taskIDs, err := database.Tasks.Distinct(ctx, "_id", bson.M{"operation_id": id})
+	if err != nil {
+		return err
+	}
+	if len(taskIDs) > 0 {
+		if err := removeEvidence(ctx, bson.M{"task_id": bson.M{"$in": taskIDs}}); err != nil {
+			return err
+		}
+		if _, err := database.Results.DeleteMany(ctx, bson.M{"taskId": bson.M{"$in": taskIDs}}); err != nil {
+			return err
+		}
+	}
+
+	for _, collection := range []*mongo.Collection{database.Executions, database.Assets, database.Comments, database.TimeLog, database.Tasks} {
+		if _, err := collection.DeleteMany(ctx, bson.M{"operation_id": id}); err != nil {
+			return err
+		}
+	}
+
+	_, err = database.Operations.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
 
-func (r *OperationRepository) List() ([]models.Operation, error) {
+// List returns the operations, leaving out archived ones unless asked for
+func (r *OperationRepository) List(includeArchived bool) ([]models.Operation, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	filter := live(bson.M{})
+	if !includeArchived {
+		filter["status"] = bson.M{"$ne": models.OperationStatusArchived}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -95,12 +297,13 @@ func (r *OperationRepository) GetByTeamMember(userID primitive.ObjectID) ([]mode
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{
+	filter := live(bson.M{
 		"$or": []bson.M{
 			{"team_lead": userID},
 			{"members": userID},
 		},
-	}
+		"status": bson.M{"$ne": models.OperationStatusArchived},
+	})
 
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
@@ -116,19 +319,118 @@ func (r *OperationRepository) GetByTeamMember(userID primitive.ObjectID) ([]mode
 	return operations, nil
 }
 
-func (r *OperationRepository) UpdatePhase(id primitive.ObjectID, phase models.OperationPhase) error {
+func (r *OperationRepository) UpdateROERules(id primitive.ObjectID, rules *models.ROERules) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	_, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": id},
-		bson.M{
+		live(bson.M{"_id": id}),
+		bumpVersion(bson.M{
 			"$set": bson.M{
-				"current_phase": phase,
+				"roe_rules":  rules,
+				"updated_at": time.Now(),
+			},
+		}),
+	)
+	return err
+}
+
+// TransitionStatus moves an operation from one lifecycle status to another
+// and appends the change to its history. Extra fields in set are written in
+// the same update. It reports false when the operation's status is no longer
+// from, for example because someone else changed it first.
+func (r *OperationRepository) TransitionStatus(id primitive.ObjectID, from models.OperationStatus, change models.StatusChange, set bson.M) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := bson.M{}
+	for k, v := range set {
+		fields[k] = v
+	}
+	fields["status"] = change.To
+	fields["updated_at"] = time.Now()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		live(bson.M{"_id": id, "status": statusFilter(string(from))}),
+		bumpVersion(bson.M{
+			"$set":  fields,
+			"$push": bson.M{"status_history": change},
+		}),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// Halt engages the kill switch on an operation. It reports false when the
+// operation's status is no longer from.
+func (r *OperationRepository) Halt(id primitive.ObjectID, from models.OperationStatus, halt *models.OperationHalt, change models.StatusChange) (bool, error) {
+	return r.TransitionStatus(id, from, change, bson.M{"halt": halt})
+}
+
+// Resume releases the kill switch and restores the status the operation had
+// when it was halted. It reports false when the operation was not halted.
+func (r *OperationRepository) Resume(id primitive.ObjectID, change models.StatusChange) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		live(bson.M{"_id": id, "status": models.OperationStatusHalted}),
+		bumpVersion(bson.M{
+			"$set": bson.M{
+				"status":     change.To,
+				"updated_at": time.Now(),
+			},
+			"$unset": bson.M{"halt": ""},
+			"$push":  bson.M{"status_history": change},
+		}),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// statusFilter matches a stored status. Operations and tasks created before
+// statuses were tracked may have none at all.
+func statusFilter(status string) interface{} {
+	if status == "" {
+		return bson.M{"$in": bson.A{"", nil}}
+	}
+	return status
+}
+
+// UpdatePhase moves the operation to another phase and appends the change to
+// its phase history in one write. It fails with ErrVersionConflict when the
+// operation has left change.From in the meantime.
+func (r *OperationRepository) UpdatePhase(id primitive.ObjectID, change models.PhaseChange) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Operations stored before phases were tracked may have no phase at all
+	var from interface{} = change.From
+	if change.From == "" {
+		from = bson.M{"$in": bson.A{"", nil}}
+	}
+	result, err := r.collection.UpdateOne(
+		ctx,
+		live(bson.M{"_id": id, "current_phase": from}),
+		bumpVersion(bson.M{
+			"$set": bson.M{
+				"current_phase": change.To,
 				"updated_at":    time.Now(),
 			},
-		},
+			"$push": bson.M{"phase_history": change},
+		}),
 	)
+	if err == nil && result.MatchedCount == 0 {
+		err = missOrConflict(ctx, r.collection, id)
+	}
 	return err
 }
@@ -2,16 +2,61 @@ package repositories
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"redops/database"
 	"redops/models"
 )
 
+const (
+	defaultResultPageSize = 50
+	maxResultPageSize     = 500
+)
+
+var (
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInvalidSortField  = errors.New("invalid sort field")
+	ErrDuplicateResult   = errors.New("an identical result already exists")
+	ErrInvalidImportMode = errors.New("invalid import mode")
+)
+
+// resultSortFields maps the sort keys accepted by List to their document fields
+var resultSortFields = map[string]string{
+	"startTime":       "startTime",
+	"createdAt":       "createdAt",
+	"updatedAt":       "updatedAt",
+	"sourceIP":        "sourceIP",
+	"destinationIP":   "destinationIP",
+	"destinationPort": "destinationPort",
+	"toolApp":         "toolApp",
+	"operatorName":    "operatorName",
+}
+
+// ResultFilter describes which results List returns and in what order
+type ResultFilter struct {
+	TaskID          primitive.ObjectID
+	SourceIP        string
+	DestinationIP   string
+	DestinationPort string
+	ToolApp         string
+	OperatorName    string
+	OutOfScope      *bool
+	From            time.Time
+	To              time.Time
+	SortBy          string
+	SortDesc        bool
+	Cursor          string
+	Limit           int64
+}
+
 type ResultRepository struct {
 	collection *mongo.Collection
 }
@@ -26,14 +71,129 @@ func NewResultRepository() *ResultRepository {
 func (r *ResultRepository) Create(result *models.Result) error {
 	result.CreatedAt = time.Now()
 	result.UpdatedAt = time.Now()
+	result.Fingerprint = result.ComputeFingerprint()
+	result.TrashedFingerprint = ""
+	result.DeletedAt = time.Time{}
+
+	res, err := r.collection.InsertOne(context.Background(), result)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrDuplicateResult
+		}
+		return err
+	}
 
-	_, err := r.collection.InsertOne(context.Background(), result)
+	result.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetByID retrieves a single result
+func (r *ResultRepository) GetByID(id primitive.ObjectID) (*models.Result, error) {
+	var result models.Result
+	err := r.collection.FindOne(context.Background(), liveResults(bson.M{"_id": id})).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Update replaces the editable fields of a single result
+func (r *ResultRepository) Update(result *models.Result) error {
+	existing, err := r.GetByID(result.ID)
+	if err != nil {
+		return err
+	}
+
+	result.TaskID = existing.TaskID
+	result.CreatedAt = existing.CreatedAt
+	result.UpdatedAt = time.Now()
+	result.Fingerprint = result.ComputeFingerprint()
+	result.TrashedFingerprint = ""
+	result.DeletedAt = time.Time{}
+
+	_, err = r.collection.ReplaceOne(context.Background(), liveResults(bson.M{"_id": result.ID}), result)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateResult
+	}
+	return err
+}
+
+// Delete moves a single result to the trash
+func (r *ResultRepository) Delete(id primitive.ObjectID) error {
+	res, err := r.collection.UpdateOne(context.Background(), liveResults(bson.M{"_id": id}), trashResults(time.Now()))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Restore takes a result out of the trash. It fails with ErrParentDeleted
+// while the result's task is in the trash and with ErrDuplicateResult when an
+// identical result has been recorded since.
+func (r *ResultRepository) Restore(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var result models.Result
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, resultDeletedAtField: bson.M{"$ne": nil}}).Decode(&result)
+	if err != nil {
+		return err
+	}
+
+	parents, err := database.Tasks.CountDocuments(ctx, live(bson.M{"_id": result.TaskID}))
+	if err != nil {
+		return err
+	}
+	if parents == 0 {
+		return ErrParentDeleted
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": id}, restoreResults())
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateResult
+	}
 	return err
 }
 
+// ListDeleted returns the results in the trash, most recently deleted first
+func (r *ResultRepository) ListDeleted() ([]models.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{resultDeletedAtField: bson.M{"$ne": nil}}, trashOptions(resultDeletedAtField))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []models.Result{}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Purge permanently removes results that were moved to the trash before cutoff
+func (r *ResultRepository) Purge(cutoff time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := r.collection.DeleteMany(ctx, bson.M{resultDeletedAtField: bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
 // GetByTaskID retrieves all results for a specific task
 func (r *ResultRepository) GetByTaskID(taskID primitive.ObjectID) ([]models.Result, error) {
-	cursor, err := r.collection.Find(context.Background(), bson.M{"taskId": taskID})
+	cursor, err := r.collection.Find(context.Background(), liveResults(bson.M{"taskId": taskID}))
 	if err != nil {
 		return nil, err
 	}
@@ -47,9 +207,142 @@ func (r *ResultRepository) GetByTaskID(taskID primitive.ObjectID) ([]models.Resu
 	return results, nil
 }
 
-// DeleteByTaskID deletes all results for a specific task
+// GetByTaskIDs retrieves the results of several tasks ordered by start time
+func (r *ResultRepository) GetByTaskIDs(taskIDs []primitive.ObjectID) ([]models.Result, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "startTime", Value: 1}, {Key: "createdAt", Value: 1}})
+	cursor, err := r.collection.Find(context.Background(), liveResults(bson.M{"taskId": bson.M{"$in": taskIDs}}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var results []models.Result
+	if err = cursor.All(context.Background(), &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// List returns one page of results matching the filter. Pages are keyed on
+// the sort field and _id, so NextCursor stays stable while results are added.
+func (r *ResultRepository) List(filter ResultFilter) (*models.ResultPage, error) {
+	query, sort, err := resultListQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	sortField := sort[0].Key
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultResultPageSize
+	}
+	if limit > maxResultPageSize {
+		limit = maxResultPageSize
+	}
+
+	opts := options.Find().
+		SetSort(sort).
+		SetLimit(limit + 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []models.Result{}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	page := &models.ResultPage{Items: results}
+	if int64(len(results)) > limit {
+		page.Items = results[:limit]
+		last := page.Items[len(page.Items)-1]
+		next, err := encodeResultCursor(&last, sortField)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next
+	}
+
+	return page, nil
+}
+
+// resultListQuery builds the query and sort order List runs for a filter,
+// continuing after the filter's cursor when it has one
+func resultListQuery(filter ResultFilter) (bson.M, bson.D, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = "createdAt"
+	}
+	sortField, ok := resultSortFields[sortBy]
+	if !ok {
+		return nil, nil, ErrInvalidSortField
+	}
+
+	direction := 1
+	if filter.SortDesc {
+		direction = -1
+	}
+
+	query := liveResults(bson.M{})
+	if !filter.TaskID.IsZero() {
+		query["taskId"] = filter.TaskID
+	}
+	if filter.SourceIP != "" {
+		query["sourceIP"] = filter.SourceIP
+	}
+	if filter.DestinationIP != "" {
+		query["destinationIP"] = filter.DestinationIP
+	}
+	if filter.DestinationPort != "" {
+		query["destinationPort"] = filter.DestinationPort
+	}
+	if filter.ToolApp != "" {
+		query["toolApp"] = filter.ToolApp
+	}
+	if filter.OperatorName != "" {
+		query["operatorName"] = filter.OperatorName
+	}
+	if filter.OutOfScope != nil {
+		if *filter.OutOfScope {
+			query["outOfScope"] = true
+		} else {
+			query["outOfScope"] = bson.M{"$ne": true}
+		}
+	}
+
+	window := bson.M{}
+	if !filter.From.IsZero() {
+		window["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		window["$lte"] = filter.To
+	}
+	if len(window) > 0 {
+		query["startTime"] = window
+	}
+
+	if filter.Cursor != "" {
+		value, lastID, err := decodeResultCursor(filter.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = bson.M{"$and": []bson.M{query, keysetFilter(sortField, value, lastID, filter.SortDesc)}}
+	}
+
+	sort := bson.D{{Key: sortField, Value: direction}, {Key: "_id", Value: direction}}
+	return query, sort, nil
+}
+
+// DeleteByTaskID moves all results for a specific task to the trash
 func (r *ResultRepository) DeleteByTaskID(taskID primitive.ObjectID) error {
-	_, err := r.collection.DeleteMany(context.Background(), bson.M{"taskId": taskID})
+	_, err := r.collection.UpdateMany(context.Background(), liveResults(bson.M{"taskId": taskID}), trashResults(time.Now()))
 	return err
 }
 
@@ -70,3 +363,196 @@ func (r *ResultRepository) CreateMany(results []models.Result) error {
 	_, err := r.collection.InsertMany(context.Background(), docs)
 	return err
 }
+
+// Import stores imported rows according to mode. Rows are matched on their
+// fingerprint, both against stored results and earlier rows of the same sheet.
+// In append mode repeated rows are stored without a fingerprint so they stay
+// out of the unique index.
+func (r *ResultRepository) Import(results []models.Result, mode models.ImportMode) (*models.ImportSummary, error) {
+	if !mode.Valid() {
+		return nil, ErrInvalidImportMode
+	}
+
+	summary := &models.ImportSummary{Mode: mode, Total: len(results)}
+	if len(results) == 0 {
+		return summary, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fingerprints := make([]string, len(results))
+	for i := range results {
+		fingerprints[i] = results[i].ComputeFingerprint()
+	}
+
+	cursor, err := r.collection.Find(ctx, liveResults(bson.M{"fingerprint": bson.M{"$in": fingerprints}}))
+	if err != nil {
+		return nil, err
+	}
+	var stored []models.Result
+	if err = cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]models.Result, len(stored))
+	for _, result := range stored {
+		existing[result.Fingerprint] = result
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool, len(results))
+	var writes []mongo.WriteModel
+
+	for i := range results {
+		result := results[i]
+		fingerprint := fingerprints[i]
+		result.ID = primitive.NilObjectID
+		result.CreatedAt = now
+		result.UpdatedAt = now
+		result.Fingerprint = fingerprint
+
+		current, found := existing[fingerprint]
+		duplicate := found || seen[fingerprint]
+		seen[fingerprint] = true
+
+		switch {
+		case !duplicate:
+			summary.New++
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(result))
+		case mode == models.ImportAppend:
+			summary.Duplicate++
+			result.Fingerprint = ""
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(result))
+		case mode == models.ImportReplace && found:
+			summary.Updated++
+			result.ID = current.ID
+			result.CreatedAt = current.CreatedAt
+			writes = append(writes, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": current.ID}).SetReplacement(result))
+			// Later copies of this row in the same sheet are plain duplicates
+			delete(existing, fingerprint)
+		default:
+			summary.Duplicate++
+		}
+	}
+
+	if len(writes) == 0 {
+		return summary, nil
+	}
+
+	if _, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true)); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// BackfillFingerprints sets the fingerprint of results stored before
+// deduplication existed. Older duplicates keep no fingerprint so the unique
+// index can still be built.
+func (r *ResultRepository) BackfillFingerprints() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, liveResults(bson.M{"fingerprint": bson.M{"$exists": false}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var result models.Result
+		if err := cursor.Decode(&result); err != nil {
+			return err
+		}
+
+		_, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": result.ID},
+			bson.M{"$set": bson.M{"fingerprint": result.ComputeFingerprint()}},
+		)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// keysetFilter selects the documents that sort after (value, lastID). Fields
+// left out by omitempty sort as null, which needs its own comparison because
+// $gt and $lt never match across BSON types.
+func keysetFilter(field string, value bson.RawValue, lastID primitive.ObjectID, desc bool) bson.M {
+	idOp, valueOp := "$gt", "$gt"
+	if desc {
+		idOp, valueOp = "$lt", "$lt"
+	}
+	isNull := value.Type == bsontype.Null || value.Type == 0
+
+	switch {
+	case isNull && !desc:
+		return bson.M{"$or": []bson.M{
+			{field: nil, "_id": bson.M{idOp: lastID}},
+			{field: bson.M{"$ne": nil}},
+		}}
+	case isNull && desc:
+		return bson.M{field: nil, "_id": bson.M{idOp: lastID}}
+	case desc:
+		return bson.M{"$or": []bson.M{
+			{field: bson.M{valueOp: value}},
+			{field: value, "_id": bson.M{idOp: lastID}},
+			{field: nil},
+		}}
+	default:
+		return bson.M{"$or": []bson.M{
+			{field: bson.M{valueOp: value}},
+			{field: value, "_id": bson.M{idOp: lastID}},
+		}}
+	}
+}
+
+func encodeResultCursor(result *models.Result, field string) (string, error) {
+	raw, err := bson.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := bson.Raw(raw).LookupErr(field)
+	if err != nil {
+		value = bson.RawValue{Type: bsontype.Null}
+	}
+
+	data, err := bson.Marshal(bson.D{{Key: "v", Value: value}, {Key: "id", Value: result.ID}})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeResultCursor(cursor string) (bson.RawValue, primitive.ObjectID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return bson.RawValue{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	raw := bson.Raw(data)
+	if err := raw.Validate(); err != nil {
+		return bson.RawValue{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	idValue, err := raw.LookupErr("id")
+	if err != nil {
+		return bson.RawValue{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+	lastID, ok := idValue.ObjectIDOK()
+	if !ok {
+		return bson.RawValue{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	value, err := raw.LookupErr("v")
+	if err != nil {
+		return bson.RawValue{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	return value, lastID, nil
+}
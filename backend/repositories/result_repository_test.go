@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"redops/models"
+)
+
+func TestResultCursorRoundTrip(t *testing.T) {
+	start := time.Date(2024, 3, 4, 10, 30, 0, 0, time.UTC)
+	result := &models.Result{ID: primitive.NewObjectID(), StartTime: start, ToolApp: "nmap"}
+
+	cursor, err := encodeResultCursor(result, "startTime")
+	if err != nil {
+		t.Fatalf("encodeResultCursor() error = %v", err)
+	}
+	value, lastID, err := decodeResultCursor(cursor)
+	if err != nil {
+		t.Fatalf("decodeResultCursor() error = %v", err)
+	}
+	if lastID != result.ID {
+		t.Errorf("lastID = %s, want %s", lastID.Hex(), result.ID.Hex())
+	}
+	if got := value.Time(); !got.Equal(start) {
+		t.Errorf("value = %s, want %s", got, start)
+	}
+
+	// StartTime is omitted when zero, so the cursor carries a null
+	result.StartTime = time.Time{}
+	cursor, err = encodeResultCursor(result, "startTime")
+	if err != nil {
+		t.Fatalf("encodeResultCursor() error = %v", err)
+	}
+	value, _, err = decodeResultCursor(cursor)
+	if err != nil {
+		t.Fatalf("decodeResultCursor() error = %v", err)
+	}
+	if value.Type != bsontype.Null {
+		t.Errorf("value type = %s, want null", value.Type)
+	}
+}
+
+func TestDecodeResultCursorRejects(t *testing.T) {
+	encode := func(doc bson.D) string {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	tests := map[string]string{
+		"not base64":    "!!!",
+		"not bson":      base64.RawURLEncoding.EncodeToString([]byte("hello")),
+		"missing id":    encode(bson.D{{Key: "v", Value: 1}}),
+		"id not object": encode(bson.D{{Key: "v", Value: 1}, {Key: "id", Value: "abc"}}),
+		"missing value": encode(bson.D{{Key: "id", Value: primitive.NewObjectID()}}),
+	}
+	for name, cursor := range tests {
+		if _, _, err := decodeResultCursor(cursor); err != ErrInvalidCursor {
+			t.Errorf("%s: decodeResultCursor() error = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func TestResultListQuery(t *testing.T) {
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	taskID := primitive.NewObjectID()
+	flagged := false
+
+	query, sort, err := resultListQuery(ResultFilter{
+		TaskID:     taskID,
+		ToolApp:    "nmap",
+		OutOfScope: &flagged,
+		From:       from,
+		To:         to,
+	})
+	if err != nil {
+		t.Fatalf("resultListQuery() error = %v", err)
+	}
+	want := bson.M{
+		resultDeletedAtField: nil,
+		"taskId":             taskID,
+		"toolApp":            "nmap",
+		"outOfScope":         bson.M{"$ne": true},
+		"startTime":          bson.M{"$gte": from, "$lte": to},
+	}
+	if !reflect.DeepEqual(query, want) {
+		t.Errorf("query = %v, want %v", query, want)
+	}
+	if wantSort := (bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}); !reflect.DeepEqual(sort, wantSort) {
+		t.Errorf("sort = %v, want %v", sort, wantSort)
+	}
+
+	query, _, err = resultListQuery(ResultFilter{From: from})
+	if err != nil {
+		t.Fatalf("resultListQuery() error = %v", err)
+	}
+	if got := query["startTime"]; !reflect.DeepEqual(got, bson.M{"$gte": from}) {
+		t.Errorf("open-ended window = %v, want only $gte", got)
+	}
+
+	if _, _, err := resultListQuery(ResultFilter{SortBy: "output"}); err != ErrInvalidSortField {
+		t.Errorf("unknown sort error = %v, want ErrInvalidSortField", err)
+	}
+}
+
+func TestResultListQueryCursor(t *testing.T) {
+	last := &models.Result{ID: primitive.NewObjectID(), ToolApp: "nmap"}
+	cursor, err := encodeResultCursor(last, "toolApp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, sort, err := resultListQuery(ResultFilter{SortBy: "toolApp", SortDesc: true, Cursor: cursor})
+	if err != nil {
+		t.Fatalf("resultListQuery() error = %v", err)
+	}
+	if wantSort := (bson.D{{Key: "toolApp", Value: -1}, {Key: "_id", Value: -1}}); !reflect.DeepEqual(sort, wantSort) {
+		t.Errorf("sort = %v, want %v", sort, wantSort)
+	}
+	and, ok := query["$and"].([]bson.M)
+	if !ok || len(and) != 2 {
+		t.Fatalf("query = %v, want the filter and the keyset condition under $and", query)
+	}
+	value, _, _ := decodeResultCursor(cursor)
+	if want := keysetFilter("toolApp", value, last.ID, true); !reflect.DeepEqual(and[1], want) {
+		t.Errorf("keyset = %v, want %v", and[1], want)
+	}
+
+	if _, _, err := resultListQuery(ResultFilter{Cursor: "bogus!"}); err != ErrInvalidCursor {
+		t.Errorf("bad cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TaskRepository struct {
@@ -22,19 +23,52 @@ func NewTaskRepository() *TaskRepository {
 	}
 }
 
-func (r *TaskRepository) Create(task *models.Task) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Create inserts a task. With a non-nil check the insert is guarded as
+// described at DependencyCheck.
+func (r *TaskRepository) Create(task *models.Task, check DependencyCheck) error {
+	return r.guarded(task.OperationID, check, func(ctx context.Context) error {
+		task.CreatedAt = time.Now()
+		task.UpdatedAt = time.Now()
+		task.DeletedAt = time.Time{}
+		task.Version = 1
+
+		result, err := r.collection.InsertOne(ctx, task)
+		if err != nil {
+			return err
+		}
+
+		task.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+}
+
+// CreateMany inserts a batch of tasks in one round trip
+func (r *TaskRepository) CreateMany(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	task.CreatedAt = time.Now()
-	task.UpdatedAt = time.Now()
+	now := time.Now()
+	docs := make([]interface{}, len(tasks))
+	for i := range tasks {
+		tasks[i].CreatedAt = now
+		tasks[i].UpdatedAt = now
+		tasks[i].DeletedAt = time.Time{}
+		tasks[i].Version = 1
+		docs[i] = tasks[i]
+	}
 
-	result, err := r.collection.InsertOne(ctx, task)
+	result, err := r.collection.InsertMany(ctx, docs)
 	if err != nil {
 		return err
 	}
 
-	task.ID = result.InsertedID.(primitive.ObjectID)
+	for i, id := range result.InsertedIDs {
+		tasks[i].ID = id.(primitive.ObjectID)
+	}
 	return nil
 }
 
@@ -43,9 +77,9 @@ func (r *TaskRepository) GetByID(id primitive.ObjectID) (*models.Task, error) {
 	defer cancel()
 
 	var task models.Task
-	err := r.collection.FindOne(ctx, bson.M{
+	err := r.collection.FindOne(ctx, live(bson.M{
 		"_id": id,
-	}).Decode(&task)
+	})).Decode(&task)
 	if err != nil {
 		return nil, err
 	}
@@ -58,10 +92,10 @@ func (r *TaskRepository) GetByOperationAndTaskID(operationID, taskID primitive.O
 	defer cancel()
 
 	var task models.Task
-	err := r.collection.FindOne(ctx, bson.M{
+	err := r.collection.FindOne(ctx, live(bson.M{
 		"_id":          taskID,
 		"operation_id": operationID,
-	}).Decode(&task)
+	})).Decode(&task)
 	if err != nil {
 		return nil, err
 	}
@@ -69,33 +103,381 @@ func (r *TaskRepository) GetByOperationAndTaskID(operationID, taskID primitive.O
 	return &task, nil
 }
 
-func (r *TaskRepository) Update(task *models.Task) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Update replaces the stored task. It fails with ErrVersionConflict unless
+// the stored version still equals task.Version, which is bumped on success.
+// With a non-nil check the write is guarded as described at DependencyCheck.
+func (r *TaskRepository) Update(task *models.Task, check DependencyCheck) error {
+	expected := task.Version
+	err := r.guarded(task.OperationID, check, func(ctx context.Context) error {
+		task.UpdatedAt = time.Now()
+		task.DeletedAt = time.Time{}
+		task.Version = expected + 1
+
+		result, err := r.collection.UpdateOne(
+			ctx,
+			versioned(live(bson.M{"_id": task.ID}), expected),
+			bson.M{"$set": task},
+		)
+		if err == nil && result.MatchedCount == 0 {
+			err = missOrConflict(ctx, r.collection, task.ID)
+		}
+		return err
+	})
+	if err != nil {
+		task.Version = expected
+		return err
+	}
+	return nil
+}
 
-	task.UpdatedAt = time.Now()
+// Patch sets only the given fields, leaving the rest of the task untouched,
+// and returns the task's new version. A non-zero version must match the
+// stored one or the patch fails with ErrVersionConflict. It returns
+// mongo.ErrNoDocuments when the task does not exist. With a non-nil check
+// the write is guarded as described at DependencyCheck.
+func (r *TaskRepository) Patch(operationID, id primitive.ObjectID, set bson.M, version int64, check DependencyCheck) (int64, error) {
+	fields := bson.M{}
+	for k, v := range set {
+		fields[k] = v
+	}
+	fields["updated_at"] = time.Now()
 
-	_, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": task.ID},
-		bson.M{"$set": task},
-	)
-	return err
+	var updated struct {
+		Version int64 `bson:"version"`
+	}
+	err := r.guarded(operationID, check, func(ctx context.Context) error {
+		err := r.collection.FindOneAndUpdate(
+			ctx,
+			versioned(live(bson.M{"_id": id}), version),
+			bumpVersion(bson.M{"$set": fields}),
+			options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{versionField: 1}),
+		).Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			return missOrConflict(ctx, r.collection, id)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated.Version, nil
 }
 
+// DependencyCheck validates a task's dependencies against the live tasks of
+// its operation. A write given a check runs in a transaction that reads the
+// tasks, runs the check and only then writes, failing with a
+// *DependencyError when the check does. The transaction also bumps a counter
+// on the operation, so two concurrent dependency changes in one operation
+// conflict and the later one is retried against the tasks the earlier one
+// wrote instead of both passing their checks.
+type DependencyCheck func(tasks []models.Task) error
+
+// DependencyError is the error of a DependencyCheck that rejected a write
+type DependencyError struct {
+	Err error
+}
+
+func (e *DependencyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+// guarded runs write, inside a dependency checked transaction when check is
+// not nil
+func (r *TaskRepository) guarded(operationID primitive.ObjectID, check DependencyCheck, write func(ctx context.Context) error) error {
+	if check == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return write(ctx)
+	}
+
+	return database.WithTransaction(func(ctx mongo.SessionContext) error {
+		_, err := database.Operations.UpdateOne(ctx, bson.M{"_id": operationID}, bson.M{"$inc": bson.M{"dependency_revision": 1}})
+		if err != nil {
+			return err
+		}
+
+		cursor, err := r.collection.Find(ctx, live(bson.M{"operation_id": operationID}))
+		if err != nil {
+			return err
+		}
+		var tasks []models.Task
+		if err := cursor.All(ctx, &tasks); err != nil {
+			return err
+		}
+
+		if err := check(tasks); err != nil {
+			return &DependencyError{Err: err}
+		}
+		return write(ctx)
+	})
+}
+
+// Delete moves a task and its results, evidence, comments and time entries
+// to the trash in a single transaction. It refuses with ErrActiveExecutions while tools are still
+// queued or running and returns mongo.ErrNoDocuments when the task does not
+// exist or is already in the trash.
 func (r *TaskRepository) Delete(id primitive.ObjectID) error {
+	return database.WithTransaction(func(ctx mongo.SessionContext) error {
+		return r.trash(ctx, id, time.Now())
+	})
+}
+
+// trash moves a task and everything attached to it to the trash within a
+// transaction
+func (r *TaskRepository) trash(ctx mongo.SessionContext, id primitive.ObjectID, now time.Time) error {
+	active, err := database.Executions.CountDocuments(ctx, bson.M{
+		"task_id": id,
+		"status":  bson.M{"$in": bson.A{models.ExecutionQueued, models.ExecutionRunning}},
+	})
+	if err != nil {
+		return err
+	}
+	if active > 0 {
+		return ErrActiveExecutions
+	}
+
+	result, err := r.collection.UpdateOne(ctx, live(bson.M{"_id": id}), bson.M{"$set": bson.M{deletedAtField: now}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	if _, err := database.Results.UpdateMany(ctx, liveResults(bson.M{"taskId": id}), trashResults(now)); err != nil {
+		return err
+	}
+	return trashTaskContent(ctx, []interface{}{id}, now)
+}
+
+// TaskChange is one task's part of a bulk write: an update document applied
+// with a version bump, or a move to the trash. A non-zero Version must match
+// the stored one. A non-nil Check runs just before the change is written.
+type TaskChange struct {
+	ID      primitive.ObjectID
+	Version int64
+	Update  bson.M
+	Delete  bool
+	Check   DependencyCheck
+}
+
+// TaskChangeError tells which change of a bulk write failed
+type TaskChangeError struct {
+	Index int
+	Err   error
+}
+
+func (e *TaskChangeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TaskChangeError) Unwrap() error {
+	return e.Err
+}
+
+// Bulk applies the changes to tasks of an operation in order in a single
+// transaction, so either all of them are written or none. Checks see the
+// operation's tasks as the changes before them left them, and are guarded
+// against concurrent dependency changes as described at DependencyCheck.
+// When a change fails the error is a *TaskChangeError, wrapping a
+// *DependencyError when its check failed.
+func (r *TaskRepository) Bulk(operationID primitive.ObjectID, changes []TaskChange) error {
+	checked := false
+	for _, change := range changes {
+		checked = checked || change.Check != nil
+	}
+
+	return database.WithTransaction(func(ctx mongo.SessionContext) error {
+		var tasks []models.Task
+		if checked {
+			_, err := database.Operations.UpdateOne(ctx, bson.M{"_id": operationID}, bson.M{"$inc": bson.M{"dependency_revision": 1}})
+			if err != nil {
+				return err
+			}
+			cursor, err := r.collection.Find(ctx, live(bson.M{"operation_id": operationID}))
+			if err != nil {
+				return err
+			}
+			if err := cursor.All(ctx, &tasks); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		for i, change := range changes {
+			if change.Check != nil {
+				if err := change.Check(tasks); err != nil {
+					return &TaskChangeError{Index: i, Err: &DependencyError{Err: err}}
+				}
+			}
+			if err := r.applyChange(ctx, change, now); err != nil {
+				return &TaskChangeError{Index: i, Err: err}
+			}
+			if checked {
+				var err error
+				if tasks, err = r.reload(ctx, tasks, change.ID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// reload replaces a task in tasks with its state as stored in the
+// transaction, dropping it when it has moved to the trash
+func (r *TaskRepository) reload(ctx mongo.SessionContext, tasks []models.Task, id primitive.ObjectID) ([]models.Task, error) {
+	var task models.Task
+	err := r.collection.FindOne(ctx, live(bson.M{"_id": id})).Decode(&task)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	for i := range tasks {
+		if tasks[i].ID != id {
+			continue
+		}
+		if err == mongo.ErrNoDocuments {
+			return append(tasks[:i], tasks[i+1:]...), nil
+		}
+		tasks[i] = task
+		return tasks, nil
+	}
+	return tasks, nil
+}
+
+func (r *TaskRepository) applyChange(ctx mongo.SessionContext, change TaskChange, now time.Time) error {
+	if change.Delete {
+		if change.Version != 0 {
+			if err := r.collection.FindOne(ctx, versioned(live(bson.M{"_id": change.ID}), change.Version)).Err(); err == mongo.ErrNoDocuments {
+				return missOrConflict(ctx, r.collection, change.ID)
+			} else if err != nil {
+				return err
+			}
+		}
+		return r.trash(ctx, change.ID, now)
+	}
+
+	update := bson.M{}
+	for k, v := range change.Update {
+		update[k] = v
+	}
+	set, _ := update["$set"].(bson.M)
+	fields := bson.M{"updated_at": now}
+	for k, v := range set {
+		fields[k] = v
+	}
+	update["$set"] = fields
+
+	result, err := r.collection.UpdateOne(ctx, versioned(live(bson.M{"_id": change.ID}), change.Version), bumpVersion(update))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return missOrConflict(ctx, r.collection, change.ID)
+	}
+	return nil
+}
+
+// Restore takes a task out of the trash together with the results,
+// evidence, comments and time entries that were deleted with it. It fails with ErrParentDeleted while the task's operation
+// is in the trash.
+func (r *TaskRepository) Restore(id primitive.ObjectID) error {
+	return database.WithTransaction(func(ctx mongo.SessionContext) error {
+		var task models.Task
+		if err := r.collection.FindOne(ctx, trashed(bson.M{"_id": id})).Decode(&task); err != nil {
+			return err
+		}
+
+		parents, err := database.Operations.CountDocuments(ctx, live(bson.M{"_id": task.OperationID}))
+		if err != nil {
+			return err
+		}
+		if parents == 0 {
+			return ErrParentDeleted
+		}
+
+		if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{deletedAtField: ""}}); err != nil {
+			return err
+		}
+
+		_, err = database.Results.UpdateMany(ctx, bson.M{"taskId": id, resultDeletedAtField: task.DeletedAt}, restoreResults())
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrDuplicateResult
+		}
+		if err != nil {
+			return err
+		}
+		return restoreTaskContent(ctx, []interface{}{id}, task.DeletedAt)
+	})
+}
+
+// ListDeleted returns the tasks in the trash, most recently deleted first
+func (r *TaskRepository) ListDeleted() ([]models.Task, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	cursor, err := r.collection.Find(ctx, trashed(bson.M{}), trashOptions(deletedAtField))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tasks := []models.Task{}
+	if err = cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// Purge permanently removes tasks that were moved to the trash before
+// cutoff, together with their results, evidence and its stored content,
+// executions, comments and time entries
+func (r *TaskRepository) Purge(cutoff time.Time) (int64, error) {
+	var purged int64
+	err := database.WithTransaction(func(ctx mongo.SessionContext) error {
+		filter := bson.M{deletedAtField: bson.M{"$lt": cutoff}}
+		ids, err := r.collection.Distinct(ctx, "_id", filter)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		if err := removeEvidence(ctx, bson.M{"task_id": bson.M{"$in": ids}}); err != nil {
+			return err
+		}
+		if _, err := database.Results.DeleteMany(ctx, bson.M{"taskId": bson.M{"$in": ids}}); err != nil {
+			return err
+		}
+		if _, err := database.Executions.DeleteMany(ctx, bson.M{"task_id": bson.M{"$in": ids}}); err != nil {
+			return err
+		}
+		if _, err := database.Comments.DeleteMany(ctx, bson.M{"task_id": bson.M{"$in": ids}}); err != nil {
+			return err
+		}
+		if _, err := database.TimeLog.DeleteMany(ctx, bson.M{"task_id": bson.M{"$in": ids}}); err != nil {
+			return err
+		}
+
+		result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return err
+		}
+		purged = result.DeletedCount
+		return nil
+	})
+	return purged, err
 }
 
 func (r *TaskRepository) List() ([]models.Task, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, live(bson.M{}))
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +495,7 @@ func (r *TaskRepository) GetByOperationID(operationID primitive.ObjectID) ([]mod
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{"operation_id": operationID})
+	cursor, err := r.collection.Find(ctx, live(bson.M{"operation_id": operationID}))
 	if err != nil {
 		return nil, err
 	}
@@ -131,7 +513,7 @@ func (r *TaskRepository) GetByAssignedUser(userID primitive.ObjectID) ([]models.
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{"assigned_to": userID})
+	cursor, err := r.collection.Find(ctx, live(bson.M{"assigned_to": userID}))
 	if err != nil {
 		return nil, err
 	}
@@ -145,21 +527,53 @@ func (r *TaskRepository) GetByAssignedUser(userID primitive.ObjectID) ([]models.
 	return tasks, nil
 }
 
-func (r *TaskRepository) UpdateStatus(id primitive.ObjectID, status models.TaskStatus) error {
+// GetByAssignees returns the tasks assigned to any of the given users
+func (r *TaskRepository) GetByAssignees(userIDs []primitive.ObjectID) ([]models.Task, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.collection.UpdateOne(
+	cursor, err := r.collection.Find(ctx, live(bson.M{"assigned_to": bson.M{"$in": userIDs}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tasks := []models.Task{}
+	if err = cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// TransitionStatus moves a task from one status to another and appends the
+// change to its history. Extra fields in set are written in the same update.
+// It reports false when the task's status is no longer from, for example
+// because someone else changed it first.
+func (r *TaskRepository) TransitionStatus(id primitive.ObjectID, from models.TaskStatus, change models.TaskStatusChange, set bson.M) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := bson.M{}
+	for k, v := range set {
+		fields[k] = v
+	}
+	fields["status"] = change.To
+	fields["updated_at"] = time.Now()
+
+	result, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": id},
-		bson.M{
-			"$set": bson.M{
-				"status":     status,
-				"updated_at": time.Now(),
-			},
-		},
+		live(bson.M{"_id": id, "status": statusFilter(string(from))}),
+		bumpVersion(bson.M{
+			"$set":  fields,
+			"$push": bson.M{"status_history": change},
+		}),
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
 }
 
 func (r *TaskRepository) UpdateResults(id primitive.ObjectID, results string) error {
@@ -168,13 +582,13 @@ func (r *TaskRepository) UpdateResults(id primitive.ObjectID, results string) er
 
 	_, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": id},
-		bson.M{
+		live(bson.M{"_id": id}),
+		bumpVersion(bson.M{
 			"$set": bson.M{
 				"results":    results,
 				"updated_at": time.Now(),
 			},
-		},
+		}),
 	)
 	return err
 }
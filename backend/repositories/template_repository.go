@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"redops/database"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TemplateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTemplateRepository() *TemplateRepository {
+	return &TemplateRepository{
+		collection: database.Templates,
+	}
+}
+
+func (r *TemplateRepository) Create(template *models.OperationTemplate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, template)
+	if err != nil {
+		return err
+	}
+
+	template.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *TemplateRepository) GetByID(id primitive.ObjectID) (*models.OperationTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var template models.OperationTemplate
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&template)
+	if err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// Update replaces a template's contents. It returns mongo.ErrNoDocuments
+// when the template does not exist.
+func (r *TemplateRepository) Update(template *models.OperationTemplate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	template.UpdatedAt = time.Now()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": template.ID},
+		bson.M{"$set": bson.M{
+			"name":        template.Name,
+			"description": template.Description,
+			"type":        template.Type,
+			"roe":         template.ROE,
+			"roe_rules":   template.ROERules,
+			"tasks":       template.Tasks,
+			"updated_at":  template.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Delete removes a template. It returns mongo.ErrNoDocuments when the
+// template does not exist.
+func (r *TemplateRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *TemplateRepository) List() ([]models.OperationTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []models.OperationTemplate{}
+	if err = cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"redops/database"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrTimerRunning = errors.New("a timer is already running for this task")
+
+type TimeEntryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTimeEntryRepository() *TimeEntryRepository {
+	return &TimeEntryRepository{
+		collection: database.TimeLog,
+	}
+}
+
+// Create stores a time entry. Starting a second timer for the same user and
+// task fails with ErrTimerRunning.
+func (r *TimeEntryRepository) Create(entry *models.TimeEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry.CreatedAt = time.Now()
+	entry.UpdatedAt = entry.CreatedAt
+
+	result, err := r.collection.InsertOne(ctx, entry)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrTimerRunning
+	}
+	if err != nil {
+		return err
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Stop ends the user's running timer on a task and returns the finished
+// entry. It returns mongo.ErrNoDocuments when no timer is running.
+func (r *TimeEntryRepository) Stop(taskID primitive.ObjectID, userID string, end time.Time) (*models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var entry models.TimeEntry
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		live(bson.M{"task_id": taskID, "user_id": userID, "running": true}),
+		bson.M{
+			"$set":   bson.M{"end": end, "updated_at": time.Now()},
+			"$unset": bson.M{"running": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (r *TimeEntryRepository) GetByID(id primitive.ObjectID) (*models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var entry models.TimeEntry
+	if err := r.collection.FindOne(ctx, live(bson.M{"_id": id})).Decode(&entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// Update replaces the start, end and note of a stopped entry
+func (r *TimeEntryRepository) Update(entry *models.TimeEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry.UpdatedAt = time.Now()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		live(bson.M{"_id": entry.ID, "running": bson.M{"$ne": true}}),
+		bson.M{"$set": bson.M{
+			"start":      entry.Start,
+			"end":        entry.End,
+			"note":       entry.Note,
+			"updated_at": entry.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *TimeEntryRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, live(bson.M{"_id": id}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetByTaskID returns the time booked on a task, earliest first
+func (r *TimeEntryRepository) GetByTaskID(taskID primitive.ObjectID) ([]models.TimeEntry, error) {
+	return r.find(bson.M{"task_id": taskID})
+}
+
+// GetByOperationID returns the time booked on an operation that started
+// within [from, to), earliest first. A zero bound leaves that side open.
+func (r *TimeEntryRepository) GetByOperationID(operationID primitive.ObjectID, from, to time.Time) ([]models.TimeEntry, error) {
+	filter := bson.M{"operation_id": operationID}
+	start := bson.M{}
+	if !from.IsZero() {
+		start["$gte"] = from
+	}
+	if !to.IsZero() {
+		start["$lt"] = to
+	}
+	if len(start) > 0 {
+		filter["start"] = start
+	}
+	return r.find(filter)
+}
+
+// GetRunning returns the timers the user has running
+func (r *TimeEntryRepository) GetRunning(userID string) ([]models.TimeEntry, error) {
+	return r.find(bson.M{"user_id": userID, "running": true})
+}
+
+func (r *TimeEntryRepository) find(filter bson.M) ([]models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, live(filter), options.Find().SetSort(bson.D{{Key: "start", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.TimeEntry{}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
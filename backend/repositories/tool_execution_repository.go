@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"redops/database"
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrActiveExecutions is returned when something cannot be removed while
+// tools are still queued or running against it
+var ErrActiveExecutions = errors.New("tool executions are still queued or running")
+
+type ToolExecutionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewToolExecutionRepository() *ToolExecutionRepository {
+	return &ToolExecutionRepository{
+		collection: database.Executions,
+	}
+}
+
+func (r *ToolExecutionRepository) Create(execution *models.ToolExecution) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	execution.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, execution)
+	if err != nil {
+		return err
+	}
+
+	execution.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *ToolExecutionRepository) GetByID(id primitive.ObjectID) (*models.ToolExecution, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var execution models.ToolExecution
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&execution)
+	if err != nil {
+		return nil, err
+	}
+
+	return &execution, nil
+}
+
+func (r *ToolExecutionRepository) GetByTaskID(taskID primitive.ObjectID) ([]models.ToolExecution, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"task_id": taskID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var executions []models.ToolExecution
+	if err = cursor.All(ctx, &executions); err != nil {
+		return nil, err
+	}
+
+	return executions, nil
+}
+
+// CountStartedSince counts the executions an operation queued after a moment
+func (r *ToolExecutionRepository) CountStartedSince(operationID primitive.ObjectID, since time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{
+		"operation_id": operationID,
+		"created_at":   bson.M{"$gte": since},
+	})
+}
+
+// CancelActive marks every queued or running execution of an operation as
+// cancelled and returns their IDs so the processes can be stopped
+func (r *ToolExecutionRepository) CancelActive(operationID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"operation_id": operationID,
+		"status":       bson.M{"$in": bson.A{models.ExecutionQueued, models.ExecutionRunning}},
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var active []models.ToolExecution
+	if err = cursor.All(ctx, &active); err != nil {
+		return nil, err
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(active))
+	for i, execution := range active {
+		ids[i] = execution.ID
+	}
+
+	_, err = r.collection.UpdateMany(
+		ctx,
+		bson.M{
+			"_id":    bson.M{"$in": ids},
+			"status": bson.M{"$in": bson.A{models.ExecutionQueued, models.ExecutionRunning}},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status": models.ExecutionCancelled,
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// MarkRunning moves a queued execution to running. It reports false when the
+// execution is no longer queued, for example because it was cancelled.
+func (r *ToolExecutionRepository) MarkRunning(id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.ExecutionQueued},
+		bson.M{
+			"$set": bson.M{
+				"status":     models.ExecutionRunning,
+				"start_time": time.Now(),
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// Finish records the outcome of an execution. An execution that was
+// cancelled while running keeps its cancelled status but still gets its output.
+func (r *ToolExecutionRepository) Finish(id primitive.ObjectID, status models.ExecutionStatus, output, errMsg string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"status": bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{"$status", models.ExecutionCancelled}},
+					models.ExecutionCancelled,
+					status,
+				}},
+				// Values are wrapped in $literal so output starting with "$"
+				// is not read as a field path
+				"output":   bson.M{"$literal": output},
+				"error":    bson.M{"$literal": errMsg},
+				"end_time": time.Now(),
+			}}},
+		},
+	)
+	return err
+}
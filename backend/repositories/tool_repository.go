@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"regexp"
 	"time"
 
 	"redops/database"
@@ -10,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ToolRepository struct {
@@ -28,6 +30,8 @@ func (r *ToolRepository) Create(tool *models.Tool) error {
 
 	tool.CreatedAt = time.Now()
 	tool.UpdatedAt = time.Now()
+	tool.DeletedAt = time.Time{}
+	tool.Version = 1
 
 	result, err := r.collection.InsertOne(ctx, tool)
 	if err != nil {
@@ -43,7 +47,7 @@ func (r *ToolRepository) GetByID(id primitive.ObjectID) (*models.Tool, error) {
 	defer cancel()
 
 	var tool models.Tool
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&tool)
+	err := r.collection.FindOne(ctx, live(bson.M{"_id": id})).Decode(&tool)
 	if err != nil {
 		return nil, err
 	}
@@ -51,33 +55,155 @@ func (r *ToolRepository) GetByID(id primitive.ObjectID) (*models.Tool, error) {
 	return &tool, nil
 }
 
+// GetByName finds a tool by name, ignoring case
+func (r *ToolRepository) GetByName(name string) (*models.Tool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"name": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"}}
+
+	var tool models.Tool
+	err := r.collection.FindOne(ctx, live(filter)).Decode(&tool)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tool, nil
+}
+
+// Update replaces the stored tool. It fails with ErrVersionConflict unless
+// the stored version still equals tool.Version, which is bumped on success.
 func (r *ToolRepository) Update(tool *models.Tool) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	expected := tool.Version
 	tool.UpdatedAt = time.Now()
+	tool.DeletedAt = time.Time{}
+	tool.Version = expected + 1
 
-	_, err := r.collection.UpdateOne(
+	result, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": tool.ID},
+		versioned(live(bson.M{"_id": tool.ID}), expected),
 		bson.M{"$set": tool},
 	)
-	return err
+	if err == nil && result.MatchedCount == 0 {
+		err = missOrConflict(ctx, r.collection, tool.ID)
+	}
+	if err != nil {
+		tool.Version = expected
+		return err
+	}
+	return nil
+}
+
+// Patch sets only the given fields, leaving the rest of the tool untouched,
+// and returns the tool's new version. A non-zero version must match the
+// stored one or the patch fails with ErrVersionConflict. It returns
+// mongo.ErrNoDocuments when the tool does not exist.
+func (r *ToolRepository) Patch(id primitive.ObjectID, set bson.M, version int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := bson.M{}
+	for k, v := range set {
+		fields[k] = v
+	}
+	fields["updated_at"] = time.Now()
+
+	var updated struct {
+		Version int64 `bson:"version"`
+	}
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		versioned(live(bson.M{"_id": id}), version),
+		bumpVersion(bson.M{"$set": fields}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{versionField: 1}),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return 0, missOrConflict(ctx, r.collection, id)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return updated.Version, nil
 }
 
+// Delete moves a tool to the trash. It returns mongo.ErrNoDocuments when
+// the tool does not exist or is already in the trash.
 func (r *ToolRepository) Delete(id primitive.ObjectID) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	result, err := r.collection.UpdateOne(
+		ctx,
+		live(bson.M{"_id": id}),
+		bson.M{"$set": bson.M{deletedAtField: time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Restore takes a tool out of the trash
+func (r *ToolRepository) Restore(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		trashed(bson.M{"_id": id}),
+		bson.M{"$unset": bson.M{deletedAtField: ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ListDeleted returns the tools in the trash, most recently deleted first
+func (r *ToolRepository) ListDeleted() ([]models.Tool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, trashed(bson.M{}), trashOptions(deletedAtField))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tools := []models.Tool{}
+	if err = cursor.All(ctx, &tools); err != nil {
+		return nil, err
+	}
+
+	return tools, nil
+}
+
+// Purge permanently removes tools that were moved to the trash before cutoff
+func (r *ToolRepository) Purge(cutoff time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{deletedAtField: bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
 }
 
 func (r *ToolRepository) List() ([]models.Tool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, live(bson.M{}))
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +221,7 @@ func (r *ToolRepository) GetByType(toolType models.ToolType) ([]models.Tool, err
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{"type": toolType})
+	cursor, err := r.collection.Find(ctx, live(bson.M{"type": toolType}))
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +239,7 @@ func (r *ToolRepository) GetActiveTools() ([]models.Tool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{"is_active": true})
+	cursor, err := r.collection.Find(ctx, live(bson.M{"is_active": true}))
 	if err != nil {
 		return nil, err
 	}
@@ -133,13 +259,13 @@ func (r *ToolRepository) UpdateStatus(id primitive.ObjectID, isActive bool) erro
 
 	_, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": id},
-		bson.M{
+		live(bson.M{"_id": id}),
+		bumpVersion(bson.M{
 			"$set": bson.M{
 				"is_active":  isActive,
 				"updated_at": time.Now(),
 			},
-		},
+		}),
 	)
 	return err
 }
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"redops/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Deleted documents stay in their collection with the time they were moved
+// to the trash until the purge job removes them for good. Results use the
+// camelCase field names of the rest of that collection.
+const (
+	deletedAtField       = "deleted_at"
+	resultDeletedAtField = "deletedAt"
+)
+
+// ErrParentDeleted is returned when restoring something whose operation or
+// task is itself still in the trash
+var ErrParentDeleted = errors.New("restore the parent from the trash first")
+
+// live restricts a filter to documents that are not in the trash
+func live(filter bson.M) bson.M {
+	filter[deletedAtField] = nil
+	return filter
+}
+
+// liveResults restricts a results filter to results that are not in the trash
+func liveResults(filter bson.M) bson.M {
+	filter[resultDeletedAtField] = nil
+	return filter
+}
+
+// trashed matches documents in the trash
+func trashed(filter bson.M) bson.M {
+	filter[deletedAtField] = bson.M{"$ne": nil}
+	return filter
+}
+
+// trashOptions lists the most recently deleted documents first
+func trashOptions(field string) *options.FindOptions {
+	return options.Find().SetSort(bson.D{{Key: field, Value: -1}})
+}
+
+// trashResults moves results to the trash. Their fingerprint is set aside so
+// the same rows can be imported again while they are in the trash.
+func trashResults(deletedAt interface{}) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			resultDeletedAtField: deletedAt,
+			"trashedFingerprint": "$fingerprint",
+		}}},
+		{{Key: "$unset", Value: "fingerprint"}},
+	}
+}
+
+// restoreResults takes results out of the trash and gives them back their
+// fingerprint
+func restoreResults() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"fingerprint": "$trashedFingerprint"}}},
+		{{Key: "$unset", Value: bson.A{resultDeletedAtField, "trashedFingerprint"}}},
+	}
+}
+
+// taskContent returns the collections holding what is attached to a task by
+// its task_id: evidence, comments and time entries. They go to the trash and
+// come back with the task.
+func taskContent() []*mongo.Collection {
+	return []*mongo.Collection{database.Evidence, database.Comments, database.TimeLog}
+}
+
+// trashTaskContent moves the live evidence, comments and time entries of the
+// given tasks to the trash, stamped with the tasks' deletion time
+func trashTaskContent(ctx mongo.SessionContext, taskIDs []interface{}, now time.Time) error {
+	for _, collection := range taskContent() {
+		filter := live(bson.M{"task_id": bson.M{"$in": taskIDs}})
+		if _, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{deletedAtField: now}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreTaskContent brings back the evidence, comments and time entries that
+// went to the trash with the given tasks at deletedAt. Anything deleted on
+// its own beforehand stays in the trash.
+func restoreTaskContent(ctx mongo.SessionContext, taskIDs []interface{}, deletedAt time.Time) error {
+	for _, collection := range taskContent() {
+		filter := bson.M{"task_id": bson.M{"$in": taskIDs}, deletedAtField: deletedAt}
+		if _, err := collection.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{deletedAtField: ""}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
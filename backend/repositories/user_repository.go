@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"regexp"
 	"time"
 
 	"redops/database"
@@ -78,6 +79,28 @@ func (r *UserRepository) Update(user *models.User) error {
 	return err
 }
 
+// Patch sets only the given fields, leaving the rest of the user untouched.
+// It returns mongo.ErrNoDocuments when the user does not exist.
+func (r *UserRepository) Patch(id primitive.ObjectID, set bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := bson.M{}
+	for k, v := range set {
+		fields[k] = v
+	}
+	fields["updated_at"] = time.Now()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (r *UserRepository) Delete(id primitive.ObjectID) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -103,3 +126,51 @@ func (r *UserRepository) List() ([]models.User, error) {
 
 	return users, nil
 }
+
+// GetByUsernames returns the users with any of the given usernames, ignoring
+// case
+func (r *UserRepository) GetByUsernames(usernames []string) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if len(usernames) == 0 {
+		return []models.User{}, nil
+	}
+
+	patterns := make(bson.A, len(usernames))
+	for i, name := range usernames {
+		patterns[i] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"}
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"username": bson.M{"$in": patterns}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetByIDs returns the users with any of the given IDs
+func (r *UserRepository) GetByIDs(ids []primitive.ObjectID) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
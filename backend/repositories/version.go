@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"redops/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Operations, tasks and tools carry a version that every write bumps, so a
+// client can tell when the copy it edited has changed underneath it.
+const versionField = "version"
+
+// ErrVersionConflict is returned when a write expected a version the stored
+// document no longer has
+var ErrVersionConflict = errors.New("document was changed by someone else")
+
+// versioned restricts a filter to the expected version. A zero version skips
+// the check.
+func versioned(filter bson.M, version int64) bson.M {
+	if version != 0 {
+		filter[versionField] = version
+	}
+	return filter
+}
+
+// bumpVersion adds a version increment to an update document
+func bumpVersion(update bson.M) bson.M {
+	update["$inc"] = bson.M{versionField: 1}
+	return update
+}
+
+// missOrConflict explains why a versioned write matched nothing: the
+// document is gone or its version moved on
+func missOrConflict(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) error {
+	count, err := collection.CountDocuments(ctx, live(bson.M{"_id": id}))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return ErrVersionConflict
+}
+
+// BackfillVersions gives documents stored before versioning was introduced
+// their first version
+func BackfillVersions() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, collection := range []*mongo.Collection{database.Operations, database.Tasks, database.Tools} {
+		if _, err := collection.UpdateMany(ctx,
+			bson.M{versionField: bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{versionField: int64(1)}},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
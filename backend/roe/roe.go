@@ -0,0 +1,268 @@
+// Package roe checks tool executions and recorded activity against the
+// machine-checkable rules of engagement of an operation.
+package roe
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"redops/models"
+)
+
+const (
+	RuleToolType     = "tool_type"
+	RuleDeniedCmd    = "denied_command"
+	RuleTestingHours = "testing_hours"
+	RuleScanRate     = "scan_rate"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// rateFlags are command-line options that set a scanner's packet rate
+var rateFlags = regexp.MustCompile(`(?:^|\s)--?(?:max-rate|rate|min-rate)[=\s]+(\d+)`)
+
+// patterns caches the compiled denied-command patterns by their source, so
+// each is compiled once rather than on every evaluation
+var patterns sync.Map
+
+// pattern returns the compiled form of a denied-command pattern
+func pattern(source string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(source); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(source, re)
+	return re, nil
+}
+
+// Action is something about to happen, or recorded as having happened,
+// during an operation
+type Action struct {
+	Phase     models.OperationPhase
+	ToolType  models.ToolType
+	ToolName  string
+	Command   string
+	Arguments map[string]string
+	Time      time.Time
+	// RecentExecutions is the number of executions started in the last
+	// minute, or -1 when the action is not an execution
+	RecentExecutions int
+}
+
+// Validate checks that rules are well formed before they are stored
+func Validate(rules *models.ROERules) error {
+	for _, r := range rules.ToolTypeRestrictions {
+		if r.Type == "" {
+			return fmt.Errorf("tool type restriction needs a type")
+		}
+		if !r.AllowedFromPhase.Valid() {
+			return fmt.Errorf("unknown phase %q", r.AllowedFromPhase)
+		}
+		if err := validEnforcement(r.Enforcement); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range rules.DeniedCommands {
+		if _, err := pattern(p.Pattern); err != nil {
+			return fmt.Errorf("invalid command pattern %q: %v", p.Pattern, err)
+		}
+		if err := validEnforcement(p.Enforcement); err != nil {
+			return err
+		}
+	}
+
+	if h := rules.TestingHours; h != nil {
+		if _, err := clock(h.Start); err != nil {
+			return fmt.Errorf("invalid testing hours start %q", h.Start)
+		}
+		if _, err := clock(h.End); err != nil {
+			return fmt.Errorf("invalid testing hours end %q", h.End)
+		}
+		for _, day := range h.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("invalid weekday %q", day)
+			}
+		}
+		if _, err := time.LoadLocation(h.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", h.Timezone)
+		}
+		if err := validEnforcement(h.Enforcement); err != nil {
+			return err
+		}
+	}
+
+	if s := rules.ScanRate; s != nil {
+		if s.MaxExecutionsPerMinute < 0 || s.MaxPacketsPerSecond < 0 {
+			return fmt.Errorf("scan rate limits cannot be negative")
+		}
+		if err := validEnforcement(s.Enforcement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Evaluate returns every rule the action breaks
+func Evaluate(rules *models.ROERules, action Action) []models.ROEViolation {
+	if rules == nil {
+		return nil
+	}
+
+	var violations []models.ROEViolation
+
+	for _, r := range rules.ToolTypeRestrictions {
+		if action.ToolType != r.Type {
+			continue
+		}
+		if action.Phase.Index() < r.AllowedFromPhase.Index() {
+			violations = append(violations, models.ROEViolation{
+				Rule:        RuleToolType,
+				Message:     fmt.Sprintf("%s tools are not allowed before the %s phase (current phase: %s)", r.Type, r.AllowedFromPhase, phaseName(action.Phase)),
+				Enforcement: r.Enforcement,
+			})
+		}
+	}
+
+	if action.Command != "" {
+		for _, p := range rules.DeniedCommands {
+			re, err := pattern(p.Pattern)
+			if err != nil || !re.MatchString(action.Command) {
+				continue
+			}
+			message := fmt.Sprintf("command matches denied pattern %q", p.Pattern)
+			if p.Description != "" {
+				message += ": " + p.Description
+			}
+			violations = append(violations, models.ROEViolation{
+				Rule:        RuleDeniedCmd,
+				Message:     message,
+				Enforcement: p.Enforcement,
+			})
+		}
+	}
+
+	if h := rules.TestingHours; h != nil && !action.Time.IsZero() && !withinHours(h, action.Time) {
+		violations = append(violations, models.ROEViolation{
+			Rule:        RuleTestingHours,
+			Message:     fmt.Sprintf("activity at %s is outside the allowed testing hours %s-%s", action.Time.UTC().Format(time.RFC3339), h.Start, h.End),
+			Enforcement: h.Enforcement,
+		})
+	}
+
+	if s := rules.ScanRate; s != nil {
+		if s.MaxExecutionsPerMinute > 0 && action.RecentExecutions >= s.MaxExecutionsPerMinute {
+			violations = append(violations, models.ROEViolation{
+				Rule:        RuleScanRate,
+				Message:     fmt.Sprintf("operation already started %d executions in the last minute (limit %d)", action.RecentExecutions, s.MaxExecutionsPerMinute),
+				Enforcement: s.Enforcement,
+			})
+		}
+		if s.MaxPacketsPerSecond > 0 {
+			if rate, ok := requestedRate(action); ok && rate > s.MaxPacketsPerSecond {
+				violations = append(violations, models.ROEViolation{
+					Rule:        RuleScanRate,
+					Message:     fmt.Sprintf("requested rate of %d packets per second exceeds the limit of %d", rate, s.MaxPacketsPerSecond),
+					Enforcement: s.Enforcement,
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// Blocking reports whether any violation is a hard block
+func Blocking(violations []models.ROEViolation) bool {
+	for _, v := range violations {
+		if v.Enforcement == models.ROEBlock {
+			return true
+		}
+	}
+	return false
+}
+
+func withinHours(h *models.TestingHours, at time.Time) bool {
+	loc, err := time.LoadLocation(h.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := at.In(loc)
+
+	if len(h.Days) > 0 {
+		allowed := false
+		for _, day := range h.Days {
+			if weekdays[strings.ToLower(day)] == local.Weekday() {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	start, err := clock(h.Start)
+	if err != nil {
+		return true
+	}
+	end, err := clock(h.End)
+	if err != nil {
+		return true
+	}
+
+	now := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// clock parses HH:MM into minutes after midnight
+func clock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// requestedRate finds the packet rate an action asks for, from a rate
+// argument or a --rate style flag on the command line
+func requestedRate(action Action) (int, bool) {
+	for _, key := range []string{"max_rate", "max-rate", "rate"} {
+		if n, err := strconv.Atoi(action.Arguments[key]); err == nil {
+			return n, true
+		}
+	}
+	if m := rateFlags.FindStringSubmatch(action.Command); m != nil {
+		n, err := strconv.Atoi(m[1])
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func validEnforcement(e models.ROEEnforcement) error {
+	if e != models.ROEWarn && e != models.ROEBlock {
+		return fmt.Errorf("enforcement must be %q or %q", models.ROEWarn, models.ROEBlock)
+	}
+	return nil
+}
+
+func phaseName(phase models.OperationPhase) string {
+	if phase == "" {
+		return "none"
+	}
+	return string(phase)
+}
@@ -0,0 +1,142 @@
+package roe
+
+import (
+	"testing"
+	"time"
+
+	"redops/models"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   models.ROERules
+		wantErr bool
+	}{
+		{"empty", models.ROERules{}, false},
+		{"valid", models.ROERules{
+			ToolTypeRestrictions: []models.ToolTypeRestriction{{Type: models.TypeExploitation, AllowedFromPhase: models.PhaseInitialAccess, Enforcement: models.ROEBlock}},
+			DeniedCommands:       []models.CommandPattern{{Pattern: `rm\s+-rf`, Enforcement: models.ROEWarn}},
+			TestingHours:         &models.TestingHours{Start: "09:00", End: "17:00", Days: []string{"mon", "Tue"}, Timezone: "Europe/London", Enforcement: models.ROEWarn},
+			ScanRate:             &models.ScanRateLimit{MaxPacketsPerSecond: 100, Enforcement: models.ROEBlock},
+		}, false},
+		{"restriction without type", models.ROERules{
+			ToolTypeRestrictions: []models.ToolTypeRestriction{{AllowedFromPhase: models.PhaseInitialAccess, Enforcement: models.ROEBlock}},
+		}, true},
+		{"unknown phase", models.ROERules{
+			ToolTypeRestrictions: []models.ToolTypeRestriction{{Type: models.TypeExploitation, AllowedFromPhase: "looting", Enforcement: models.ROEBlock}},
+		}, true},
+		{"invalid pattern", models.ROERules{
+			DeniedCommands: []models.CommandPattern{{Pattern: `(`, Enforcement: models.ROEWarn}},
+		}, true},
+		{"unknown enforcement", models.ROERules{
+			DeniedCommands: []models.CommandPattern{{Pattern: `x`, Enforcement: "ignore"}},
+		}, true},
+		{"invalid start", models.ROERules{
+			TestingHours: &models.TestingHours{Start: "9am", End: "17:00", Enforcement: models.ROEWarn},
+		}, true},
+		{"invalid weekday", models.ROERules{
+			TestingHours: &models.TestingHours{Start: "09:00", End: "17:00", Days: []string{"funday"}, Enforcement: models.ROEWarn},
+		}, true},
+		{"invalid timezone", models.ROERules{
+			TestingHours: &models.TestingHours{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus", Enforcement: models.ROEWarn},
+		}, true},
+		{"negative rate", models.ROERules{
+			ScanRate: &models.ScanRateLimit{MaxPacketsPerSecond: -1, Enforcement: models.ROEWarn},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(&tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := &models.ROERules{
+		ToolTypeRestrictions: []models.ToolTypeRestriction{{Type: models.TypeExploitation, AllowedFromPhase: models.PhaseInitialAccess, Enforcement: models.ROEBlock}},
+		DeniedCommands:       []models.CommandPattern{{Pattern: `rm\s+-rf`, Enforcement: models.ROEBlock}},
+		TestingHours:         &models.TestingHours{Start: "09:00", End: "17:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Timezone: "UTC", Enforcement: models.ROEWarn},
+		ScanRate:             &models.ScanRateLimit{MaxPacketsPerSecond: 100, Enforcement: models.ROEWarn},
+	}
+	// Monday 4 March 2024
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 4, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		action   Action
+		want     []string
+		blocking bool
+	}{
+		{"allowed", Action{Phase: models.PhaseInitialAccess, ToolType: models.TypeExploitation, Command: "msfconsole", Time: monday(10, 0)}, nil, false},
+		{"exploitation too early", Action{Phase: models.PhaseReconnaissance, ToolType: models.TypeExploitation, Time: monday(10, 0)}, []string{RuleToolType}, true},
+		{"other tool type early", Action{Phase: models.PhaseReconnaissance, ToolType: models.TypeReconnaissance, Time: monday(10, 0)}, nil, false},
+		{"denied command", Action{Command: "rm  -rf /", Time: monday(10, 0)}, []string{RuleDeniedCmd}, true},
+		{"after hours", Action{Time: monday(17, 0)}, []string{RuleTestingHours}, false},
+		{"weekend", Action{Time: time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC)}, []string{RuleTestingHours}, false},
+		{"unknown time", Action{}, nil, false},
+		{"rate flag over limit", Action{Command: "masscan 10.0.0.0/8 --rate 5000", Time: monday(10, 0)}, []string{RuleScanRate}, false},
+		{"rate argument within limit", Action{Arguments: map[string]string{"rate": "50"}, Time: monday(10, 0)}, nil, false},
+		{"several rules", Action{Phase: models.PhaseReconnaissance, ToolType: models.TypeExploitation, Command: "rm -rf /tmp", Time: monday(20, 0)}, []string{RuleToolType, RuleDeniedCmd, RuleTestingHours}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := Evaluate(rules, tt.action)
+			if len(violations) != len(tt.want) {
+				t.Fatalf("Evaluate() = %v, want rules %v", violations, tt.want)
+			}
+			for i, rule := range tt.want {
+				if violations[i].Rule != rule {
+					t.Errorf("violation %d rule = %s, want %s", i, violations[i].Rule, rule)
+				}
+			}
+			if got := Blocking(violations); got != tt.blocking {
+				t.Errorf("Blocking() = %v, want %v", got, tt.blocking)
+			}
+		})
+	}
+}
+
+func TestEvaluateExecutionRate(t *testing.T) {
+	rules := &models.ROERules{
+		ScanRate: &models.ScanRateLimit{MaxExecutionsPerMinute: 3, Enforcement: models.ROEBlock},
+	}
+
+	tests := []struct {
+		recent int
+		want   int
+	}{
+		{2, 0},
+		{3, 1},
+		{-1, 0}, // not an execution, such as an imported row
+	}
+	for _, tt := range tests {
+		violations := Evaluate(rules, Action{RecentExecutions: tt.recent})
+		if len(violations) != tt.want {
+			t.Errorf("Evaluate(recent %d) = %v, want %d violations", tt.recent, violations, tt.want)
+		}
+	}
+}
+
+func TestWithinHoursAcrossMidnight(t *testing.T) {
+	hours := &models.TestingHours{Start: "22:00", End: "06:00", Timezone: "UTC"}
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2024, 3, 4, 23, 30, 0, 0, time.UTC), true},
+		{time.Date(2024, 3, 4, 5, 59, 0, 0, time.UTC), true},
+		{time.Date(2024, 3, 4, 6, 0, 0, 0, time.UTC), false},
+		{time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got := withinHours(hours, tt.at); got != tt.want {
+			t.Errorf("withinHours(%s) = %v, want %v", tt.at.Format("15:04"), got, tt.want)
+		}
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, operationHandler *handlers.OperationHandler, taskHandler *handlers.TaskHandler, toolHandler *handlers.ToolHandler, resultHandler *handlers.ResultHandler) {
+func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, operationHandler *handlers.OperationHandler, taskHandler *handlers.TaskHandler, toolHandler *handlers.ToolHandler, resultHandler *handlers.ResultHandler, timelineHandler *handlers.TimelineHandler, assetHandler *handlers.AssetHandler, graphHandler *handlers.GraphHandler, scopeHandler *handlers.ScopeHandler, haltHandler *handlers.HaltHandler, webSocketHandler *handlers.WebSocketHandler, templateHandler *handlers.TemplateHandler, trashHandler *handlers.TrashHandler, attackHandler *handlers.AttackHandler, owaspHandler *handlers.OWASPHandler, commentHandler *handlers.CommentHandler, evidenceHandler *handlers.EvidenceHandler, timeHandler *handlers.TimeHandler, workloadHandler *handlers.WorkloadHandler) {
 	// Group all routes under /api
 	api := router.Group("/api")
 	{
@@ -19,10 +19,14 @@ func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, operatio
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
 		{
+			// Live notifications
+			protected.GET("/ws", webSocketHandler.Connect)
+
 			// User routes
 			protected.GET("/users", userHandler.ListUsers)
 			protected.GET("/users/:id", userHandler.GetUser)
 			protected.PUT("/users/:id", userHandler.UpdateUser)
+			protected.PATCH("/users/:id", userHandler.PatchUser)
 			protected.DELETE("/users/:id", userHandler.DeleteUser)
 
 			// Operation routes
@@ -30,13 +34,41 @@ func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, operatio
 			protected.POST("/operations", operationHandler.CreateOperation)
 			protected.GET("/operations/:id", operationHandler.GetOperation)
 			protected.PUT("/operations/:id", operationHandler.UpdateOperation)
+			protected.PATCH("/operations/:id", operationHandler.PatchOperation)
 			protected.DELETE("/operations/:id", operationHandler.DeleteOperation)
+			protected.POST("/operations/:id/clone", templateHandler.CloneOperation)
+			protected.PUT("/operations/:id/phase", operationHandler.UpdateOperationPhase)
+			protected.GET("/operations/:id/phases", operationHandler.GetPhaseHistory)
+			protected.GET("/operations/:id/status", operationHandler.GetOperationStatus)
+			protected.POST("/operations/:id/status", operationHandler.TransitionOperationStatus)
+			protected.POST("/operations/:id/halt", haltHandler.HaltOperation)
+			protected.POST("/operations/:id/resume", haltHandler.ResumeOperation)
+			protected.GET("/operations/:id/roe-rules", operationHandler.GetROERules)
+			protected.PUT("/operations/:id/roe-rules", operationHandler.UpdateROERules)
+			protected.GET("/operations/:id/timeline", timelineHandler.GetOperationTimeline)
+			protected.GET("/operations/:id/graph", graphHandler.GetOperationGraph)
+			protected.GET("/operations/:id/attack-layer", attackHandler.GetAttackLayer)
+			protected.POST("/operations/:id/attack-layer", attackHandler.ImportAttackLayer)
+			protected.GET("/operations/:id/owasp-coverage", owaspHandler.GetOWASPCoverage)
+			protected.GET("/operations/:id/scope", scopeHandler.GetOperationScope)
+			protected.POST("/operations/:id/scope/evaluate", scopeHandler.EvaluateScope)
+
+			// Template routes
+			protected.GET("/templates", templateHandler.ListTemplates)
+			protected.POST("/templates", templateHandler.CreateTemplate)
+			protected.GET("/templates/:id", templateHandler.GetTemplate)
+			protected.PUT("/templates/:id", templateHandler.UpdateTemplate)
+			protected.DELETE("/templates/:id", templateHandler.DeleteTemplate)
+			protected.POST("/templates/:id/instantiate", templateHandler.InstantiateTemplate)
 
 			// Task routes
 			protected.GET("/operations/:id/tasks", taskHandler.GetTasksByOperation)
 			protected.POST("/operations/:id/tasks", taskHandler.CreateTask)
+			protected.POST("/operations/:id/tasks/bulk", taskHandler.BulkUpdateTasks)
+			protected.GET("/operations/:id/task-plan", taskHandler.GetTaskPlan)
 			protected.GET("/operations/:id/tasks/:taskId", taskHandler.GetTask)
 			protected.PUT("/operations/:id/tasks/:taskId", taskHandler.UpdateTask)
+			protected.PATCH("/operations/:id/tasks/:taskId", taskHandler.PatchTask)
 			protected.DELETE("/operations/:id/tasks/:taskId", taskHandler.DeleteTask)
 
 			// Tool routes
@@ -44,12 +76,74 @@ func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, operatio
 			protected.POST("/tools", toolHandler.CreateTool)
 			protected.GET("/tools/:id", toolHandler.GetTool)
 			protected.PUT("/tools/:id", toolHandler.UpdateTool)
+			protected.PATCH("/tools/:id", toolHandler.PatchTool)
 			protected.DELETE("/tools/:id", toolHandler.DeleteTool)
+			protected.POST("/tools/:id/execute", toolHandler.ExecuteTool)
+
+			// Tool execution routes
+			protected.GET("/executions/:id", toolHandler.GetExecution)
+			protected.GET("/tasks/:taskId/status", taskHandler.GetTaskStatus)
+			protected.PUT("/tasks/:taskId/status", taskHandler.UpdateTaskStatus)
+			protected.GET("/tasks/:taskId/executions", toolHandler.GetTaskExecutions)
+
+			// Comment routes
+			protected.GET("/tasks/:taskId/comments", commentHandler.GetTaskComments)
+			protected.POST("/tasks/:taskId/comments", commentHandler.CreateComment)
+			protected.PUT("/comments/:id", commentHandler.UpdateComment)
+			protected.DELETE("/comments/:id", commentHandler.DeleteComment)
 
 			// Result routes
 			protected.GET("/tasks/:taskId/results", resultHandler.GetTaskResults)
+			protected.POST("/tasks/:taskId/results", resultHandler.CreateResult)
 			protected.POST("/tasks/:taskId/results/import", resultHandler.ImportResults)
 			protected.DELETE("/tasks/:taskId/results", resultHandler.DeleteTaskResults)
+			protected.GET("/results", resultHandler.ListResults)
+			protected.GET("/results/:id", resultHandler.GetResult)
+			protected.PUT("/results/:id", resultHandler.UpdateResult)
+			protected.DELETE("/results/:id", resultHandler.DeleteResult)
+
+			// Evidence routes
+			protected.GET("/tasks/:taskId/evidence", evidenceHandler.GetTaskEvidence)
+			protected.POST("/tasks/:taskId/evidence", evidenceHandler.UploadTaskEvidence)
+			protected.GET("/results/:id/evidence", evidenceHandler.GetResultEvidence)
+			protected.POST("/results/:id/evidence", evidenceHandler.UploadResultEvidence)
+			protected.GET("/evidence/:id", evidenceHandler.GetEvidence)
+			protected.GET("/evidence/:id/download", evidenceHandler.DownloadEvidence)
+			protected.POST("/evidence/:id/verify", evidenceHandler.VerifyEvidence)
+			protected.DELETE("/evidence/:id", evidenceHandler.DeleteEvidence)
+
+			// Time tracking routes
+			protected.GET("/timers", timeHandler.GetRunningTimers)
+			protected.POST("/tasks/:taskId/timer/start", timeHandler.StartTimer)
+			protected.POST("/tasks/:taskId/timer/stop", timeHandler.StopTimer)
+			protected.GET("/tasks/:taskId/time-entries", timeHandler.GetTaskTimeEntries)
+			protected.POST("/tasks/:taskId/time-entries", timeHandler.CreateTimeEntry)
+			protected.PUT("/time-entries/:id", timeHandler.UpdateTimeEntry)
+			protected.DELETE("/time-entries/:id", timeHandler.DeleteTimeEntry)
+			protected.GET("/operations/:id/time-report", timeHandler.GetTimeReport)
+
+			// Workload routes
+			protected.GET("/workload", workloadHandler.GetWorkload)
+			protected.GET("/operations/:id/assignee-suggestions", workloadHandler.GetAssigneeSuggestions)
+
+			// Asset routes
+			protected.GET("/operations/:id/assets", assetHandler.GetOperationAssets)
+			protected.POST("/operations/:id/assets/merge", assetHandler.MergeAssets)
+			protected.GET("/assets/:id", assetHandler.GetAsset)
+
+			// MITRE ATT&CK catalogue routes
+			protected.GET("/attack/techniques", attackHandler.SearchTechniques)
+			protected.GET("/attack/techniques/:techniqueId", attackHandler.GetTechnique)
+			protected.POST("/attack/import", attackHandler.ImportCatalogue)
+
+			// OWASP catalogue routes
+			protected.GET("/owasp/top10", owaspHandler.ListTop10)
+			protected.GET("/owasp/wstg", owaspHandler.ListWSTG)
+			protected.POST("/owasp/operations", owaspHandler.CreateWebAppOperation)
+
+			// Trash routes
+			protected.GET("/trash/:type", trashHandler.ListTrash)
+			protected.POST("/trash/:type/:id/restore", trashHandler.RestoreFromTrash)
 		}
 	}
 }
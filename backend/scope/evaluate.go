@@ -0,0 +1,255 @@
+package scope
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"redops/models"
+)
+
+// target is a normalised form of something an operator pointed a tool at
+type target struct {
+	start, end netip.Addr
+	host       string
+	url        *url.URL
+}
+
+func (t target) isIP() bool {
+	return t.start.IsValid()
+}
+
+// Empty reports whether the definition places no restrictions at all. A
+// scope with entries that could not be parsed is not empty.
+func Empty(def *models.ScopeDefinition) bool {
+	return len(def.Include) == 0 && len(def.Exclude) == 0 && len(def.Windows) == 0 && len(def.Unparsed) == 0
+}
+
+// Evaluate checks a target at the given time against the scope. Exclusions
+// win over inclusions; with no inclusions every target not excluded is in
+// scope. Hostnames are never resolved, so they only match domain and URL
+// rules. While any entry of the scope cannot be parsed nothing is in scope,
+// since that entry might have excluded the target.
+func Evaluate(def *models.ScopeDefinition, raw string, at time.Time) models.ScopeVerdict {
+	verdict := models.ScopeVerdict{Target: raw}
+
+	if len(def.Unparsed) > 0 {
+		verdict.Reason = fmt.Sprintf("scope has entries that could not be parsed: %s", strings.Join(def.Unparsed, ", "))
+		return verdict
+	}
+
+	t, ok := parseTarget(raw)
+	if !ok {
+		verdict.Reason = "target could not be interpreted"
+		return verdict
+	}
+
+	if len(def.Windows) > 0 && !at.IsZero() {
+		inWindow := false
+		for _, window := range def.Windows {
+			if !at.Before(window.Start) && !at.After(window.End) {
+				inWindow = true
+				break
+			}
+		}
+		if !inWindow {
+			verdict.Reason = fmt.Sprintf("%s is outside every testing window", at.UTC().Format(time.RFC3339))
+			return verdict
+		}
+	}
+
+	for _, rule := range def.Exclude {
+		if matches(rule, t, true) {
+			verdict.Reason = "target is explicitly excluded"
+			verdict.Rule = rule.Value
+			return verdict
+		}
+	}
+
+	if len(def.Include) == 0 {
+		verdict.InScope = true
+		verdict.Reason = "scope defines no inclusions"
+		return verdict
+	}
+
+	for _, rule := range def.Include {
+		if matches(rule, t, false) {
+			verdict.InScope = true
+			verdict.Reason = "target is included"
+			verdict.Rule = rule.Value
+			return verdict
+		}
+	}
+
+	verdict.Reason = "target matches no included entry"
+	return verdict
+}
+
+// EvaluateAll checks several targets at once
+func EvaluateAll(def *models.ScopeDefinition, targets []string, at time.Time) []models.ScopeVerdict {
+	verdicts := make([]models.ScopeVerdict, 0, len(targets))
+	for _, raw := range targets {
+		verdicts = append(verdicts, Evaluate(def, raw, at))
+	}
+	return verdicts
+}
+
+// matches reports whether the rule covers the target. For exclusions any
+// overlap counts, for inclusions an address range must fit entirely.
+func matches(rule models.ScopeRule, t target, overlap bool) bool {
+	switch rule.Kind {
+	case models.ScopeCIDR, models.ScopeIPRange:
+		if !t.isIP() {
+			return false
+		}
+		start, end, ok := ruleRange(rule)
+		if !ok || start.Is4() != t.start.Is4() {
+			return false
+		}
+		if overlap {
+			return !t.end.Less(start) && !end.Less(t.start)
+		}
+		return !t.start.Less(start) && !end.Less(t.end)
+
+	case models.ScopeDomain:
+		return t.host != "" && matchHost(rule.Value, t.host)
+
+	case models.ScopeURL:
+		ruleURL, ok := parseURL(rule.Value)
+		if !ok {
+			return false
+		}
+		ruleHost := strings.ToLower(ruleURL.Hostname())
+		if t.url == nil {
+			if t.isIP() {
+				addr, err := netip.ParseAddr(ruleHost)
+				return err == nil && t.start == t.end && addr == t.start
+			}
+			return t.host != "" && matchHost(ruleHost, t.host)
+		}
+		if !strings.EqualFold(ruleURL.Scheme, t.url.Scheme) || !matchHost(ruleHost, strings.ToLower(t.url.Hostname())) {
+			return false
+		}
+		if effectivePort(ruleURL) != effectivePort(t.url) {
+			return false
+		}
+		return pathWithin(cleanPath(t.url.Path), cleanPath(ruleURL.Path))
+	}
+
+	return false
+}
+
+func parseTarget(raw string) (target, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return target{}, false
+	}
+
+	if strings.Contains(raw, "://") {
+		u, ok := parseURL(raw)
+		if !ok {
+			return target{}, false
+		}
+		t, ok := parseTarget(u.Hostname())
+		t.url = u
+		return t, ok
+	}
+
+	if prefix, err := netip.ParsePrefix(raw); err == nil {
+		prefix = prefix.Masked()
+		return target{start: prefix.Addr(), end: lastAddr(prefix)}, true
+	}
+
+	if addr, err := netip.ParseAddr(raw); err == nil {
+		return target{start: addr, end: addr}, true
+	}
+
+	if start, end, ok := parseRange(raw); ok {
+		return target{start: start, end: end}, true
+	}
+
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		return parseTarget(host)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(raw), ".")
+	if domainPattern.MatchString(host) || !strings.ContainsAny(host, " /*") {
+		return target{host: host}, true
+	}
+
+	return target{}, false
+}
+
+func ruleRange(rule models.ScopeRule) (netip.Addr, netip.Addr, bool) {
+	if rule.Kind == models.ScopeIPRange {
+		return parseRange(rule.Value)
+	}
+	prefix, err := netip.ParsePrefix(rule.Value)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, false
+	}
+	return prefix.Addr(), lastAddr(prefix), true
+}
+
+// lastAddr returns the highest address inside a masked prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// matchHost matches a host against a domain pattern where "*" stands for any
+// run of characters, so *.example.com covers every subdomain but not the apex
+func matchHost(pattern, host string) bool {
+	if pattern == host {
+		return true
+	}
+	ok, err := path.Match(pattern, host)
+	return err == nil && ok
+}
+
+func parseURL(value string) (*url.URL, bool) {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || u.Scheme == "" || u.Hostname() == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+func effectivePort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "ws":
+		return "80"
+	case "https", "wss":
+		return "443"
+	case "ftp":
+		return "21"
+	}
+	return ""
+}
+
+// pathWithin reports whether p is base or lies below it, so /api covers
+// /api/users but not /apix
+func pathWithin(p, base string) bool {
+	if !strings.HasPrefix(p, base) {
+		return false
+	}
+	return len(p) == len(base) || strings.HasSuffix(base, "/") || p[len(base)] == '/'
+}
+
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return p
+}
@@ -0,0 +1,159 @@
+// Package scope parses the free-text scope of an operation and checks
+// targets against it.
+//
+// Entries are separated by newlines, commas or semicolons. Each entry is one
+// of a CIDR (10.0.0.0/24), a single IP, an IP range (10.0.0.1-10.0.0.50 or
+// 10.0.0.1-50), a domain with optional wildcards (*.example.com) or a URL
+// (https://app.example.com/api). Entries prefixed with "exclude:" or "!" are
+// exclusions. "window: <start> to <end>" adds a permitted testing window.
+// Lines starting with "#" are comments.
+package scope
+
+import (
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"redops/models"
+	"redops/utils"
+)
+
+var (
+	entrySeparators = regexp.MustCompile(`[\n,;]+`)
+	windowSeparator = regexp.MustCompile(`(?i)\s+(?:to|until|/)\s+`)
+	domainPattern   = regexp.MustCompile(`^(\*\.)?([a-z0-9*]([a-z0-9*-]*[a-z0-9*])?\.)+[a-z0-9*-]+\.?$`)
+)
+
+// Parse converts an operation's scope text into a structured definition.
+// Entries that cannot be understood are kept in Unparsed.
+func Parse(text string) *models.ScopeDefinition {
+	def := &models.ScopeDefinition{
+		Include: []models.ScopeRule{},
+		Exclude: []models.ScopeRule{},
+		Windows: []models.ScopeWindow{},
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, "window:") {
+			if window, ok := parseWindow(line[len("window:"):]); ok {
+				def.Windows = append(def.Windows, window)
+			} else {
+				def.Unparsed = append(def.Unparsed, line)
+			}
+			continue
+		}
+
+		for _, entry := range entrySeparators.Split(line, -1) {
+			addEntry(def, strings.TrimSpace(entry))
+		}
+	}
+
+	return def
+}
+
+func addEntry(def *models.ScopeDefinition, entry string) {
+	if entry == "" {
+		return
+	}
+
+	exclude := false
+	lower := strings.ToLower(entry)
+	switch {
+	case strings.HasPrefix(lower, "exclude:"):
+		exclude = true
+		entry = strings.TrimSpace(entry[len("exclude:"):])
+	case strings.HasPrefix(lower, "include:"):
+		entry = strings.TrimSpace(entry[len("include:"):])
+	case strings.HasPrefix(entry, "!"):
+		exclude = true
+		entry = strings.TrimSpace(entry[1:])
+	}
+
+	rule, ok := parseRule(entry)
+	switch {
+	case !ok:
+		def.Unparsed = append(def.Unparsed, entry)
+	case exclude:
+		def.Exclude = append(def.Exclude, rule)
+	default:
+		def.Include = append(def.Include, rule)
+	}
+}
+
+func parseRule(entry string) (models.ScopeRule, bool) {
+	if strings.Contains(entry, "://") {
+		if _, ok := parseURL(entry); ok {
+			return models.ScopeRule{Kind: models.ScopeURL, Value: entry}, true
+		}
+		return models.ScopeRule{}, false
+	}
+
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		return models.ScopeRule{Kind: models.ScopeCIDR, Value: prefix.Masked().String()}, true
+	}
+
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		return models.ScopeRule{Kind: models.ScopeCIDR, Value: netip.PrefixFrom(addr, addr.BitLen()).String()}, true
+	}
+
+	if _, _, ok := parseRange(entry); ok {
+		return models.ScopeRule{Kind: models.ScopeIPRange, Value: entry}, true
+	}
+
+	domain := strings.ToLower(entry)
+	if domainPattern.MatchString(domain) {
+		return models.ScopeRule{Kind: models.ScopeDomain, Value: strings.TrimSuffix(domain, ".")}, true
+	}
+
+	return models.ScopeRule{}, false
+}
+
+// parseRange accepts "10.0.0.1-10.0.0.50" and the shorthand "10.0.0.1-50"
+func parseRange(entry string) (netip.Addr, netip.Addr, bool) {
+	first, last, found := strings.Cut(entry, "-")
+	if !found {
+		return netip.Addr{}, netip.Addr{}, false
+	}
+
+	start, err := netip.ParseAddr(strings.TrimSpace(first))
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, false
+	}
+
+	last = strings.TrimSpace(last)
+	end, err := netip.ParseAddr(last)
+	if err != nil && start.Is4() {
+		octets := strings.Split(start.String(), ".")
+		end, err = netip.ParseAddr(strings.Join(append(octets[:3], last), "."))
+	}
+	if err != nil || end.Less(start) || start.Is4() != end.Is4() {
+		return netip.Addr{}, netip.Addr{}, false
+	}
+
+	return start, end, true
+}
+
+func parseWindow(value string) (models.ScopeWindow, bool) {
+	parts := windowSeparator.Split(strings.TrimSpace(value), 2)
+	if len(parts) != 2 {
+		return models.ScopeWindow{}, false
+	}
+
+	loc := utils.ResultTimezone()
+	start, err := utils.ParseTimestamp(parts[0], loc)
+	if err != nil {
+		return models.ScopeWindow{}, false
+	}
+	end, err := utils.ParseTimestamp(parts[1], loc)
+	if err != nil || end.Before(start) {
+		return models.ScopeWindow{}, false
+	}
+
+	return models.ScopeWindow{Start: start, End: end}, true
+}
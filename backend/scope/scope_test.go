@@ -0,0 +1,149 @@
+package scope
+
+import (
+	"testing"
+	"time"
+
+	"redops/models"
+)
+
+func TestParse(t *testing.T) {
+	def := Parse(`# engagement scope
+10.0.0.0/24, 192.168.1.5; 172.16.0.1-20
+*.example.com
+https://app.example.org/api
+exclude: 10.0.0.128/25
+!admin.example.com
+not a target
+window: 2024-03-01 09:00 to 2024-03-05 17:00`)
+
+	wantInclude := []models.ScopeRule{
+		{Kind: models.ScopeCIDR, Value: "10.0.0.0/24"},
+		{Kind: models.ScopeCIDR, Value: "192.168.1.5/32"},
+		{Kind: models.ScopeIPRange, Value: "172.16.0.1-20"},
+		{Kind: models.ScopeDomain, Value: "*.example.com"},
+		{Kind: models.ScopeURL, Value: "https://app.example.org/api"},
+	}
+	if len(def.Include) != len(wantInclude) {
+		t.Fatalf("Include = %v, want %v", def.Include, wantInclude)
+	}
+	for i, rule := range wantInclude {
+		if def.Include[i] != rule {
+			t.Errorf("Include[%d] = %v, want %v", i, def.Include[i], rule)
+		}
+	}
+
+	wantExclude := []models.ScopeRule{
+		{Kind: models.ScopeCIDR, Value: "10.0.0.128/25"},
+		{Kind: models.ScopeDomain, Value: "admin.example.com"},
+	}
+	if len(def.Exclude) != len(wantExclude) {
+		t.Fatalf("Exclude = %v, want %v", def.Exclude, wantExclude)
+	}
+	for i, rule := range wantExclude {
+		if def.Exclude[i] != rule {
+			t.Errorf("Exclude[%d] = %v, want %v", i, def.Exclude[i], rule)
+		}
+	}
+
+	if len(def.Unparsed) != 1 || def.Unparsed[0] != "not a target" {
+		t.Errorf("Unparsed = %v, want [not a target]", def.Unparsed)
+	}
+	if len(def.Windows) != 1 {
+		t.Fatalf("Windows = %v, want one window", def.Windows)
+	}
+	if start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC); !def.Windows[0].Start.Equal(start) {
+		t.Errorf("window start = %v, want %v", def.Windows[0].Start, start)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	def := Parse(`10.0.0.0/24
+172.16.0.1-20
+*.example.com
+https://app.example.org/api
+exclude: 10.0.0.128/25
+!admin.example.com`)
+
+	tests := []struct {
+		target  string
+		inScope bool
+	}{
+		{"10.0.0.5", true},
+		{"10.0.0.200", false},
+		{"10.0.0.0/25", true},
+		{"10.0.0.0/24", false},
+		{"10.0.1.1", false},
+		{"172.16.0.10", true},
+		{"172.16.0.10-30", false},
+		{"www.example.com", true},
+		{"WWW.Example.com.", true},
+		{"www.example.com:8443", true},
+		{"example.com", false},
+		{"admin.example.com", false},
+		{"https://app.example.org/api/users", true},
+		{"https://app.example.org/apix", false},
+		{"http://app.example.org/api", false},
+		{"https://app.example.org:8443/api", false},
+		{"app.example.org", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			verdict := Evaluate(def, tt.target, time.Time{})
+			if verdict.InScope != tt.inScope {
+				t.Errorf("Evaluate(%q) = %v (%s), want %v", tt.target, verdict.InScope, verdict.Reason, tt.inScope)
+			}
+		})
+	}
+}
+
+func TestEvaluateWindows(t *testing.T) {
+	def := Parse("10.0.0.0/24\nwindow: 2024-03-01 09:00 to 2024-03-01 17:00")
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		inScope bool
+	}{
+		{"inside", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"at start", time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), true},
+		{"after end", time.Date(2024, 3, 1, 17, 1, 0, 0, time.UTC), false},
+		{"unknown time", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if verdict := Evaluate(def, "10.0.0.1", tt.at); verdict.InScope != tt.inScope {
+				t.Errorf("InScope = %v (%s), want %v", verdict.InScope, verdict.Reason, tt.inScope)
+			}
+		})
+	}
+}
+
+func TestEvaluateFailsClosed(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		inScope bool
+	}{
+		{"empty scope", "", true},
+		{"only exclusions", "exclude: 10.0.0.0/8", true},
+		{"unparseable entry", "10.0.0.0/24\nexclude: prod database", false},
+		{"only unparseable", "the usual hosts", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := Parse(tt.scope)
+			if verdict := Evaluate(def, "192.168.0.1", time.Time{}); verdict.InScope != tt.inScope {
+				t.Errorf("InScope = %v (%s), want %v", verdict.InScope, verdict.Reason, tt.inScope)
+			}
+		})
+	}
+
+	if Empty(Parse("the usual hosts")) {
+		t.Error("a scope with unparseable entries must not be empty")
+	}
+	if !Empty(Parse("# nothing yet")) {
+		t.Error("a scope with only comments must be empty")
+	}
+}
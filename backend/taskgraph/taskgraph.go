@@ -0,0 +1,222 @@
+// Package taskgraph treats the tasks of an operation and their dependencies
+// as a directed acyclic graph for validation and planning.
+package taskgraph
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrSelfDependency    = errors.New("a task cannot depend on itself")
+	ErrUnknownDependency = errors.New("dependency is not a task of this operation")
+	ErrCycle             = errors.New("dependencies form a cycle")
+)
+
+// Graph indexes an operation's tasks by ID
+type Graph struct {
+	tasks map[primitive.ObjectID]*models.Task
+	order []primitive.ObjectID
+}
+
+// New builds the graph of the given tasks. Dependencies on tasks outside the
+// set, for example deleted ones, are ignored.
+func New(tasks []models.Task) *Graph {
+	g := &Graph{tasks: make(map[primitive.ObjectID]*models.Task, len(tasks))}
+	for i := range tasks {
+		g.tasks[tasks[i].ID] = &tasks[i]
+		g.order = append(g.order, tasks[i].ID)
+	}
+	return g
+}
+
+// Set replaces a task's dependencies, adding the task when it is new, and
+// checks the result is still a DAG. The graph is left unchanged on error.
+func (g *Graph) Set(task models.Task) error {
+	seen := make(map[primitive.ObjectID]bool)
+	for _, dep := range task.DependsOn {
+		if dep == task.ID {
+			return ErrSelfDependency
+		}
+		if _, ok := g.tasks[dep]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownDependency, dep.Hex())
+		}
+		if seen[dep] {
+			return fmt.Errorf("task %s is listed twice", dep.Hex())
+		}
+		seen[dep] = true
+	}
+
+	previous, existed := g.tasks[task.ID]
+	g.tasks[task.ID] = &task
+	if !existed {
+		g.order = append(g.order, task.ID)
+	}
+
+	if _, err := g.Order(); err != nil {
+		if existed {
+			g.tasks[task.ID] = previous
+		} else {
+			delete(g.tasks, task.ID)
+			g.order = g.order[:len(g.order)-1]
+		}
+		return err
+	}
+	return nil
+}
+
+// deps returns the dependencies of a task that are part of the graph
+func (g *Graph) deps(id primitive.ObjectID) []primitive.ObjectID {
+	var deps []primitive.ObjectID
+	for _, dep := range g.tasks[id].DependsOn {
+		if _, ok := g.tasks[dep]; ok {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// Order returns the task IDs in an order where every task comes after its
+// dependencies. Among tasks that are ready at the same time the earlier
+// created task comes first.
+func (g *Graph) Order() ([]primitive.ObjectID, error) {
+	indegree := make(map[primitive.ObjectID]int, len(g.tasks))
+	dependents := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, id := range g.order {
+		for _, dep := range g.deps(id) {
+			indegree[id]++
+			dependents[dep] = append(dependents[dep], id)
+		}
+	}
+
+	var ready []primitive.ObjectID
+	for _, id := range g.order {
+		if indegree[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	order := make([]primitive.ObjectID, 0, len(g.order))
+	for len(ready) > 0 {
+		sort.SliceStable(ready, func(i, j int) bool { return g.before(ready[i], ready[j]) })
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, next := range dependents[id] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	if len(order) != len(g.order) {
+		return nil, ErrCycle
+	}
+	return order, nil
+}
+
+func (g *Graph) before(a, b primitive.ObjectID) bool {
+	ta, tb := g.tasks[a], g.tasks[b]
+	if !ta.CreatedAt.Equal(tb.CreatedAt) {
+		return ta.CreatedAt.Before(tb.CreatedAt)
+	}
+	return a.Hex() < b.Hex()
+}
+
+// Weight is the planning weight of a task in hours. Tasks without an
+// estimate count as one hour.
+func Weight(task *models.Task) float64 {
+	if task.EstimatedHours > 0 {
+		return task.EstimatedHours
+	}
+	return 1
+}
+
+// CriticalPath returns the chain of dependent tasks with the most estimated
+// hours, from first to last, and its length in hours
+func (g *Graph) CriticalPath() ([]primitive.ObjectID, float64, error) {
+	order, err := g.Order()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	finish := make(map[primitive.ObjectID]float64, len(order))
+	via := make(map[primitive.ObjectID]primitive.ObjectID, len(order))
+	var last primitive.ObjectID
+	longest := 0.0
+	for _, id := range order {
+		start := 0.0
+		for _, dep := range g.deps(id) {
+			if finish[dep] > start {
+				start = finish[dep]
+				via[id] = dep
+			}
+		}
+		finish[id] = start + Weight(g.tasks[id])
+		if finish[id] > longest {
+			longest = finish[id]
+			last = id
+		}
+	}
+
+	var path []primitive.ObjectID
+	for id, ok := last, len(order) > 0; ok; id, ok = via[id] {
+		path = append([]primitive.ObjectID{id}, path...)
+	}
+	return path, longest, nil
+}
+
+// Waiting reports whether any of the task's dependencies is not completed
+func (g *Graph) Waiting(id primitive.ObjectID) bool {
+	for _, dep := range g.deps(id) {
+		if g.tasks[dep].Status != models.StatusCompleted {
+			return true
+		}
+	}
+	return false
+}
+
+// AutomaticReason is the reason recorded for the status changes made from
+// StatusUpdates
+const AutomaticReason = "Updated automatically from task dependencies"
+
+// StatusUpdates works out the automatic blocked and unblocked transitions:
+// pending tasks waiting on an unfinished dependency become blocked, and
+// tasks that were blocked automatically become pending again once their
+// dependencies are all completed. A task someone blocked by hand stays
+// blocked. Tasks without dependencies are never changed.
+func (g *Graph) StatusUpdates() map[primitive.ObjectID]models.TaskStatus {
+	updates := make(map[primitive.ObjectID]models.TaskStatus)
+	for _, id := range g.order {
+		task := g.tasks[id]
+		if len(g.deps(id)) == 0 {
+			continue
+		}
+		waiting := g.Waiting(id)
+		switch {
+		case waiting && task.Status == models.StatusPending:
+			updates[id] = models.StatusBlocked
+		case !waiting && task.Status == models.StatusBlocked && blockedAutomatically(task):
+			updates[id] = models.StatusPending
+		}
+	}
+	return updates
+}
+
+// blockedAutomatically reports whether the task's last status change was
+// made by StatusUpdates
+func blockedAutomatically(task *models.Task) bool {
+	history := task.StatusHistory
+	return len(history) > 0 && history[len(history)-1].Reason == AutomaticReason
+}
+
+// Task returns a task of the graph
+func (g *Graph) Task(id primitive.ObjectID) *models.Task {
+	return g.tasks[id]
+}
@@ -0,0 +1,176 @@
+package taskgraph
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// plan builds tasks a to e created one minute apart:
+//
+//	a (2h) -> b (3h) -> d (1h)
+//	a (2h) -> c (1h) -> d
+//	e (4h)
+func plan() ([]models.Task, map[string]primitive.ObjectID) {
+	ids := make(map[string]primitive.ObjectID)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		ids[name] = primitive.NewObjectID()
+	}
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	task := func(name string, hours float64, deps ...string) models.Task {
+		t := models.Task{ID: ids[name], Title: name, Status: models.StatusPending, EstimatedHours: hours, CreatedAt: created}
+		for _, dep := range deps {
+			t.DependsOn = append(t.DependsOn, ids[dep])
+		}
+		created = created.Add(time.Minute)
+		return t
+	}
+	return []models.Task{
+		task("a", 2),
+		task("b", 3, "a"),
+		task("c", 1, "a"),
+		task("d", 1, "b", "c"),
+		task("e", 4),
+	}, ids
+}
+
+func names(ids map[string]primitive.ObjectID, order []primitive.ObjectID) []string {
+	byID := make(map[primitive.ObjectID]string, len(ids))
+	for name, id := range ids {
+		byID[id] = name
+	}
+	var out []string
+	for _, id := range order {
+		out = append(out, byID[id])
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOrder(t *testing.T) {
+	tasks, ids := plan()
+	order, err := New(tasks).Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(ids, order), []string{"a", "b", "c", "d", "e"}; !equal(got, want) {
+		t.Errorf("Order() = %v, want %v", got, want)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tasks, ids := plan()
+	unknown := primitive.NewObjectID()
+
+	tests := []struct {
+		name    string
+		task    string
+		deps    []primitive.ObjectID
+		wantErr error
+	}{
+		{"new dependency", "e", []primitive.ObjectID{ids["d"]}, nil},
+		{"clear dependencies", "d", nil, nil},
+		{"self", "a", []primitive.ObjectID{ids["a"]}, ErrSelfDependency},
+		{"unknown", "a", []primitive.ObjectID{unknown}, ErrUnknownDependency},
+		{"direct cycle", "a", []primitive.ObjectID{ids["b"]}, ErrCycle},
+		{"indirect cycle", "a", []primitive.ObjectID{ids["d"]}, ErrCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(append([]models.Task(nil), tasks...))
+			task := *g.Task(ids[tt.task])
+			task.DependsOn = tt.deps
+
+			err := g.Set(task)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Set() = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Set() = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := g.Order(); err != nil {
+				t.Errorf("graph left with %v after Set", err)
+			}
+		})
+	}
+}
+
+func TestSetNewTask(t *testing.T) {
+	tasks, ids := plan()
+	g := New(tasks)
+	if err := g.Set(models.Task{Title: "f", DependsOn: []primitive.ObjectID{ids["d"], ids["d"]}}); err == nil {
+		t.Error("Set() accepted a dependency listed twice")
+	}
+	if err := g.Set(models.Task{ID: primitive.NewObjectID(), Title: "f", DependsOn: []primitive.ObjectID{ids["d"]}}); err != nil {
+		t.Errorf("Set() = %v, want nil", err)
+	}
+}
+
+func TestCriticalPath(t *testing.T) {
+	tasks, ids := plan()
+	path, hours, err := New(tasks).CriticalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(ids, path), []string{"a", "b", "d"}; !equal(got, want) {
+		t.Errorf("CriticalPath() = %v, want %v", got, want)
+	}
+	if hours != 6 {
+		t.Errorf("CriticalPath() hours = %v, want 6", hours)
+	}
+
+	path, hours, err = New(nil).CriticalPath()
+	if err != nil || len(path) != 0 || hours != 0 {
+		t.Errorf("CriticalPath() of no tasks = %v, %v, %v", path, hours, err)
+	}
+}
+
+func TestStatusUpdates(t *testing.T) {
+	automatic := []models.TaskStatusChange{{From: models.StatusPending, To: models.StatusBlocked, Reason: AutomaticReason}}
+	manual := []models.TaskStatusChange{{From: models.StatusPending, To: models.StatusBlocked, Reason: "waiting on the client"}}
+
+	tests := []struct {
+		name    string
+		depA    models.TaskStatus
+		status  models.TaskStatus
+		history []models.TaskStatusChange
+		want    models.TaskStatus
+	}{
+		{"pending waits", models.StatusInProgress, models.StatusPending, nil, models.StatusBlocked},
+		{"in progress is left alone", models.StatusPending, models.StatusInProgress, nil, ""},
+		{"still waiting", models.StatusPending, models.StatusBlocked, automatic, ""},
+		{"automatic block released", models.StatusCompleted, models.StatusBlocked, automatic, models.StatusPending},
+		{"manual block kept", models.StatusCompleted, models.StatusBlocked, manual, ""},
+		{"block without history kept", models.StatusCompleted, models.StatusBlocked, nil, ""},
+		{"ready pending unchanged", models.StatusCompleted, models.StatusPending, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := models.Task{ID: primitive.NewObjectID(), Status: tt.depA}
+			b := models.Task{ID: primitive.NewObjectID(), Status: tt.status, StatusHistory: tt.history, DependsOn: []primitive.ObjectID{a.ID}}
+
+			updates := New([]models.Task{a, b}).StatusUpdates()
+			if _, ok := updates[a.ID]; ok {
+				t.Errorf("task without dependencies was changed")
+			}
+			if got := updates[b.ID]; got != tt.want {
+				t.Errorf("StatusUpdates()[b] = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
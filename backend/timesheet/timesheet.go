@@ -0,0 +1,252 @@
+// Package timesheet rolls up the time operators booked on an operation and
+// exports it for invoicing.
+package timesheet
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"redops/models"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// unphased names the phase total of tasks without a phase
+const unphased = "unphased"
+
+// Build rolls up the entries per task, phase and operator. Entries of tasks
+// that are not among tasks, for example because they are in the trash, are
+// left out. Running timers count up to now.
+func Build(operationID primitive.ObjectID, entries []models.TimeEntry, tasks []models.Task, now time.Time) models.TimeReport {
+	byID := make(map[primitive.ObjectID]*models.Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	byTask := newTotals()
+	byPhase := newTotals()
+	byOperator := newTotals()
+	report := models.TimeReport{OperationID: operationID, GeneratedAt: now}
+
+	for i := range entries {
+		entry := &entries[i]
+		task, ok := byID[entry.TaskID]
+		if !ok {
+			continue
+		}
+
+		hours := entry.Hours(now)
+		report.TotalHours += hours
+		if entry.Running {
+			report.Running++
+		}
+
+		byTask.add(task.ID.Hex(), task.Title, hours)
+		phase := string(task.Phase)
+		if phase == "" {
+			phase = unphased
+		}
+		byPhase.add(phase, phase, hours)
+		byOperator.add(entry.UserID, entry.Username, hours)
+	}
+
+	report.TotalHours = round(report.TotalHours)
+	report.ByTask = byTask.sorted()
+	report.ByPhase = byPhase.phaseOrder()
+	report.ByOperator = byOperator.sorted()
+	return report
+}
+
+// WriteCSV writes one row per entry, ready to be attached to an invoice
+func WriteCSV(w io.Writer, entries []models.TimeEntry, tasks []models.Task, now time.Time) error {
+	out := csv.NewWriter(w)
+	for _, row := range rows(entries, tasks, now) {
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteXLSX writes a workbook with the entries on one sheet and the
+// roll-ups on another
+func WriteXLSX(w io.Writer, report models.TimeReport, entries []models.TimeEntry, tasks []models.Task, now time.Time) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const entriesSheet, summarySheet = "Entries", "Summary"
+	if err := f.SetSheetName("Sheet1", entriesSheet); err != nil {
+		return err
+	}
+	for i, row := range rows(entries, tasks, now) {
+		values := make([]interface{}, len(row))
+		for j, value := range row {
+			values[j] = value
+			// Keep hours numeric so the sheet can sum them
+			if i > 0 && j == hoursColumn {
+				values[j], _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		if err := f.SetSheetRow(entriesSheet, cell(1, i+1), &values); err != nil {
+			return err
+		}
+	}
+
+	if _, err := f.NewSheet(summarySheet); err != nil {
+		return err
+	}
+	line := 1
+	section := func(title string, totals []models.TimeTotal) error {
+		if err := f.SetSheetRow(summarySheet, cell(1, line), &[]interface{}{title, "Hours", "Entries"}); err != nil {
+			return err
+		}
+		line++
+		for _, total := range totals {
+			if err := f.SetSheetRow(summarySheet, cell(1, line), &[]interface{}{text(total.Name), total.Hours, total.Entries}); err != nil {
+				return err
+			}
+			line++
+		}
+		line++
+		return nil
+	}
+	if err := f.SetSheetRow(summarySheet, cell(1, line), &[]interface{}{"Total hours", report.TotalHours}); err != nil {
+		return err
+	}
+	line += 2
+	for _, s := range []struct {
+		title  string
+		totals []models.TimeTotal
+	}{
+		{"Task", report.ByTask},
+		{"Phase", report.ByPhase},
+		{"Operator", report.ByOperator},
+	} {
+		if err := section(s.title, s.totals); err != nil {
+			return err
+		}
+	}
+
+	return f.Write(w)
+}
+
+// hoursColumn is the position of the hours in an exported row
+const hoursColumn = 6
+
+// rows lays out the entries of known tasks with a header row first
+func rows(entries []models.TimeEntry, tasks []models.Task, now time.Time) [][]string {
+	byID := make(map[primitive.ObjectID]*models.Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	out := [][]string{{"Date", "Operator", "Task", "Phase", "Start", "End", "Hours", "Type", "Note"}}
+	for i := range entries {
+		entry := &entries[i]
+		task, ok := byID[entry.TaskID]
+		if !ok {
+			continue
+		}
+
+		end, kind := "", "timer"
+		if !entry.End.IsZero() {
+			end = entry.End.UTC().Format(time.RFC3339)
+		}
+		if entry.Running {
+			kind = "running"
+		}
+		if entry.Manual {
+			kind = "manual"
+		}
+
+		out = append(out, []string{
+			entry.Start.UTC().Format("2006-01-02"),
+			text(entry.Username),
+			text(task.Title),
+			text(string(task.Phase)),
+			entry.Start.UTC().Format(time.RFC3339),
+			end,
+			strconv.FormatFloat(round(entry.Hours(now)), 'f', 2, 64),
+			kind,
+			text(entry.Note),
+		})
+	}
+	return out
+}
+
+// text keeps an operator-written value from being read as a formula when the
+// export is opened in a spreadsheet, by quoting values that start like one
+func text(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func cell(col, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
+
+// round keeps two decimals, which is as precise as invoices go
+func round(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
+
+// totals adds up hours per key, remembering the order keys were first seen
+type totals struct {
+	index map[string]int
+	list  []models.TimeTotal
+}
+
+func newTotals() *totals {
+	return &totals{index: make(map[string]int)}
+}
+
+func (t *totals) add(key, name string, hours float64) {
+	i, ok := t.index[key]
+	if !ok {
+		i = len(t.list)
+		t.index[key] = i
+		t.list = append(t.list, models.TimeTotal{Key: key, Name: name})
+	}
+	t.list[i].Hours += hours
+	t.list[i].Entries++
+}
+
+// sorted returns the totals with the most hours first
+func (t *totals) sorted() []models.TimeTotal {
+	list := t.rounded()
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Hours > list[j].Hours })
+	return list
+}
+
+// phaseOrder returns the totals in the order of the engagement's phases,
+// with tasks without a phase last
+func (t *totals) phaseOrder() []models.TimeTotal {
+	list := t.rounded()
+	position := func(key string) int {
+		if i := models.OperationPhase(key).Index(); i >= 0 {
+			return i
+		}
+		return len(models.OperationPhases)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return position(list[i].Key) < position(list[j].Key) })
+	return list
+}
+
+func (t *totals) rounded() []models.TimeTotal {
+	list := make([]models.TimeTotal, len(t.list))
+	for i, total := range t.list {
+		total.Hours = round(total.Hours)
+		list[i] = total
+	}
+	return list
+}
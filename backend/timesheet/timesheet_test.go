@@ -0,0 +1,176 @@
+package timesheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"redops/models"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var now = time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+func fixture() ([]models.TimeEntry, []models.Task) {
+	recon := models.Task{ID: primitive.NewObjectID(), Title: "Port scan", Phase: models.PhaseReconnaissance}
+	access := models.Task{ID: primitive.NewObjectID(), Title: "=HYPERLINK(\"http://evil\")", Phase: models.PhaseInitialAccess}
+	loose := models.Task{ID: primitive.NewObjectID(), Title: "Report"}
+	at := func(hour int) time.Time { return time.Date(2024, 3, 4, hour, 0, 0, 0, time.UTC) }
+
+	entries := []models.TimeEntry{
+		{TaskID: access.ID, UserID: "u1", Username: "alice", Start: at(8), End: at(9), Manual: true},
+		{TaskID: recon.ID, UserID: "u1", Username: "alice", Start: at(9), End: at(11)},
+		{TaskID: recon.ID, UserID: "u2", Username: "@bob", Start: at(9), End: at(10), Note: "+1 host"},
+		{TaskID: loose.ID, UserID: "u2", Username: "@bob", Start: at(10), Running: true},
+		{TaskID: primitive.NewObjectID(), UserID: "u2", Username: "@bob", Start: at(1), End: at(5)},
+	}
+	return entries, []models.Task{recon, access, loose}
+}
+
+func TestBuild(t *testing.T) {
+	entries, tasks := fixture()
+	report := Build(primitive.NewObjectID(), entries, tasks, now)
+
+	if report.TotalHours != 6 {
+		t.Errorf("TotalHours = %v, want 6", report.TotalHours)
+	}
+	if report.Running != 1 {
+		t.Errorf("Running = %d, want 1", report.Running)
+	}
+
+	tests := []struct {
+		name   string
+		totals []models.TimeTotal
+		want   []models.TimeTotal
+	}{
+		{"by task", report.ByTask, []models.TimeTotal{
+			{Name: "Port scan", Hours: 3, Entries: 2},
+			{Name: "Report", Hours: 2, Entries: 1},
+			{Name: tasks[1].Title, Hours: 1, Entries: 1},
+		}},
+		{"by phase", report.ByPhase, []models.TimeTotal{
+			{Name: string(models.PhaseReconnaissance), Hours: 3, Entries: 2},
+			{Name: string(models.PhaseInitialAccess), Hours: 1, Entries: 1},
+			{Name: unphased, Hours: 2, Entries: 1},
+		}},
+		{"by operator", report.ByOperator, []models.TimeTotal{
+			{Name: "alice", Hours: 3, Entries: 2},
+			{Name: "@bob", Hours: 3, Entries: 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.totals) != len(tt.want) {
+				t.Fatalf("totals = %+v, want %+v", tt.totals, tt.want)
+			}
+			for i, want := range tt.want {
+				got := tt.totals[i]
+				if got.Name != want.Name || got.Hours != want.Hours || got.Entries != want.Entries {
+					t.Errorf("totals[%d] = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	entries, tasks := fixture()
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, entries, tasks, now); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("got %d rows, want a header and 4 entries", len(records))
+	}
+
+	tests := []struct {
+		row, col int
+		want     string
+	}{
+		{0, 0, "Date"},
+		{1, 1, "alice"},
+		{1, 2, `'=HYPERLINK("http://evil")`},
+		{1, 7, "manual"},
+		{2, hoursColumn, "2.00"},
+		{3, 1, "'@bob"},
+		{3, 8, "'+1 host"},
+		{4, 3, ""},
+		{4, 5, ""},
+		{4, 7, "running"},
+	}
+	for _, tt := range tests {
+		if got := records[tt.row][tt.col]; got != tt.want {
+			t.Errorf("row %d column %d = %q, want %q", tt.row, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	entries, tasks := fixture()
+	report := Build(primitive.NewObjectID(), entries, tasks, now)
+
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, report, entries, tasks, now); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		sheet, cell string
+		want        string
+	}{
+		{"Entries", "C2", `'=HYPERLINK("http://evil")`},
+		{"Entries", "G3", "2"},
+		{"Summary", "A1", "Total hours"},
+		{"Summary", "B1", "6"},
+		{"Summary", "A3", "Task"},
+		{"Summary", "A4", "Port scan"},
+		{"Summary", "A6", `'=HYPERLINK("http://evil")`},
+	}
+	for _, tt := range tests {
+		got, err := f.GetCellValue(tt.sheet, tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s!%s = %q, want %q", tt.sheet, tt.cell, got, tt.want)
+		}
+	}
+
+	formula, err := f.GetCellFormula("Entries", "C2")
+	if err != nil || formula != "" {
+		t.Errorf("C2 formula = %q, %v, want none", formula, err)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"plain", "plain"},
+		{"=1+1", "'=1+1"},
+		{"+44 20", "'+44 20"},
+		{"-2", "'-2"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := text(tt.in); got != tt.want {
+			t.Errorf("text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
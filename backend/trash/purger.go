@@ -0,0 +1,91 @@
+// Package trash permanently removes deleted documents once they have been in
+// the trash longer than the retention period.
+package trash
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"redops/repositories"
+)
+
+// purgeInterval is how often the purger looks for expired documents
+const purgeInterval = time.Hour
+
+// Purger empties the trash of everything older than its retention period
+type Purger struct {
+	operationRepo *repositories.OperationRepository
+	taskRepo      *repositories.TaskRepository
+	resultRepo    *repositories.ResultRepository
+	toolRepo      *repositories.ToolRepository
+	evidenceRepo  *repositories.EvidenceRepository
+	retention     time.Duration
+}
+
+// NewPurger creates a purger. The retention period is read from
+// TRASH_RETENTION (a Go duration, 30 days by default).
+func NewPurger(operationRepo *repositories.OperationRepository, taskRepo *repositories.TaskRepository, resultRepo *repositories.ResultRepository, toolRepo *repositories.ToolRepository, evidenceRepo *repositories.EvidenceRepository) *Purger {
+	retention := 30 * 24 * time.Hour
+	if value := os.Getenv("TRASH_RETENTION"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			retention = d
+		}
+	}
+
+	return &Purger{
+		operationRepo: operationRepo,
+		taskRepo:      taskRepo,
+		resultRepo:    resultRepo,
+		toolRepo:      toolRepo,
+		evidenceRepo:  evidenceRepo,
+		retention:     retention,
+	}
+}
+
+// Retention returns how long deleted documents are kept
+func (p *Purger) Retention() time.Duration {
+	return p.retention
+}
+
+// Run purges the trash straight away and then once every interval. It never
+// returns, so start it in its own goroutine.
+func (p *Purger) Run() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		p.Purge()
+		<-ticker.C
+	}
+}
+
+// Purge removes everything that went into the trash before the retention
+// period. Operations go first so their tasks and results are removed with
+// them, then tasks, results and tools deleted on their own. Evidence goes
+// last so files whose task or result was just purged go with it.
+func (p *Purger) Purge() {
+	cutoff := time.Now().Add(-p.retention)
+
+	steps := []struct {
+		name  string
+		purge func(time.Time) (int64, error)
+	}{
+		{"operations", p.operationRepo.Purge},
+		{"tasks", p.taskRepo.Purge},
+		{"results", p.resultRepo.Purge},
+		{"tools", p.toolRepo.Purge},
+		{"evidence files", p.evidenceRepo.Purge},
+	}
+
+	for _, step := range steps {
+		purged, err := step.purge(cutoff)
+		if err != nil {
+			log.Printf("Failed to purge %s from the trash: %v", step.name, err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Purged %d %s from the trash", purged, step.name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnrecognisedTimestamp = errors.New("unrecognised timestamp format")
+
+// timestampLayouts lists the formats operators commonly paste into result
+// sheets. Layouts without a zone are interpreted in the caller's location.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"1/2/06 15:04",
+	"01-02-06 15:04",
+	"02-Jan-2006 15:04:05",
+	"02 Jan 2006 15:04:05",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006 3:04:05 PM",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+	"2006-01-02",
+	"01/02/2006",
+	"1/2/2006",
+}
+
+// excelEpoch is day zero of the 1900 date system used by spreadsheet serials
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ResultTimezone returns the location used for timestamps that carry no zone,
+// taken from RESULT_TIMEZONE and defaulting to UTC.
+func ResultTimezone() *time.Location {
+	if name := os.Getenv("RESULT_TIMEZONE"); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// ParseTimestamp converts a free-form timestamp into a UTC time. Besides the
+// layouts above it accepts Unix epoch seconds.
+func ParseTimestamp(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, ErrUnrecognisedTimestamp
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	if n, err := strconv.ParseFloat(value, 64); err == nil && n > 1e9 {
+		sec, frac := math.Modf(n)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+
+	return time.Time{}, ErrUnrecognisedTimestamp
+}
+
+// ParseSpreadsheetTimestamp is ParseTimestamp for values read from xlsx
+// cells, where a bare number below 100000 is a date serial of the 1900 date
+// system. Text from CSV or JSON never is, so a year such as "2024" is not
+// mistaken for a day in 1905.
+func ParseSpreadsheetTimestamp(value string, loc *time.Location) (time.Time, error) {
+	if t, err := ParseTimestamp(value, loc); err == nil {
+		return t, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || n <= 0 || n >= 100000 {
+		return time.Time{}, ErrUnrecognisedTimestamp
+	}
+	days, frac := math.Modf(n)
+	wall := excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration(math.Round(frac*86400)) * time.Second)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc).UTC(), nil
+}
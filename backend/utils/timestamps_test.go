@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+
+	tests := []struct {
+		value   string
+		loc     *time.Location
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-03-04T10:15:30Z", nil, time.Date(2024, 3, 4, 10, 15, 30, 0, time.UTC), false},
+		{"2024-03-04T10:15:30+02:00", nil, time.Date(2024, 3, 4, 8, 15, 30, 0, time.UTC), false},
+		{"2024-03-04 10:15", nil, time.Date(2024, 3, 4, 10, 15, 0, 0, time.UTC), false},
+		{"2024-03-04 10:15", berlin, time.Date(2024, 3, 4, 9, 15, 0, 0, time.UTC), false},
+		{"03/04/2024 10:15", nil, time.Date(2024, 3, 4, 10, 15, 0, 0, time.UTC), false},
+		{"04-Mar-2024 10:15:30", nil, time.Date(2024, 3, 4, 10, 15, 30, 0, time.UTC), false},
+		{"  2024-03-04  ", nil, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), false},
+		{"1709547330", nil, time.Date(2024, 3, 4, 10, 15, 30, 0, time.UTC), false},
+		{"1709547330.5", nil, time.Date(2024, 3, 4, 10, 15, 30, 500000000, time.UTC), false},
+		{"2024", nil, time.Time{}, true},
+		{"45355.5", nil, time.Time{}, true},
+		{"", nil, time.Time{}, true},
+		{"yesterday", nil, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTimestamp(tt.value, tt.loc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimestamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpreadsheetTimestamp(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"45355", time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), false},
+		{"45355.5", time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC), false},
+		{"2024", time.Date(1905, 7, 16, 0, 0, 0, 0, time.UTC), false},
+		{"2024-03-04 10:15", time.Date(2024, 3, 4, 10, 15, 0, 0, time.UTC), false},
+		{"1709547330", time.Date(2024, 3, 4, 10, 15, 30, 0, time.UTC), false},
+		{"0", time.Time{}, true},
+		{"100000", time.Time{}, true},
+		{"n/a", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSpreadsheetTimestamp(tt.value, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSpreadsheetTimestamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseSpreadsheetTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
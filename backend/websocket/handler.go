@@ -14,25 +14,72 @@ type Client struct {
 	Hub    *Hub
 	UserID string
 	mu     sync.Mutex
+	// topics is only touched by the hub's Run loop
+	topics map[string]bool
 }
 
 type Hub struct {
 	clients    map[*Client]bool
 	Broadcast  chan []byte
+	direct     chan directMessage
+	subscribe  chan subscription
+	publish    chan topicMessage
 	Register   chan *Client
 	Unregister chan *Client
 	mu         sync.RWMutex
 }
 
+// TypeNotification is the message type of stored notifications pushed to
+// their users; the severity is the payload's own type
+const TypeNotification = "notification"
+
 type Notification struct {
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload"`
 }
 
+// directMessage is a message for the connections of particular users
+type directMessage struct {
+	userIDs map[string]bool
+	data    []byte
+}
+
+// subscription adds a client to or removes it from a topic
+type subscription struct {
+	client *Client
+	topic  string
+	on     bool
+}
+
+// topicMessage is a message for the connections subscribed to a topic
+type topicMessage struct {
+	topic string
+	data  []byte
+}
+
+// clientMessage is what a client sends to follow or stop following a topic,
+// for example {"action": "subscribe", "topic": "task:<id>"}
+type clientMessage struct {
+	Action string `json:"action"`
+	Topic  string `json:"topic"`
+}
+
+// TaskTopic names the topic that carries the live updates of a task
+func TaskTopic(taskID string) string {
+	return "task:" + taskID
+}
+
+// hubBuffer is how many messages may wait for the hub's Run loop before a
+// request handler sending one has to wait
+const hubBuffer = 256
+
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		Broadcast:  make(chan []byte),
+		Broadcast:  make(chan []byte, hubBuffer),
+		direct:     make(chan directMessage, hubBuffer),
+		subscribe:  make(chan subscription, hubBuffer),
+		publish:    make(chan topicMessage, hubBuffer),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 	}
@@ -63,6 +110,47 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mu.RUnlock()
+		case message := <-h.direct:
+			h.mu.Lock()
+			for client := range h.clients {
+				if !message.userIDs[client.UserID] {
+					continue
+				}
+				select {
+				case client.Send <- message.data:
+				default:
+					close(client.Send)
+					delete(h.clients, client)
+				}
+			}
+			h.mu.Unlock()
+		case sub := <-h.subscribe:
+			h.mu.Lock()
+			if _, ok := h.clients[sub.client]; ok {
+				if sub.client.topics == nil {
+					sub.client.topics = make(map[string]bool)
+				}
+				if sub.on {
+					sub.client.topics[sub.topic] = true
+				} else {
+					delete(sub.client.topics, sub.topic)
+				}
+			}
+			h.mu.Unlock()
+		case message := <-h.publish:
+			h.mu.Lock()
+			for client := range h.clients {
+				if !client.topics[message.topic] {
+					continue
+				}
+				select {
+				case client.Send <- message.data:
+				default:
+					close(client.Send)
+					delete(h.clients, client)
+				}
+			}
+			h.mu.Unlock()
 		}
 	}
 }
@@ -76,6 +164,32 @@ func (h *Hub) BroadcastNotification(notification Notification) {
 	h.Broadcast <- data
 }
 
+// SendToUsers delivers a notification to every connection belonging to one
+// of the given users
+func (h *Hub) SendToUsers(userIDs []string, notification Notification) {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Error marshaling notification: %v", err)
+		return
+	}
+
+	recipients := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		recipients[id] = true
+	}
+	h.direct <- directMessage{userIDs: recipients, data: data}
+}
+
+// Publish delivers a notification to every connection subscribed to the topic
+func (h *Hub) Publish(topic string, notification Notification) {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Error marshaling notification: %v", err)
+		return
+	}
+	h.publish <- topicMessage{topic: topic, data: data}
+}
+
 func (c *Client) ReadPump() {
 	defer func() {
 		c.Hub.Unregister <- c
@@ -83,13 +197,24 @@ func (c *Client) ReadPump() {
 	}()
 
 	for {
-		_, _, err := c.Conn.ReadMessage()
+		_, data, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("error: %v", err)
 			}
 			break
 		}
+
+		var message clientMessage
+		if err := json.Unmarshal(data, &message); err != nil || message.Topic == "" {
+			continue
+		}
+		switch message.Action {
+		case "subscribe":
+			c.Hub.subscribe <- subscription{client: c, topic: message.Topic, on: true}
+		case "unsubscribe":
+			c.Hub.subscribe <- subscription{client: c, topic: message.Topic, on: false}
+		}
 	}
 }
 
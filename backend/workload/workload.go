@@ -0,0 +1,170 @@
+// Package workload works out how much open work each operator carries and
+// who on a team is best placed to take on a new task.
+package workload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"redops/models"
+	"redops/taskgraph"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Counts reports whether an operation's tasks still count towards workload.
+// Closed and archived operations are finished.
+func Counts(operation *models.Operation) bool {
+	switch operation.Status {
+	case models.OperationStatusClosed, models.OperationStatusArchived:
+		return false
+	}
+	return true
+}
+
+// Build adds up the open tasks of each user in operations that still count,
+// keeping the order of users. Completed tasks and tasks of operations not in
+// operations are ignored.
+func Build(users []models.User, tasks []models.Task, operations map[primitive.ObjectID]*models.Operation) []models.MemberWorkload {
+	loads := make([]models.MemberWorkload, len(users))
+	index := make(map[primitive.ObjectID]int, len(users))
+	for i, user := range users {
+		loads[i] = models.MemberWorkload{UserID: user.ID, Username: user.Username, Operations: []models.OperationWorkload{}}
+		index[user.ID] = i
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Status == models.StatusCompleted {
+			continue
+		}
+		operation, ok := operations[task.OperationID]
+		if !ok || !Counts(operation) {
+			continue
+		}
+		u, ok := index[task.AssignedTo]
+		if !ok {
+			continue
+		}
+
+		load := &loads[u]
+		load.OpenTasks++
+		switch task.Status {
+		case models.StatusInProgress:
+			load.InProgress++
+		case models.StatusBlocked:
+			load.Blocked++
+		}
+		load.EstimatedHours += task.EstimatedHours
+		if task.EstimatedHours <= 0 {
+			load.Unestimated++
+		}
+		load.LoadHours += taskgraph.Weight(task)
+
+		share := -1
+		for j := range load.Operations {
+			if load.Operations[j].OperationID == operation.ID {
+				share = j
+				break
+			}
+		}
+		if share < 0 {
+			load.Operations = append(load.Operations, models.OperationWorkload{OperationID: operation.ID, Name: operation.Name})
+			share = len(load.Operations) - 1
+		}
+		load.Operations[share].OpenTasks++
+		load.Operations[share].EstimatedHours += task.EstimatedHours
+	}
+
+	return loads
+}
+
+// Suggest ranks the team for a new task. Members who completed similar tasks
+// before are qualified and come first; when nobody is, or the task is not
+// described at all, everyone is. Within that the least loaded member comes
+// first.
+func Suggest(team []models.User, tasks []models.Task, operations map[primitive.ObjectID]*models.Operation, criteria models.AssignmentCriteria) []models.AssignmentCandidate {
+	loads := Build(team, tasks, operations)
+	candidates := make([]models.AssignmentCandidate, len(loads))
+	index := make(map[primitive.ObjectID]int, len(loads))
+	for i, load := range loads {
+		candidates[i] = models.AssignmentCandidate{MemberWorkload: load, Reasons: []string{}}
+		index[load.UserID] = i
+	}
+
+	described := criteria.Phase != "" || criteria.MITREID != "" || criteria.OWASPID != "" || len(criteria.Tools) > 0
+	if described {
+		for i := range tasks {
+			task := &tasks[i]
+			c, ok := index[task.AssignedTo]
+			if !ok || task.Status != models.StatusCompleted {
+				continue
+			}
+			if reason := similarity(task, criteria); reason != "" {
+				candidate := &candidates[c]
+				candidate.Experience++
+				if len(candidate.Reasons) < 3 {
+					candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("completed %q (%s)", task.Title, reason))
+				}
+			}
+		}
+	}
+
+	anyQualified := false
+	for i := range candidates {
+		candidates[i].Qualified = !described || candidates[i].Experience > 0
+		anyQualified = anyQualified || candidates[i].Qualified
+	}
+	if !anyQualified {
+		for i := range candidates {
+			candidates[i].Qualified = true
+			candidates[i].Reasons = append(candidates[i].Reasons, "nobody on the team has completed similar work")
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		switch {
+		case a.Qualified != b.Qualified:
+			return a.Qualified
+		case a.LoadHours != b.LoadHours:
+			return a.LoadHours < b.LoadHours
+		case a.OpenTasks != b.OpenTasks:
+			return a.OpenTasks < b.OpenTasks
+		default:
+			return a.Experience > b.Experience
+		}
+	})
+	return candidates
+}
+
+// similarity says what a completed task has in common with the criteria, or
+// returns "" when nothing. Sub-techniques match their parent technique.
+func similarity(task *models.Task, criteria models.AssignmentCriteria) string {
+	if criteria.MITREID != "" && task.MITREID != "" && parentTechnique(task.MITREID) == parentTechnique(criteria.MITREID) {
+		return "technique " + task.MITREID
+	}
+	if criteria.OWASPID != "" && strings.EqualFold(task.OWASPID, criteria.OWASPID) {
+		return "OWASP " + task.OWASPID
+	}
+	for _, tool := range criteria.Tools {
+		for _, used := range task.Tools {
+			if strings.EqualFold(tool, used) {
+				return "tool " + used
+			}
+		}
+	}
+	if criteria.Phase != "" && task.Phase == criteria.Phase {
+		return "phase " + string(task.Phase)
+	}
+	return ""
+}
+
+func parentTechnique(id string) string {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if i := strings.IndexByte(id, '.'); i >= 0 {
+		return id[:i]
+	}
+	return id
+}
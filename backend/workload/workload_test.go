@@ -0,0 +1,137 @@
+package workload
+
+import (
+	"testing"
+
+	"redops/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCounts(t *testing.T) {
+	tests := []struct {
+		status models.OperationStatus
+		want   bool
+	}{
+		{models.OperationStatusPlanning, true},
+		{models.OperationStatusActive, true},
+		{models.OperationStatusHalted, true},
+		{models.OperationStatusReporting, true},
+		{models.OperationStatusClosed, false},
+		{models.OperationStatusArchived, false},
+	}
+	for _, tt := range tests {
+		if got := Counts(&models.Operation{Status: tt.status}); got != tt.want {
+			t.Errorf("Counts(%s) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	alice := models.User{ID: primitive.NewObjectID(), Username: "alice"}
+	bob := models.User{ID: primitive.NewObjectID(), Username: "bob"}
+	active := &models.Operation{ID: primitive.NewObjectID(), Name: "Acme", Status: models.OperationStatusActive}
+	other := &models.Operation{ID: primitive.NewObjectID(), Name: "Globex", Status: models.OperationStatusPlanning}
+	closed := &models.Operation{ID: primitive.NewObjectID(), Name: "Initech", Status: models.OperationStatusClosed}
+	operations := map[primitive.ObjectID]*models.Operation{active.ID: active, other.ID: other, closed.ID: closed}
+
+	task := func(operation *models.Operation, user models.User, status models.TaskStatus, hours float64) models.Task {
+		return models.Task{ID: primitive.NewObjectID(), OperationID: operation.ID, AssignedTo: user.ID, Status: status, EstimatedHours: hours}
+	}
+	tasks := []models.Task{
+		task(active, alice, models.StatusInProgress, 4),
+		task(active, alice, models.StatusBlocked, 0),
+		task(other, alice, models.StatusPending, 2),
+		task(active, alice, models.StatusCompleted, 8),
+		task(closed, alice, models.StatusPending, 8),
+		task(active, models.User{ID: primitive.NewObjectID()}, models.StatusPending, 8),
+		{ID: primitive.NewObjectID(), OperationID: primitive.NewObjectID(), AssignedTo: bob.ID, Status: models.StatusPending},
+	}
+
+	loads := Build([]models.User{alice, bob}, tasks, operations)
+	if len(loads) != 2 || loads[0].Username != "alice" || loads[1].Username != "bob" {
+		t.Fatalf("Build() = %+v, want alice then bob", loads)
+	}
+
+	got := loads[0]
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"open tasks", float64(got.OpenTasks), 3},
+		{"in progress", float64(got.InProgress), 1},
+		{"blocked", float64(got.Blocked), 1},
+		{"estimated hours", got.EstimatedHours, 6},
+		{"unestimated", float64(got.Unestimated), 1},
+		{"load hours", got.LoadHours, 7},
+		{"operations", float64(len(got.Operations)), 2},
+		{"acme share", float64(got.Operations[0].OpenTasks), 2},
+		{"globex share", got.Operations[1].EstimatedHours, 2},
+		{"bob open tasks", float64(loads[1].OpenTasks), 0},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if loads[1].Operations == nil {
+		t.Error("Operations must be an empty list, not nil")
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	alice := models.User{ID: primitive.NewObjectID(), Username: "alice"}
+	bob := models.User{ID: primitive.NewObjectID(), Username: "bob"}
+	carol := models.User{ID: primitive.NewObjectID(), Username: "carol"}
+	team := []models.User{alice, bob, carol}
+	operation := &models.Operation{ID: primitive.NewObjectID(), Status: models.OperationStatusActive}
+	operations := map[primitive.ObjectID]*models.Operation{operation.ID: operation}
+
+	done := func(user models.User, task models.Task) models.Task {
+		task.ID = primitive.NewObjectID()
+		task.OperationID = operation.ID
+		task.AssignedTo = user.ID
+		task.Status = models.StatusCompleted
+		return task
+	}
+	open := func(user models.User, hours float64) models.Task {
+		return models.Task{ID: primitive.NewObjectID(), OperationID: operation.ID, AssignedTo: user.ID, Status: models.StatusPending, EstimatedHours: hours}
+	}
+	tasks := []models.Task{
+		done(alice, models.Task{Title: "Kerberoast", MITREID: "T1558.003"}),
+		done(bob, models.Task{Title: "Spray", Tools: []string{"Hydra"}}),
+		done(carol, models.Task{Title: "SQLi", OWASPID: "A03"}),
+		open(alice, 10),
+		open(bob, 2),
+	}
+
+	tests := []struct {
+		name          string
+		criteria      models.AssignmentCriteria
+		want          []string
+		wantQualified int
+	}{
+		{"no criteria ranks by load", models.AssignmentCriteria{}, []string{"carol", "bob", "alice"}, 3},
+		{"sub-technique matches parent", models.AssignmentCriteria{MITREID: "T1558"}, []string{"alice", "carol", "bob"}, 1},
+		{"tool match ignores case", models.AssignmentCriteria{Tools: []string{"hydra"}}, []string{"bob", "carol", "alice"}, 1},
+		{"owasp", models.AssignmentCriteria{OWASPID: "a03"}, []string{"carol", "bob", "alice"}, 1},
+		{"nobody qualified", models.AssignmentCriteria{Phase: models.PhaseImpact}, []string{"carol", "bob", "alice"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := Suggest(team, tasks, operations, tt.criteria)
+			qualified := 0
+			for i, candidate := range candidates {
+				if candidate.Username != tt.want[i] {
+					t.Errorf("candidate %d = %s, want %s", i, candidate.Username, tt.want[i])
+				}
+				if candidate.Qualified {
+					qualified++
+				}
+			}
+			if qualified != tt.wantQualified {
+				t.Errorf("%d qualified, want %d", qualified, tt.wantQualified)
+			}
+		})
+	}
+}
@@ -30,7 +30,7 @@ import { useKeyboardShortcuts } from '../hooks/useKeyboardShortcuts';
 
 interface Notification {
     id: string;
-    type: 'success' | 'error' | 'warning' | 'info';
+    type: 'success' | 'error' | 'warning' | 'info' | 'urgent';
     title: string;
     message: string;
     timestamp: string;
@@ -136,6 +136,7 @@ const Notifications: React.FC = () => {
             case 'success':
                 return <CheckCircleIcon color="success" />;
             case 'error':
+            case 'urgent':
                 return <ErrorIcon color="error" />;
             case 'warning':
                 return <WarningIcon color="warning" />;
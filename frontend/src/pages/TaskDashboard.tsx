@@ -268,12 +268,26 @@ const TaskDashboard: React.FC = () => {
 
     const handleManualResultsChange = async (value: string) => {
         setManualResults(value);
+        if (!task) {
+            // Nothing to send a version for until the task has loaded
+            return;
+        }
         try {
-            await axiosInstance.put(`${API_URL}/tasks/${taskId}`, {
+            // Send back the version that was loaded so a concurrent edit is
+            // reported instead of overwritten
+            const response = await axiosInstance.put(`${API_URL}/operations/${operationId}/tasks/${taskId}`, {
                 ...task,
                 manualResults: value
+            }, {
+                headers: { 'If-Match': `"${task.version}"` },
             });
-        } catch (err) {
+            setTask(response.data);
+        } catch (err: any) {
+            if (err.response?.status === 409) {
+                setTask(err.response.data.current);
+                setError('This task was changed by someone else. Review the current version and try again.');
+                return;
+            }
             console.error('Error updating manual results:', err);
         }
     };
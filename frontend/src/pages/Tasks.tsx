@@ -151,6 +151,7 @@ const Tasks: React.FC = () => {
 
             if (selectedTask) {
                 await dispatch(updateTask({
+                    operationId: selectedTask.operationId,
                     id: selectedTask.id,
                     task: formData,
                 })).unwrap();
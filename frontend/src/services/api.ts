@@ -1,6 +1,11 @@
 import axiosInstance from './axiosConfig';
 import type { User, Operation, Task, Tool } from '../types/models';
 
+// ifMatch sends back the version an edit was based on; the API answers 409
+// when someone else saved first and will refuse updates without it (428)
+const ifMatch = (version?: number) =>
+    version ? { headers: { 'If-Match': `"${version}"` } } : undefined;
+
 // User API
 export const userApi = {
     create: (user: Omit<User, 'id' | 'createdAt' | 'updatedAt'>) => 
@@ -20,7 +25,7 @@ export const operationApi = {
     getAll: () => axiosInstance.get<Operation[]>('/operations'),
     getById: (id: string) => axiosInstance.get<Operation>(`/operations/${id}`),
     update: (id: string, operation: Partial<Operation>) => 
-        axiosInstance.put<Operation>(`/operations/${id}`, operation),
+        axiosInstance.put<Operation>(`/operations/${id}`, operation, ifMatch(operation.version)),
     delete: (id: string) => axiosInstance.delete(`/operations/${id}`),
     getByTeamMember: (userId: string) => 
         axiosInstance.get<Operation[]>(`/operations/user/${userId}`),
@@ -34,8 +39,8 @@ export const taskApi = {
         axiosInstance.post<Task>('/tasks', task),
     getAll: () => axiosInstance.get<Task[]>('/tasks'),
     getById: (id: string) => axiosInstance.get<Task>(`/tasks/${id}`),
-    update: (id: string, task: Partial<Task>) => 
-        axiosInstance.put<Task>(`/tasks/${id}`, task),
+    update: (operationId: string, id: string, task: Partial<Task>) => 
+        axiosInstance.put<Task>(`/operations/${operationId}/tasks/${id}`, task, ifMatch(task.version)),
     delete: (id: string) => axiosInstance.delete(`/tasks/${id}`),
     getByOperation: (operationId: string) => 
         axiosInstance.get<Task[]>(`/operations/${operationId}/tasks`),
@@ -54,7 +59,7 @@ export const toolApi = {
     getAll: () => axiosInstance.get<Tool[]>('/tools'),
     getById: (id: string) => axiosInstance.get<Tool>(`/tools/${id}`),
     update: (id: string, tool: Partial<Tool>) => 
-        axiosInstance.put<Tool>(`/tools/${id}`, tool),
+        axiosInstance.put<Tool>(`/tools/${id}`, tool, ifMatch(tool.version)),
     delete: (id: string) => axiosInstance.delete(`/tools/${id}`),
     getByType: (type: Tool['type']) => 
         axiosInstance.get<Tool[]>(`/tools/type/${type}`),
@@ -1,31 +1,53 @@
 import { store } from '../store';
 import { addNotification } from '../store/slices/notificationSlice';
+import authService from './authService';
+
+type Listener = (payload: any) => void;
+
+// Browsers cannot set headers on a WebSocket upgrade, so the token travels as
+// the second of the subprotocols ["bearer", token]
+const AUTH_PROTOCOL = 'bearer';
+
+const defaultUrl = () => {
+    const apiUrl = import.meta.env.VITE_API_URL || 'http://localhost:8080/api';
+    return apiUrl.replace(/^http/, 'ws') + '/ws';
+};
 
 class WebSocketService {
     private ws: WebSocket | null = null;
     private reconnectAttempts = 0;
     private maxReconnectAttempts = 5;
     private reconnectTimeout = 1000;
+    private listeners = new Map<string, Set<Listener>>();
+    private topics = new Set<string>();
 
     constructor() {
         this.connect();
     }
 
-    private connect() {
-        const wsUrl = process.env.REACT_APP_WS_URL || 'ws://localhost:8080/ws';
-        this.ws = new WebSocket(wsUrl);
+    public connect() {
+        const token = authService.getToken();
+        if (!token || this.ws) {
+            return;
+        }
+
+        const wsUrl = import.meta.env.VITE_WS_URL || defaultUrl();
+        this.ws = new WebSocket(wsUrl, [AUTH_PROTOCOL, token]);
 
         this.ws.onopen = () => {
             console.log('WebSocket connected');
             this.reconnectAttempts = 0;
+            this.topics.forEach(topic => this.send({ action: 'subscribe', topic }));
         };
 
         this.ws.onmessage = (event) => {
             try {
                 const data = JSON.parse(event.data);
+                // Stored notifications carry their severity in payload.type
                 if (data.type === 'notification') {
                     store.dispatch(addNotification(data.payload));
                 }
+                this.listeners.get(data.type)?.forEach(listener => listener(data.payload));
             } catch (error) {
                 console.error('Error parsing WebSocket message:', error);
             }
@@ -33,6 +55,7 @@ class WebSocketService {
 
         this.ws.onclose = () => {
             console.log('WebSocket disconnected');
+            this.ws = null;
             this.reconnect();
         };
 
@@ -48,6 +71,33 @@ class WebSocketService {
         }
     }
 
+    // on registers a listener for a message type, such as 'notification' or
+    // 'comment_created', and opens the connection if the user has signed in
+    // since the service started
+    public on(type: string, listener: Listener) {
+        if (!this.listeners.has(type)) {
+            this.listeners.set(type, new Set());
+        }
+        this.listeners.get(type)!.add(listener);
+        this.connect();
+    }
+
+    public off(type: string, listener: Listener) {
+        this.listeners.get(type)?.delete(listener);
+    }
+
+    // subscribe follows a topic such as `task:${taskId}`, again after reconnects
+    public subscribe(topic: string) {
+        this.topics.add(topic);
+        this.send({ action: 'subscribe', topic });
+        this.connect();
+    }
+
+    public unsubscribe(topic: string) {
+        this.topics.delete(topic);
+        this.send({ action: 'unsubscribe', topic });
+    }
+
     public send(message: any) {
         if (this.ws?.readyState === WebSocket.OPEN) {
             this.ws.send(JSON.stringify(message));
@@ -56,9 +106,10 @@ class WebSocketService {
 
     public close() {
         if (this.ws) {
+            this.reconnectAttempts = this.maxReconnectAttempts;
             this.ws.close();
         }
     }
 }
 
-export const websocketService = new WebSocketService(); 
\ No newline at end of file
+export const websocketService = new WebSocketService();
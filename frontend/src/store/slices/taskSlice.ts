@@ -45,8 +45,8 @@ export const createTask = createAsyncThunk(
 
 export const updateTask = createAsyncThunk(
     'tasks/update',
-    async ({ id, task }: { id: string; task: Partial<Task> }) => {
-        const response = await taskApi.update(id, task);
+    async ({ operationId, id, task }: { operationId: string; id: string; task: Partial<Task> }) => {
+        const response = await taskApi.update(operationId, id, task);
         return response.data;
     }
 );
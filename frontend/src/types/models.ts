@@ -41,6 +41,7 @@ export interface Operation {
     end_date: string;
     created_at: string;
     updated_at: string;
+    version: number;
 }
 
 export type TaskStatus = 'pending' | 'in_progress' | 'completed' | 'blocked';
@@ -61,6 +62,7 @@ export interface Task {
     endDate: string;
     createdAt: string;
     updatedAt: string;
+    version: number;
 }
 
 export type ToolType = 'reconnaissance' | 'vulnerability' | 'exploitation' | 'post_exploitation';
@@ -76,6 +78,7 @@ export interface Tool {
     isActive: boolean;
     createdAt: string;
     updatedAt: string;
+    version: number;
 }
 
 export interface ToolExecution {